@@ -21,11 +21,23 @@ const (
 	From       = "FROM"
 	Where      = "WHERE"
 	GroupBy    = "GROUP BY"
+	Rollup     = "ROLLUP"
+	Cube       = "CUBE"
+	Filter     = "FILTER"
+	Case       = "CASE"
+	When       = "WHEN"
+	Then       = "THEN"
+	Else       = "ELSE"
+	End        = "END"
 	Having     = "HAVING"
 	OrderBy    = "ORDER BY"
 	Asc        = "ASC"
 	Desc       = "DESC"
 	Limit      = "LIMIT"
+	Offset     = "OFFSET"
+	Rows       = "ROWS"
+	FetchNext  = "FETCH NEXT"
+	Only       = "ONLY"
 	Insert     = "INSERT"
 	InsertInto = "INSERT INTO"
 	Values     = "VALUES"
@@ -34,6 +46,25 @@ const (
 	Delete     = "DELETE"
 	Output     = "OUTPUT"
 	Using      = "USING"
+	With       = "WITH"
+	Recursive  = "RECURSIVE"
+
+	Union        = "UNION"
+	UnionAll     = "UNION ALL"
+	Except       = "EXCEPT"
+	ExceptAll    = "EXCEPT ALL"
+	Intersect    = "INTERSECT"
+	IntersectAll = "INTERSECT ALL"
+	MinusSet     = "MINUS"
+
+	TableSample = "TABLESAMPLE"
+	System      = "SYSTEM"
+	Bernoulli   = "BERNOULLI"
+	Percent     = "PERCENT"
+
+	UseIndex    = "USE INDEX"
+	ForceIndex  = "FORCE INDEX"
+	IgnoreIndex = "IGNORE INDEX"
 
 	Join      = "JOIN"
 	As        = "AS"
@@ -44,6 +75,21 @@ const (
 	OuterJoin = "OUTER JOIN"
 	LeftJoin  = "LEFT JOIN"
 	RightJoin = "RIGHT JOIN"
+	Lateral   = "LATERAL"
+	True      = "TRUE"
+
+	CreateTable     = "CREATE TABLE"
+	PrimaryKey      = "PRIMARY KEY"
+	DropTable       = "DROP TABLE"
+	IfExists        = "IF EXISTS"
+	TruncateTable   = "TRUNCATE TABLE"
+	RestartIdentity = "RESTART IDENTITY"
+	Cascade         = "CASCADE"
+
+	ForUpdate  = "FOR UPDATE"
+	ForShare   = "FOR SHARE"
+	NoWait     = "NOWAIT"
+	SkipLocked = "SKIP LOCKED"
 
 	And              = "AND"
 	Or               = "OR"
@@ -52,6 +98,7 @@ const (
 	Null             = "NULL"
 	IsNull           = "IS NULL"
 	IsNotNull        = "IS NOT NULL"
+	NotNull          = "NOT NULL"
 	Is               = "IS"
 	IsNot            = "IS NOT"
 	LessThan         = "<"
@@ -60,6 +107,8 @@ const (
 	GreaterOrEquals  = ">="
 	Equals           = "="
 	NotEquals        = "<>"
+	NotDistinctFrom  = "IS NOT DISTINCT FROM"
+	Spaceship        = "<=>"
 	Between          = "BETWEEN"
 	Like             = "LIKE"
 	NotLike          = "NOT LIKE"
@@ -71,12 +120,31 @@ const (
 	Exists           = "EXISTS"
 	NotExists        = "NOT EXISTS"
 
+	Plus     = "+"
+	Minus    = "-"
+	Multiply = "*"
+	Divide   = "/"
+	Modulo   = "%"
+
 	Count = "COUNT"
 	Sum   = "SUM"
 	Avg   = "AVG"
 	Min   = "MIN"
 	Max   = "MAX"
 
+	Coalesce = "COALESCE"
+	Lower    = "LOWER"
+	Upper    = "UPPER"
+	Concat   = "CONCAT"
+	Cast     = "CAST"
+	Collate  = "COLLATE"
+
+	RowNumber   = "ROW_NUMBER"
+	Rank        = "RANK"
+	DenseRank   = "DENSE_RANK"
+	Over        = "OVER"
+	PartitionBy = "PARTITION BY"
+
 	BeginTran = "BEGIN TRAN"
 	Commit    = "COMMIT"
 	Rollback  = "ROLLBACK"
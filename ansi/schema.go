@@ -20,6 +20,15 @@ type DbTable struct {
 
 	// Columns is columns of this table
 	Columns []DbColumn
+
+	// PrimaryKey is primary key columns of this table, ordered by key position
+	PrimaryKey []string
+
+	// ForeignKeys is foreign keys of this table
+	ForeignKeys []DbForeignKey
+
+	// Indexes is indexes of this table
+	Indexes []DbIndex
 }
 
 func (t *DbTable) String() string {
@@ -70,6 +79,48 @@ type DbColumn struct {
 
 	// IsPrimaryKey
 	IsPrimaryKey bool
+
+	// Members is allowed values of enum or set column
+	Members []string
+
+	// HasDefault is true if column has default value, include DEFAULT NULL
+	HasDefault bool
+
+	// Default is default value of column, nil if no default or DEFAULT NULL
+	Default *string
+}
+
+// DbForeignKey is schema of foreign key constraint
+type DbForeignKey struct {
+	// Name is constraint name
+	Name string
+
+	// Columns is local columns, ordered by key position
+	Columns []string
+
+	// RefTable is referenced table
+	RefTable string
+
+	// RefColumns is referenced columns, ordered by key position
+	RefColumns []string
+
+	// OnDelete is on delete action, like CASCADE, RESTRICT
+	OnDelete string
+
+	// OnUpdate is on update action, like CASCADE, RESTRICT
+	OnUpdate string
+}
+
+// DbIndex is schema of index
+type DbIndex struct {
+	// Name is index name
+	Name string
+
+	// Columns is columns of index, ordered by position in index
+	Columns []string
+
+	// IsUnique
+	IsUnique bool
 }
 
 // DbFunction is schema of procedure / function
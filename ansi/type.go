@@ -11,10 +11,12 @@ const (
 	Date     DbType = 4
 	DateTime DbType = 5
 	Guid     DbType = 6
+	Json     DbType = 7
 
 	Int     = 11
 	Numeric = 12
 	Float   = 13
+	Uint    = 14
 
 	Var = 21
 
@@ -48,6 +50,8 @@ func (t DbType) String() string {
 		return "dateTime"
 	case Guid:
 		return "guid"
+	case Json:
+		return "json"
 
 	case Int:
 		return "int"
@@ -55,6 +59,8 @@ func (t DbType) String() string {
 		return "numeric"
 	case Float:
 		return "float"
+	case Uint:
+		return "uint"
 	case Var:
 		return "var"
 	}
@@ -66,9 +72,9 @@ func (t DbType) IsBoolean() bool {
 	return t == Boolean
 }
 
-// IsInteger return true if t is Int 
+// IsInteger return true if t is Int,Uint
 func (t DbType) IsInteger() bool {
-	return t == Int
+	return t == Int || t == Uint
 }
 
 // IsFloat return true if t is Float 
@@ -76,10 +82,10 @@ func (t DbType) IsFloat() bool {
 	return t == Float
 }
 
-// IsNumeric return true if t is Float,Int,Numeric
+// IsNumeric return true if t is Float,Int,Uint,Numeric
 func (t DbType) IsNumeric() bool {
 	switch t {
-	case Int, Numeric, Float:
+	case Int, Uint, Numeric, Float:
 		return true
 	}
 	return false
@@ -0,0 +1,340 @@
+package kdb
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// cachedSql is compiled sql of a statement, raw is sql before compact.
+// plan replay args of a hit if planned is true, otherwise a hit visit expression to collect args
+type cachedSql struct {
+	query   string
+	raw     string
+	plan    []planStep
+	planned bool
+}
+
+// planStep locate an arg, or a value written into sql like LIMIT 10, in expression
+type planStep struct {
+	// node, field & index identify value while compiling, like field Args of *RawSql, index is -1 if field isn't a slice
+	node  interface{}
+	field string
+	index int
+
+	// guard is true if value is written into sql, cached sql is only valid for same value
+	guard bool
+	value interface{}
+
+	// notNil is true if nil value would be written as NULL instead of parameter
+	notNil bool
+
+	// path is field or element index from root of expression to value, lens is length of slices on the way, -1 for field.
+	// node is at path[depth], its type is typ
+	path  []int
+	lens  []int
+	depth int
+	typ   reflect.Type
+}
+
+// CompileCache cache compiled sql of statements by key, key should identify the structure of statement,
+// expressions with same key should only differ in values.
+//
+// the first compile of a key record a plan, which is where each arg is in expression, a hit collects args by the plan
+// without visiting expression. values written into sql like LIMIT are guarded by the plan, a hit with different value
+// is compiled without cache. if sql depends on values in a way that plan can't replay, like IN list or nil value,
+// a hit visits expression to collect args and to verify that it writes the cached sql.
+// the plan checks length of slices on the way to values only, other structure difference under same key isn't detected
+type CompileCache struct {
+	// Driver is compiler used to compile expression
+	Driver *SqlDriver
+
+	items map[string]cachedSql
+	lock  sync.RWMutex
+}
+
+// NewCompileCache return *CompileCache with provided driver
+func NewCompileCache(driver *SqlDriver) *CompileCache {
+	return &CompileCache{
+		Driver: driver,
+		items:  make(map[string]cachedSql),
+	}
+}
+
+// Compile return cached sql of key and args of exp, compile exp and cache the sql if key isn't cached.
+// text and procedure aren't cached
+func (cc *CompileCache) Compile(key string, source string, exp Expression) (query string, args []interface{}, err error) {
+	if cc.Driver == nil {
+		err = errors.New("compile cache driver is nil")
+		return
+	}
+	if exp == nil {
+		err = newCompileError(CompileInvalid, NodeZero, "", "compile expression is nil")
+		return
+	}
+
+	switch exp.Node() {
+	case NodeQuery, NodeUpdate, NodeInsert, NodeDelete, NodeDropTable, NodeTruncate:
+	default:
+		return cc.Driver.Compile(source, exp)
+	}
+
+	cc.lock.RLock()
+	item, ok := cc.items[key]
+	cc.lock.RUnlock()
+
+	if ok && item.planned {
+		if args, ok = replayPlan(exp, item.plan); ok {
+			return item.query, args, nil
+		}
+		// a guarded value differs, cached sql of key is kept
+		return cc.Driver.Compile(source, exp)
+	}
+
+	sc := cc.Driver.newStmtCompiler()
+	if ok {
+		var matched bool
+		if args, matched, err = sc.matchArgs(exp, source, item.raw); err != nil {
+			return
+		}
+		if matched {
+			return item.query, args, nil
+		}
+		// sql of exp differs from cached sql, cached sql of key is kept
+		return sc.Compile(exp, source)
+	}
+
+	sc.planning = true
+	query, args, err = sc.Compile(exp, source)
+	sc.planning = false
+	if err != nil {
+		return
+	}
+
+	item = cachedSql{query: query, raw: query}
+	if sc.Compact {
+		item.raw = sc.w.String()
+	}
+	if !sc.planBroken {
+		item.plan, item.planned = resolvePlan(exp, sc.plan, len(args))
+	}
+	cc.lock.Lock()
+	if cc.items == nil {
+		cc.items = make(map[string]cachedSql)
+	}
+	cc.items[key] = item
+	cc.lock.Unlock()
+	return
+}
+
+// Len return count of cached sql
+func (cc *CompileCache) Len() int {
+	cc.lock.RLock()
+	defer cc.lock.RUnlock()
+	return len(cc.items)
+}
+
+// Remove remove cached sql of key
+func (cc *CompileCache) Remove(key string) {
+	cc.lock.Lock()
+	delete(cc.items, key)
+	cc.lock.Unlock()
+}
+
+// Clear remove all cached sql
+func (cc *CompileCache) Clear() {
+	cc.lock.Lock()
+	cc.items = make(map[string]cachedSql)
+	cc.lock.Unlock()
+}
+
+// planArg record that the last arg is value of field of node, nil value is written as NULL so it breaks the plan
+func (sc *StmtCompiler) planArg(node interface{}, field string, index int, value interface{}) {
+	if !sc.planning {
+		return
+	}
+	if value == nil {
+		sc.planBroken = true
+		return
+	}
+	sc.plan = append(sc.plan, planStep{node: node, field: field, index: index, notNil: true})
+}
+
+// planParameter record that the last arg is value of named parameter p, nil value is bound as parameter too
+func (sc *StmtCompiler) planParameter(p *Parameter) {
+	if sc.planning {
+		sc.plan = append(sc.plan, planStep{node: p, field: "Value", index: -1})
+	}
+}
+
+// planGuard record that value of field of node is written into sql
+func (sc *StmtCompiler) planGuard(node interface{}, field string, value interface{}) {
+	if sc.planning {
+		sc.plan = append(sc.plan, planStep{node: node, field: field, index: -1, guard: true, value: value})
+	}
+}
+
+// noPlan mark that sql depends on values in a way that plan can't replay
+func (sc *StmtCompiler) noPlan() {
+	if sc.planning {
+		sc.planBroken = true
+	}
+}
+
+// planNode is pointer of expression node
+type planNode struct {
+	typ reflect.Type
+	ptr uintptr
+}
+
+// resolvePlan find path of steps from exp by exported fields, nodes of steps are released so plan doesn't hold exp.
+// it return false if count of args is wrong or a node isn't found, like a node created while compiling
+func resolvePlan(exp Expression, steps []planStep, args int) ([]planStep, bool) {
+	type found struct {
+		path []int
+		lens []int
+	}
+
+	n := 0
+	nodes := make(map[planNode]*found, len(steps))
+	for i := 0; i < len(steps); i++ {
+		if !steps[i].guard {
+			n++
+		}
+		v := reflect.ValueOf(steps[i].node)
+		if v.Kind() != reflect.Ptr || v.IsNil() {
+			return nil, false
+		}
+		nodes[planNode{v.Type(), v.Pointer()}] = nil
+	}
+	if n != args {
+		return nil, false
+	}
+
+	visited := make(map[planNode]bool)
+	var walk func(v reflect.Value, path, lens []int)
+	walk = func(v reflect.Value, path, lens []int) {
+		switch v.Kind() {
+		case reflect.Ptr:
+			if v.IsNil() {
+				return
+			}
+			key := planNode{v.Type(), v.Pointer()}
+			if visited[key] {
+				return
+			}
+			visited[key] = true
+			if f, ok := nodes[key]; ok && f == nil {
+				nodes[key] = &found{path: append([]int(nil), path...), lens: append([]int(nil), lens...)}
+			}
+			walk(v.Elem(), path, lens)
+		case reflect.Interface:
+			if !v.IsNil() {
+				walk(v.Elem(), path, lens)
+			}
+		case reflect.Struct:
+			t := v.Type()
+			for i := 0; i < v.NumField(); i++ {
+				if t.Field(i).PkgPath == "" {
+					walk(v.Field(i), append(path, i), append(lens, -1))
+				}
+			}
+		case reflect.Slice, reflect.Array:
+			switch v.Type().Elem().Kind() {
+			case reflect.Ptr, reflect.Interface, reflect.Struct, reflect.Slice, reflect.Array:
+				for i := 0; i < v.Len(); i++ {
+					walk(v.Index(i), append(path, i), append(lens, v.Len()))
+				}
+			}
+		}
+	}
+	walk(reflect.ValueOf(exp), nil, nil)
+
+	plan := make([]planStep, len(steps))
+	for i := 0; i < len(steps); i++ {
+		step := steps[i]
+		v := reflect.ValueOf(step.node)
+		f := nodes[planNode{v.Type(), v.Pointer()}]
+		if f == nil {
+			return nil, false
+		}
+		field, ok := v.Type().Elem().FieldByName(step.field)
+		if !ok {
+			return nil, false
+		}
+
+		step.depth = len(f.path)
+		step.typ = v.Type().Elem()
+		step.path = append(append([]int(nil), f.path...), field.Index...)
+		step.lens = append([]int(nil), f.lens...)
+		for range field.Index {
+			step.lens = append(step.lens, -1)
+		}
+		if step.index >= 0 {
+			step.path = append(step.path, step.index)
+			step.lens = append(step.lens, v.Elem().FieldByIndex(field.Index).Len())
+		}
+		step.node = nil
+		plan[i] = step
+	}
+	return plan, true
+}
+
+// replayPlan collect args of exp by plan, ok is false if a guarded value differs or a value isn't found
+func replayPlan(exp Expression, plan []planStep) (args []interface{}, ok bool) {
+	root := reflect.ValueOf(exp)
+	for i := 0; i < len(plan); i++ {
+		step := &plan[i]
+		v, ok := step.locate(root)
+		if !ok {
+			return nil, false
+		}
+
+		value := v.Interface()
+		if step.guard {
+			if value != step.value {
+				return nil, false
+			}
+			continue
+		}
+		if step.notNil && value == nil {
+			return nil, false
+		}
+		if args == nil {
+			args = make([]interface{}, 0, len(plan))
+		}
+		args = append(args, value)
+	}
+	return args, true
+}
+
+// locate follow path of step from root, ok is false if expression doesn't have the path
+func (step *planStep) locate(v reflect.Value) (reflect.Value, bool) {
+	for i, index := range step.path {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return v, false
+			}
+			v = v.Elem()
+		}
+		if i == step.depth && v.Type() != step.typ {
+			return v, false
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			if index >= v.NumField() {
+				return v, false
+			}
+			v = v.Field(index)
+		case reflect.Slice, reflect.Array:
+			if v.Len() != step.lens[i] {
+				return v, false
+			}
+			v = v.Index(index)
+		default:
+			return v, false
+		}
+	}
+	return v, true
+}
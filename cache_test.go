@@ -0,0 +1,192 @@
+package kdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func cacheQuery(cint int, cstring string) *Query {
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint", "cstring")
+	q.Where.Equals("cint", cint).Like("cstring", cstring)
+	q.UseOrderBy().Asc("cint")
+	return q
+}
+
+func TestCompileCache(t *testing.T) {
+	driver := &SqlDriver{Dialecter: PostgreSQLDialecter{}}
+	cache := NewCompileCache(driver)
+
+	sql1, args1, err := cache.Compile("q", "source", cacheQuery(1, "a%"))
+	if err != nil {
+		t.Fatal("compile cache error", err)
+	}
+	if cache.Len() != 1 {
+		t.Error("compile cache should have 1 item", cache.Len())
+	}
+
+	sql2, args2, err := cache.Compile("q", "source", cacheQuery(2, "b%"))
+	if err != nil {
+		t.Fatal("compile cache hit error", err)
+	}
+	if sql1 != sql2 {
+		t.Error("compile cache hit should return cached sql", "\n", sql1, "\n", sql2)
+	}
+
+	want, wantArgs, _ := driver.Compile("source", cacheQuery(2, "b%"))
+	if sql2 != want || !reflect.DeepEqual(args2, wantArgs) {
+		t.Error("compile cache hit error", "want:", want, wantArgs, "actual:", sql2, args2)
+	}
+	if reflect.DeepEqual(args1, args2) {
+		t.Error("compile cache hit should collect args again", args1, args2)
+	}
+
+	q := cacheQuery(3, "c%")
+	q.Where.Equals("cfloat", 1.5)
+	sql3, args3, err := cache.Compile("q", "source", q)
+	want, wantArgs, _ = driver.Compile("source", q)
+	if err != nil || sql3 != want || !reflect.DeepEqual(args3, wantArgs) {
+		t.Error("compile cache miss match error", "want:", want, wantArgs, "actual:", sql3, args3, err)
+	}
+	if sql, _, _ := cache.Compile("q", "source", cacheQuery(4, "d%")); sql != sql1 {
+		t.Error("compile cache should keep cached sql", sql)
+	}
+
+	cache.Clear()
+	if cache.Len() != 0 {
+		t.Error("compile cache should be empty after clear", cache.Len())
+	}
+}
+
+func TestCompileCacheInlineValues(t *testing.T) {
+	driver := &SqlDriver{Dialecter: MysqlDialecter{}}
+	cache := NewCompileCache(driver)
+
+	newQuery := func(ids interface{}, offset, count int) *Query {
+		q := NewQuery("ttable", "")
+		q.Where.In("id", ids)
+		q.Limit(offset, count)
+		return q
+	}
+
+	cases := []*Query{
+		newQuery([]int{1, 2}, 0, 10),
+		newQuery([]int{3, 4}, 10, 10),
+		newQuery([]int{}, 0, 10),
+		newQuery(map[int]bool{5: true, 6: true}, 0, 10),
+		newQuery([]int{1, 2}, 0, 10),
+	}
+	for i, q := range cases {
+		actual, args, err := cache.Compile("k", "source", q)
+		want, wantArgs, _ := driver.Compile("source", q)
+		t.Log(actual, args)
+		if err != nil || actual != want || !reflect.DeepEqual(args, wantArgs) {
+			t.Error("compile cache with inline values error", i, "want:", want, wantArgs, "actual:", actual, args, err)
+		}
+	}
+
+	compact := &SqlDriver{Dialecter: MysqlDialecter{}, Compact: true}
+	cache = NewCompileCache(compact)
+	for i, q := range cases {
+		actual, _, err := cache.Compile("k", "source", q)
+		want, _, _ := compact.Compile("source", q)
+		if err != nil || actual != want {
+			t.Error("compile compact cache with inline values error", i, "want:", want, "actual:", actual, err)
+		}
+	}
+}
+
+func TestCompileCachePlan(t *testing.T) {
+	driver := &SqlDriver{Dialecter: PostgreSQLDialecter{}}
+	cache := NewCompileCache(driver)
+
+	newQuery := func(cint int, cstring string, offset int) *Query {
+		q := cacheQuery(cint, cstring)
+		q.Where.Raw("cfloat > ?", float64(cint))
+		q.Limit(offset, 10)
+		return q
+	}
+	newUpdate := func(cstring interface{}) *Update {
+		u := NewUpdate("ttable").Set("cstring", cstring)
+		u.Where.Equals("cint", 1)
+		return u
+	}
+
+	cache.Compile("q", "source", newQuery(1, "a%", 0))
+	cache.Compile("u", "source", newUpdate("a"))
+	if item := cache.items["q"]; !item.planned || len(item.plan) == 0 {
+		t.Fatal("compile cache should plan args of query", item.plan)
+	}
+
+	cases := []struct {
+		key string
+		exp Expression
+	}{
+		{"q", newQuery(2, "b%", 0)},
+		{"q", newQuery(3, "c%", 20)},
+		{"u", newUpdate("b")},
+		{"u", newUpdate(nil)},
+	}
+	for i, c := range cases {
+		actual, args, err := cache.Compile(c.key, "source", c.exp)
+		want, wantArgs, _ := driver.Compile("source", c.exp)
+		t.Log(actual, args)
+		if err != nil || actual != want || !reflect.DeepEqual(args, wantArgs) {
+			t.Error("compile cache plan error", i, "want:", want, wantArgs, "actual:", actual, args, err)
+		}
+	}
+
+	want, _, _ := driver.Compile("source", newQuery(4, "d%", 0))
+	if actual, _, _ := cache.Compile("q", "source", newQuery(4, "d%", 0)); actual != want {
+		t.Error("compile cache should keep cached sql after guard mismatch", actual)
+	}
+}
+
+func TestCollectArgs(t *testing.T) {
+	q := cacheQuery(1, "a%")
+	sc := NewStmtCompiler(MysqlDialecter{})
+
+	_, want, err := sc.Compile(q, "source")
+	if err != nil {
+		t.Fatal("compile error", err)
+	}
+
+	args, err := sc.CollectArgs(q, "source")
+	if err != nil {
+		t.Fatal("collect args error", err)
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Error("collect args error", "want:", want, "actual:", args)
+	}
+	if sc.w.Len() != 0 {
+		t.Error("collect args should not write sql", sc.w.String())
+	}
+}
+
+func BenchmarkCompileNoCache(b *testing.B) {
+	driver := &SqlDriver{Dialecter: MysqlDialecter{}}
+	q := cacheQuery(1, "a%")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := driver.Compile("source", q); err != nil {
+			b.Fatal("compile error", err)
+		}
+	}
+}
+
+// BenchmarkCompileCache measure cache hit, args are collected by plan without visiting expression,
+// compare with BenchmarkCompileNoCache for cost of visiting expression and building sql
+func BenchmarkCompileCache(b *testing.B) {
+	cache := NewCompileCache(&SqlDriver{Dialecter: MysqlDialecter{}})
+	q := cacheQuery(1, "a%")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := cache.Compile("q", "source", q); err != nil {
+			b.Fatal("compile error", err)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package kdb
 
 import (
+	"errors"
 	"fmt"
 	"github.com/sdming/kdb/ansi"
+	"sort"
 )
 
 const nilStr string = "<nil>"
@@ -15,8 +17,8 @@ type Parameter struct {
 	// Value is value of this parameter
 	Value interface{}
 
-	// // DbType is data type
-	// DbType ansi.DbType
+	// DbType is data type, value of output parameter is converted to it, Zero means doesn't convert
+	DbType ansi.DbType
 
 	// Dir is direction, in,out, inout or return 
 	Dir ansi.Dir
@@ -139,6 +141,12 @@ func (pc *Procedure) SetDir(name string, value interface{}, dir ansi.Dir) *Proce
 	return pc
 }
 
+// SetOut append a output parameter with data type
+func (pc *Procedure) SetOut(name string, dbType ansi.DbType) *Procedure {
+	pc.Parameter(&Parameter{Name: name, Dir: ansi.DirOut, DbType: dbType})
+	return pc
+}
+
 // ReturnParameterName return parameter name if parameter is ansi.DirReturn
 func (pc *Procedure) ReturnParameterName() string {
 	l := len(pc.Parameters)
@@ -151,6 +159,16 @@ func (pc *Procedure) ReturnParameterName() string {
 	return ""
 }
 
+// ReturnValue return value of return parameter, return false if there isn't return parameter
+func (pc *Procedure) ReturnValue() (interface{}, bool) {
+	for i := 0; i < len(pc.Parameters); i++ {
+		if p := pc.Parameters[i]; p.Dir == ansi.DirReturn {
+			return p.Value, true
+		}
+	}
+	return nil, false
+}
+
 // HasOutParameter return true if any parameter is output/inputoutput
 func (t *Procedure) HasOutParameter() bool {
 	for i := 0; i < len(t.Parameters); i++ {
@@ -161,6 +179,38 @@ func (t *Procedure) HasOutParameter() bool {
 	return false
 }
 
+// validate check directions of parameters, there is at most one return parameter,
+// and output/inputoutput/return parameter should have name
+func (pc *Procedure) validate() error {
+	returnName := ""
+	hasReturn := false
+	for i := 0; i < len(pc.Parameters); i++ {
+		p := pc.Parameters[i]
+		if p == nil {
+			return newCompileError(CompileInvalid, NodeProcedure, "", "parameter of procedure is nil:"+pc.Name)
+		}
+
+		switch p.Dir {
+		case ansi.DirIn:
+			continue
+		case ansi.DirOut, ansi.DirInOut:
+		case ansi.DirReturn:
+			if hasReturn {
+				return newCompileError(CompileInvalid, NodeProcedure, p.Name, fmt.Sprintf("procedure %s has more than one return parameter: %s, %s", pc.Name, returnName, p.Name))
+			}
+			hasReturn = true
+			returnName = p.Name
+		default:
+			return newCompileError(CompileInvalid, NodeProcedure, p.Name, fmt.Sprintf("parameter %s of procedure %s has invalid direction: %d", p.Name, pc.Name, p.Dir))
+		}
+
+		if p.Name == "" {
+			return newCompileError(CompileInvalid, NodeProcedure, "", fmt.Sprintf("output or return parameter %d of procedure %s doesn't have name", i, pc.Name))
+		}
+	}
+	return nil
+}
+
 // FindParameter return a parameter by name
 func (pc *Procedure) FindParameter(name string) (*Parameter, bool) {
 	l := len(pc.Parameters)
@@ -192,6 +242,49 @@ func NewProcedure(name string) *Procedure {
 	return &Procedure{Name: name}
 }
 
+// NewProcedureFromSchema return a *Procedure built from schema of function, parameters are ordered by position
+// and take direction and data type from schema, args are values of input/inputoutput parameters in order.
+// name is qualified by schema of function if it's not empty
+func NewProcedureFromSchema(fn *ansi.DbFunction, args ...interface{}) (*Procedure, error) {
+	if fn == nil || fn.Name == "" {
+		return nil, errors.New("function is nil or name of function is empty")
+	}
+
+	params := make([]ansi.DbParameter, len(fn.Parameters))
+	copy(params, fn.Parameters)
+	sort.SliceStable(params, func(i, j int) bool {
+		return params[i].Position < params[j].Position
+	})
+
+	name := fn.Name
+	if fn.Schema != "" {
+		name = fn.Schema + ansi.Split + fn.Name
+	}
+	sp := NewProcedure(name)
+
+	index := 0
+	for i := 0; i < len(params); i++ {
+		p := &Parameter{
+			Name:   params[i].Name,
+			Dir:    params[i].Dir,
+			DbType: params[i].DbType,
+		}
+		if p.IsIn() {
+			if index >= len(args) {
+				return nil, fmt.Errorf("function %s has more input parameters than %d args", fn.Name, len(args))
+			}
+			p.Value = args[index]
+			index++
+		}
+		sp.Parameter(p)
+	}
+
+	if index != len(args) {
+		return nil, fmt.Errorf("function %s has %d input parameters, but got %d args", fn.Name, index, len(args))
+	}
+	return sp, nil
+}
+
 // Insert is sql "insert into x values(...)" clause
 type Insert struct {
 	// Table is table to insert
@@ -199,6 +292,12 @@ type Insert struct {
 
 	// Sets is set[column=value]
 	Sets []*Set
+
+	// Columns is columns to insert when rows come from Query
+	Columns []string
+
+	// Query is source of rows, INSERT INTO table (columns) SELECT ...
+	Query *Query
 }
 
 // String
@@ -207,6 +306,9 @@ func (ist *Insert) String() string {
 		return nilStr
 	}
 
+	if ist.Query != nil {
+		return fmt.Sprint(ansi.Insert, " ", ist.Table, " ", ist.Columns, "\n", ist.Query)
+	}
 	return fmt.Sprint(ansi.Insert, " ", ist.Table, " ", ist.Sets)
 }
 
@@ -232,6 +334,13 @@ func (ist *Insert) Append(a *Set) {
 	ist.Sets = append(ist.Sets, a)
 }
 
+// Select set query as source of rows to insert into columns
+func (ist *Insert) Select(query *Query, columns ...string) *Insert {
+	ist.Query = query
+	ist.Columns = columns
+	return ist
+}
+
 // NewInsert return *Insert with provided table
 func NewInsert(table string) *Insert {
 	return &Insert{Table: newTable(table, ""), Sets: make([]*Set, 0, _defaultCapicity)}
@@ -245,6 +354,9 @@ type Update struct {
 	// Sets is set[column=value]
 	Sets []*Set
 
+	// From is from clause, tables joined to update
+	From *From
+
 	// Where is where clause
 	Where *Where
 
@@ -254,6 +366,9 @@ type Update struct {
 	// Count is limit count
 	Count int
 
+	// AllowEmptyWhere allow to update all rows of table if where is empty
+	AllowEmptyWhere bool
+
 	//Output      *Output
 }
 
@@ -262,7 +377,7 @@ func (u *Update) String() string {
 	if u == nil {
 		return nilStr
 	}
-	return fmt.Sprint(ansi.Update, " ", u.Table, " ", ansi.Set, " ", u.Sets, "\n", u.Where, "\n", u.OrderBy, "\n", ansi.Limit, u.Count)
+	return fmt.Sprint(ansi.Update, " ", u.Table, " ", ansi.Set, " ", u.Sets, "\n", u.From, "\n", u.Where, "\n", u.OrderBy, "\n", ansi.Limit, u.Count)
 }
 
 // Node return NodeUpdate
@@ -284,6 +399,12 @@ func (u *Update) Append(a *Set) {
 	u.Sets = append(u.Sets, a)
 }
 
+// UseFrom new a *From and set to u.From
+func (u *Update) UseFrom(table, alias string) *From {
+	u.From = NewFrom(table, alias)
+	return u.From
+}
+
 // Limit set rows count to update
 func (u *Update) Limit(count int) *Update {
 	u.Count = count
@@ -322,6 +443,9 @@ type Delete struct {
 	// Count is limit count
 	Count int
 
+	// AllowEmptyWhere allow to delete all rows of table if where is empty
+	AllowEmptyWhere bool
+
 	//Output  *Output
 }
 
@@ -372,8 +496,69 @@ func NewDelete(table string) *Delete {
 	}
 }
 
+// DropTable is sql "drop table x" statement
+type DropTable struct {
+	// Table is table to drop
+	Table *Table
+
+	// IfExists means drop table if exists x
+	IfExists bool
+}
+
+// String
+func (d *DropTable) String() string {
+	if d == nil {
+		return nilStr
+	}
+	if d.IfExists {
+		return fmt.Sprint(ansi.DropTable, " ", ansi.IfExists, " ", d.Table)
+	}
+	return fmt.Sprint(ansi.DropTable, " ", d.Table)
+}
+
+// Node return NodeDropTable
+func (d *DropTable) Node() NodeType {
+	return NodeDropTable
+}
+
+// NewDropTable return a *DropTable with provided table
+func NewDropTable(table string) *DropTable {
+	return &DropTable{Table: newTable(table, "")}
+}
+
+// Truncate is sql "truncate table x" statement
+type Truncate struct {
+	// Table is table to truncate
+	Table *Table
+
+	// RestartIdentity means reset sequences owned by columns, postgres only
+	RestartIdentity bool
+
+	// Cascade means truncate tables that have foreign-key references, postgres only
+	Cascade bool
+}
+
+// String
+func (t *Truncate) String() string {
+	if t == nil {
+		return nilStr
+	}
+	return fmt.Sprint(ansi.TruncateTable, " ", t.Table)
+}
+
+// Node return NodeTruncate
+func (t *Truncate) Node() NodeType {
+	return NodeTruncate
+}
+
+// NewTruncate return a *Truncate with provided table
+func NewTruncate(table string) *Truncate {
+	return &Truncate{Table: newTable(table, "")}
+}
+
 // Query is sql query clause
 type Query struct {
+	With       *With
 	Select     *Select
 	From       *From
 	Where      *Where
@@ -381,8 +566,92 @@ type Query struct {
 	Having     *Having
 	OrderBy    *OrderBy
 	IsDistinct bool
+	DistinctOn []Expression
 	Offset     int
 	Count      int
+	Lock       *Lock
+	SetOps     []*SetOperation
+}
+
+// SetOperator is operator that combines results of queries, like UNION
+type SetOperator string
+
+// String
+func (op SetOperator) String() string {
+	return string(op)
+}
+
+const (
+	Union        SetOperator = ansi.Union
+	UnionAll     SetOperator = ansi.UnionAll
+	Except       SetOperator = ansi.Except
+	ExceptAll    SetOperator = ansi.ExceptAll
+	Intersect    SetOperator = ansi.Intersect
+	IntersectAll SetOperator = ansi.IntersectAll
+)
+
+// IsValid return true if op is a known set operator
+func (op SetOperator) IsValid() bool {
+	switch op {
+	case Union, UnionAll, Except, ExceptAll, Intersect, IntersectAll:
+		return true
+	}
+	return false
+}
+
+// SetOperation is a query combined by set operator, like UNION SELECT ...
+type SetOperation struct {
+	Operator SetOperator
+	Query    *Query
+}
+
+// String
+func (so *SetOperation) String() string {
+	if so == nil {
+		return nilStr
+	}
+	return fmt.Sprint(so.Operator, "\n", so.Query)
+}
+
+// LockMode is row locking mode of query
+type LockMode int
+
+const (
+	LockUpdate LockMode = 1
+	LockShare  LockMode = 2
+)
+
+// String
+func (m LockMode) String() string {
+	switch m {
+	case LockUpdate:
+		return ansi.ForUpdate
+	case LockShare:
+		return ansi.ForShare
+	}
+	return "Unknow"
+}
+
+// Lock is locking clause of query, like FOR UPDATE NOWAIT
+type Lock struct {
+	Mode       LockMode
+	NoWait     bool
+	SkipLocked bool
+}
+
+// String
+func (l *Lock) String() string {
+	if l == nil {
+		return nilStr
+	}
+	s := l.Mode.String()
+	if l.NoWait {
+		s += " " + ansi.NoWait
+	}
+	if l.SkipLocked {
+		s += " " + ansi.SkipLocked
+	}
+	return s
 }
 
 // String
@@ -391,10 +660,24 @@ func (q *Query) String() string {
 		return nilStr
 	}
 	distinct := ""
-	if q.IsDistinct {
+	if len(q.DistinctOn) > 0 {
+		distinct = fmt.Sprint(ansi.Distinct, " ", ansi.On, " ", q.DistinctOn)
+	} else if q.IsDistinct {
 		distinct = ansi.Distinct
 	}
-	return fmt.Sprint(ansi.Select, " ", distinct, " ", q.Select, "\n", q.From, "\n", q.Where, q.GroupBy, "\n", q.Having, "\n", q.OrderBy, "\n", ansi.Limit, q.Offset, q.Count)
+	with := ""
+	if q.With != nil {
+		with = fmt.Sprint(q.With, "\n")
+	}
+	lock := ""
+	if q.Lock != nil {
+		lock = fmt.Sprint("\n", q.Lock)
+	}
+	setOps := ""
+	for _, so := range q.SetOps {
+		setOps += fmt.Sprint("\n", so)
+	}
+	return fmt.Sprint(with, ansi.Select, " ", distinct, " ", q.Select, "\n", q.From, "\n", q.Where, q.GroupBy, "\n", q.Having, setOps, "\n", q.OrderBy, "\n", ansi.Limit, q.Offset, q.Count, lock)
 }
 
 // Node return NodeQuery
@@ -402,6 +685,18 @@ func (q *Query) Node() NodeType {
 	return NodeQuery
 }
 
+// ForUpdate set q.Lock to FOR UPDATE then return it
+func (q *Query) ForUpdate() *Lock {
+	q.Lock = &Lock{Mode: LockUpdate}
+	return q.Lock
+}
+
+// ForShare set q.Lock to FOR SHARE then return it
+func (q *Query) ForShare() *Lock {
+	q.Lock = &Lock{Mode: LockShare}
+	return q.Lock
+}
+
 // Limit set offset and count
 func (q *Query) Limit(offset, count int) *Query {
 	q.Offset = offset
@@ -415,6 +710,58 @@ func (q *Query) Distinct() *Query {
 	return q
 }
 
+// DistinctOnColumn append columns to DistinctOn, it's only supported by postgres
+func (q *Query) DistinctOnColumn(columns ...string) *Query {
+	for _, column := range columns {
+		q.DistinctOn = append(q.DistinctOn, Column(column))
+	}
+	return q
+}
+
+// Combine append query to q.SetOps with operator op, ORDER BY and LIMIT of q apply to the combined result
+func (q *Query) Combine(op SetOperator, query *Query) *Query {
+	q.SetOps = append(q.SetOps, &SetOperation{Operator: op, Query: query})
+	return q
+}
+
+// Union combine query by UNION
+func (q *Query) Union(query *Query) *Query {
+	return q.Combine(Union, query)
+}
+
+// UnionAll combine query by UNION ALL
+func (q *Query) UnionAll(query *Query) *Query {
+	return q.Combine(UnionAll, query)
+}
+
+// Except combine query by EXCEPT
+func (q *Query) Except(query *Query) *Query {
+	return q.Combine(Except, query)
+}
+
+// ExceptAll combine query by EXCEPT ALL
+func (q *Query) ExceptAll(query *Query) *Query {
+	return q.Combine(ExceptAll, query)
+}
+
+// Intersect combine query by INTERSECT
+func (q *Query) Intersect(query *Query) *Query {
+	return q.Combine(Intersect, query)
+}
+
+// IntersectAll combine query by INTERSECT ALL
+func (q *Query) IntersectAll(query *Query) *Query {
+	return q.Combine(IntersectAll, query)
+}
+
+// UseWith initialize q.With then return it
+func (q *Query) UseWith() *With {
+	if q.With == nil {
+		q.With = NewWith()
+	}
+	return q.With
+}
+
 // UseGroupBy initialize q.GroupBy then return it
 func (q *Query) UseGroupBy() *GroupBy {
 	if q.GroupBy == nil {
@@ -447,3 +794,46 @@ func NewQuery(table, alias string) *Query {
 		Select: NewSelect(),
 	}
 }
+
+// CountQuery return SELECT COUNT(*) FROM (q) AS sub, order by, limit and lock of q are dropped, q isn't changed
+func CountQuery(q *Query) *Query {
+	if q == nil {
+		return nil
+	}
+
+	inner := *q
+	inner.With = nil
+	inner.OrderBy = nil
+	inner.Offset = 0
+	inner.Count = 0
+	inner.Lock = nil
+
+	count := &Query{
+		With:   q.With,
+		From:   &From{Table: NewDerivedTable(&inner, "sub")},
+		Where:  NewWhere(),
+		Select: NewSelect(),
+	}
+	count.Select.CountAll("")
+	return count
+}
+
+// Paginate return copy of q with offset and count of page, and count query of q, page start from 1
+func Paginate(q *Query, page, size int) (data *Query, count *Query, err error) {
+	if q == nil {
+		err = errors.New("paginate query is nil")
+		return
+	}
+	if page < 1 {
+		err = errors.New("paginate page should be >= 1")
+		return
+	}
+	if size <= 0 {
+		err = errors.New("paginate size should be > 0")
+		return
+	}
+
+	d := *q
+	d.Limit((page-1)*size, size)
+	return &d, CountQuery(q), nil
+}
@@ -1,6 +1,7 @@
 package kdb
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -22,7 +23,11 @@ const (
 
 // DB is wrap of *sql.DB
 type DB struct {
-	DSN     *DSN
+	DSN *DSN
+
+	// ExplainFormat is output format of mysql explain, e.g. JSON
+	ExplainFormat string
+
 	innerdb *sql.DB
 	state   state
 }
@@ -101,168 +106,37 @@ func (db *DB) dialecter() (dialect Dialecter, err error) {
 
 // Function return schema of store procedure
 func (db *DB) Function(name string) (fn *ansi.DbFunction, err error) {
-	if err := db.Open(); err != nil {
-		return nil, err
-	}
-
-	var dialect Dialecter
-	if dialect, err = db.dialecter(); err != nil {
-		return
-	}
-	query := dialect.FunctionSql(name)
-	if query == "" {
-		if schm, ok := dialect.(Schemaer); ok {
-			return schm.Function(db.innerdb, name)
-		}
-		err = errors.New("driver doesn't support function schema:" + db.DSN.Driver)
+	if err = db.Open(); err != nil {
 		return
 	}
-
-	var rows *sql.Rows
-	if rows, err = db.Query(query); err != nil {
-		return
-	}
-
-	var f *ansi.DbFunction
-	for rows.Next() {
-		ff := ansi.NewFunction()
-
-		if err = rows.Scan(&ff.Catalog, &ff.Schema, &ff.Name); err != nil {
-			return
-		} else {
-			f = ff
-		}
-	}
-	if err = rows.Err(); err != nil {
-		return
-	}
-
-	if f == nil {
-		err = errors.New("function doesn't exist:" + name)
-		return
-	}
-
-	query = dialect.ParametersSql(name)
-	if query == "" {
-		err = errors.New("driver doesn't support function parameters schema:" + db.DSN.Driver)
-		return
-	}
-	if rows, err = db.Query(query); err != nil {
-		return
-	}
-
-	for rows.Next() {
-		p := ansi.DbParameter{}
-		dir := ""
-		if err = rows.Scan(&p.Name, &p.Position, &dir, &p.NativeType, &p.Size, &p.Precision, &p.Scale); err != nil {
-			return
-		} else {
-			p.DbType = dialect.DbType(p.NativeType)
-			switch dir {
-			case "IN":
-				p.Dir = ansi.DirIn
-			case "INOUT":
-				p.Dir = ansi.DirInOut
-			case "OUT":
-				p.Dir = ansi.DirOut
-			default:
-				p.Dir = ansi.DirIn
-			}
-			f.Parameters = append(f.Parameters, p)
-		}
-	}
-	if err = rows.Err(); err != nil {
+	if _, err = db.dialecter(); err != nil {
 		return
 	}
-
-	fn = f
-	return
-
+	return NewSqlSchemaer(db.innerdb, db.DSN.Driver).Function(name)
 }
 
 // Table return schema of table,view
 func (db *DB) Table(name string) (table *ansi.DbTable, err error) {
-	if err := db.Open(); err != nil {
-		return nil, err
-	}
-
-	var dialect Dialecter
-	if dialect, err = db.dialecter(); err != nil {
+	if err = db.Open(); err != nil {
 		return
 	}
-	query := dialect.TableSql(name)
-	if query == "" {
-		if schm, ok := dialect.(Schemaer); ok {
-			return schm.Table(db.innerdb, name)
-		}
-		err = errors.New("driver doesn't support table schema:" + db.DSN.Driver)
+	if _, err = db.dialecter(); err != nil {
 		return
 	}
-
-	var rows *sql.Rows
-	if rows, err = db.Query(query); err != nil {
-		return
-	}
-
-	var t *ansi.DbTable
-	for rows.Next() {
-		tt := ansi.NewTable()
-
-		if err = rows.Scan(&tt.Catalog, &tt.Schema, &tt.Name, &tt.Type); err != nil {
-			//TODO:
-		} else {
-			t = tt
-		}
-	}
-	if err = rows.Err(); err != nil {
-		return
-	}
-
-	if t == nil {
-		err = errors.New("table doesn't exist:" + name)
-		return
-	}
-
-	query = dialect.ColumnsSql(name)
-	if query == "" {
-		err = errors.New("driver doesn't support columns schema:" + db.DSN.Driver)
-		return
-	}
-	if rows, err = db.Query(query); err != nil {
-		return
-	}
-
-	for rows.Next() {
-		col := ansi.DbColumn{}
-
-		if err = rows.Scan(&col.Name, &col.Position, &col.IsNullable, &col.NativeType, &col.Size, &col.Precision, &col.Scale, &col.IsAutoIncrement, &col.IsReadOnly, &col.IsPrimaryKey); err != nil {
-			return
-		} else {
-			col.DbType = dialect.DbType(col.NativeType)
-			t.Columns = append(t.Columns, col)
-		}
-	}
-
-	if err = rows.Err(); err != nil {
-		return
-	}
-
-	if len(t.Columns) == 0 {
-		err = errors.New("table columns doesn't exist:" + name)
-		return
-	}
-
-	table = t
-	return
-
+	return NewSqlSchemaer(db.innerdb, db.DSN.Driver).Table(name)
 }
 
 // Query executes a query that returns *sql.Rows
 func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext executes a query that returns *sql.Rows, ctx can cancel the query
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
 	if err := db.Open(); err != nil {
 		return nil, err
 	}
-	rows, err := db.innerdb.Query(query, args...)
+	rows, err := db.innerdb.QueryContext(ctx, query, args...)
 	if LogLevel >= LogDebug {
 		logDebug("DB query:", query, args, err)
 	}
@@ -272,11 +146,16 @@ func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
 
 // Exec executes a query that return sql.Result
 func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext executes a query that return sql.Result, ctx can cancel the query
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
 	if err := db.Open(); err != nil {
 		return nil, err
 	}
 
-	result, err := db.innerdb.Exec(query, args...)
+	result, err := db.innerdb.ExecContext(ctx, query, args...)
 	if LogLevel >= LogDebug {
 		logDebug("DB exec:", query, args, result, err)
 	}
@@ -334,37 +213,65 @@ func (db *DB) parseText(query string, args Getter) (*Text, error) {
 
 // QueryExp query a expression
 func (db *DB) QueryExp(exp Expression) (*sql.Rows, error) {
+	return db.QueryExpContext(context.Background(), exp)
+}
+
+// QueryExpContext query a expression, ctx can cancel the query
+func (db *DB) QueryExpContext(ctx context.Context, exp Expression) (*sql.Rows, error) {
 	sql, args, err := db.Compile(exp)
 	if err != nil {
 		return nil, err
 	}
 
+	return db.QueryContext(ctx, sql, args...)
+}
+
+// Explain query plan of a expression
+func (db *DB) Explain(exp Expression, analyze bool) (*sql.Rows, error) {
+	sql, args, err := db.Compile(exp)
+	if err != nil {
+		return nil, err
+	}
+
+	if sql, err = explainQuery(db.DSN.Driver, sql, analyze, db.ExplainFormat); err != nil {
+		return nil, err
+	}
 	return db.Query(sql, args...)
 }
 
 // ExecExp execute a expression
 func (db *DB) ExecExp(exp Expression) (sql.Result, error) {
+	return db.ExecExpContext(context.Background(), exp)
+}
+
+// ExecExpContext execute a expression, ctx can cancel the execution
+func (db *DB) ExecExpContext(ctx context.Context, exp Expression) (sql.Result, error) {
 	sql, args, err := db.Compile(exp)
 	if err != nil {
 		return nil, err
 	}
 
-	return db.Exec(sql, args...)
+	return db.ExecContext(ctx, sql, args...)
 }
 
 // Compile compile expression to native sql
 func (db *DB) Compile(exp Expression) (sql string, args []interface{}, err error) {
-	if db.DSN == nil {
+	return compileDSN(db.DSN, exp)
+}
+
+// compileDSN compile expression by compiler of dsn driver
+func compileDSN(dsn *DSN, exp Expression) (sql string, args []interface{}, err error) {
+	if dsn == nil {
 		err = errors.New("kdb compile expression error, DSN is nil")
 		return
 	}
 
 	var compiler Compiler
-	compiler, err = GetCompiler(db.DSN.Driver)
+	compiler, err = GetCompiler(dsn.Driver)
 	if err != nil {
 		return
 	}
-	sql, args, err = compiler.Compile(db.DSN.Source, exp)
+	sql, args, err = compiler.Compile(dsn.Source, exp)
 	return
 }
 
@@ -401,8 +308,9 @@ func (db *DB) buildProcedure(name string, args Getter) (*Procedure, error) {
 	for i := 0; i < l; i++ {
 		p := fn.Parameters[i]
 		spp := &Parameter{
-			Name: p.Name,
-			Dir:  fn.Parameters[i].Dir,
+			Name:   p.Name,
+			Dir:    fn.Parameters[i].Dir,
+			DbType: p.DbType,
 		}
 
 		var v interface{}
@@ -434,6 +342,86 @@ func (db *DB) QueryFunc(name string, args Getter) (*sql.Rows, error) {
 	return rows, err
 }
 
+// ExecProcedure execute store procedure, return result sets of procedure as maps.
+// for mysql, values of output parameters are read from the last SELECT @out and set to Value of parameters,
+// driver should allow multiple statements with args, for go-sql-driver/mysql the DSN must set both
+// multiStatements=true and interpolateParams=true
+func (db *DB) ExecProcedure(sp *Procedure) (sets [][]map[string]interface{}, err error) {
+	if sp == nil {
+		return nil, errors.New("procedure is nil")
+	}
+
+	dialect, err := db.dialecter()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryExp(sp)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for {
+		var columns []string
+		if columns, err = rows.Columns(); err != nil {
+			return nil, err
+		}
+		// statement like SET @p = ? doesn't return any column
+		if len(columns) > 0 {
+			var set []map[string]interface{}
+			if set, err = readMaps(rows); err != nil {
+				return nil, err
+			}
+			sets = append(sets, set)
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if dialect.Name() != "mysql" || (!sp.HasOutParameter() && sp.ReturnParameterName() == "") {
+		return sets, nil
+	}
+
+	if len(sets) == 0 || len(sets[len(sets)-1]) != 1 {
+		return sets, errors.New("procedure doesn't return values of output parameters:" + sp.Name)
+	}
+	outputs := sets[len(sets)-1][0]
+	sets = sets[:len(sets)-1]
+	if err = setProcedureOutputs(sp, outputs); err != nil {
+		return sets, err
+	}
+	return sets, nil
+}
+
+// setProcedureOutputs set value of output parameter from column @name or name, value is converted to DbType of parameter
+func setProcedureOutputs(sp *Procedure, outputs map[string]interface{}) error {
+	for _, p := range sp.Parameters {
+		if p == nil || !(p.IsOut() || p.Dir == ansi.DirReturn) {
+			continue
+		}
+
+		v, ok := outputs["@"+p.Name]
+		if !ok {
+			v, ok = outputs[p.Name]
+		}
+		if !ok {
+			continue
+		}
+
+		cv, err := coerceValue(v, p.DbType)
+		if err != nil {
+			return fmt.Errorf("convert output parameter %s error: %v", p.Name, err)
+		}
+		p.Value = cv
+	}
+	return nil
+}
+
 // ExecFunc exec a store procedure
 func (db *DB) ExecFunc(name string, args Getter) (sql.Result, error) {
 	sp, err := db.buildProcedure(name, args)
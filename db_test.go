@@ -0,0 +1,365 @@
+package kdb
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"errors"
+	"github.com/sdming/kdb/ansi"
+	"reflect"
+	"testing"
+	"time"
+)
+
+/*
+CREATE TABLE tpk (
+	cint 		int not null,
+	cstring 	varchar(100) not null,
+	cfloat 		float,
+	PRIMARY KEY (cstring, cint)
+);
+
+CREATE TABLE tfk (
+	id 			int not null primary key,
+	pk_int 		int not null,
+	pk_string 	varchar(100) not null,
+	FOREIGN KEY fk_tpk (pk_string, pk_int) REFERENCES tpk(cstring, cint) ON DELETE CASCADE
+);
+
+CREATE TABLE tindex (
+	id 			int not null primary key,
+	cint 		int,
+	cstring 	varchar(100),
+	UNIQUE INDEX ux_tindex (cstring, cint)
+);
+
+*/
+
+func TestTablePrimaryKey(t *testing.T) {
+	db := NewDB("demo")
+	table, err := db.Table("tpk")
+	if err != nil {
+		t.Error("Table error", err)
+		return
+	}
+	t.Log(table)
+
+	want := []string{"cstring", "cint"}
+	if !reflect.DeepEqual(table.PrimaryKey, want) {
+		t.Error("primary key error", table.PrimaryKey, want)
+	}
+}
+
+func TestPrimaryKeyColumns(t *testing.T) {
+	columns := []ansi.DbColumn{
+		{Name: "cint", IsPrimaryKey: true},
+		{Name: "cstring"},
+		{Name: "cfloat", IsPrimaryKey: true},
+	}
+
+	want := []string{"cint", "cfloat"}
+	if keys := primaryKeyColumns(columns); !reflect.DeepEqual(keys, want) {
+		t.Error("primary key columns error", keys, want)
+	}
+}
+
+func TestTableForeignKey(t *testing.T) {
+	db := NewDB("demo")
+	table, err := db.Table("tfk")
+	if err != nil {
+		t.Error("Table error", err)
+		return
+	}
+	t.Log(table)
+
+	if len(table.ForeignKeys) != 1 {
+		t.Error("foreign keys error", table.ForeignKeys)
+		return
+	}
+
+	fk := table.ForeignKeys[0]
+	if fk.RefTable != "tpk" || fk.OnDelete != "CASCADE" ||
+		!reflect.DeepEqual(fk.Columns, []string{"pk_string", "pk_int"}) ||
+		!reflect.DeepEqual(fk.RefColumns, []string{"cstring", "cint"}) {
+		t.Error("foreign key error", fk)
+	}
+}
+
+func TestAppendForeignKey(t *testing.T) {
+	var fks []ansi.DbForeignKey
+	fks = appendForeignKey(fks, "fk_a", "a1", "ta", "id1", "CASCADE", "RESTRICT")
+	fks = appendForeignKey(fks, "fk_a", "a2", "ta", "id2", "CASCADE", "RESTRICT")
+	fks = appendForeignKey(fks, "fk_b", "b1", "tb", "id", "SET NULL", "NO ACTION")
+
+	want := []ansi.DbForeignKey{
+		{Name: "fk_a", Columns: []string{"a1", "a2"}, RefTable: "ta", RefColumns: []string{"id1", "id2"}, OnDelete: "CASCADE", OnUpdate: "RESTRICT"},
+		{Name: "fk_b", Columns: []string{"b1"}, RefTable: "tb", RefColumns: []string{"id"}, OnDelete: "SET NULL", OnUpdate: "NO ACTION"},
+	}
+	if !reflect.DeepEqual(fks, want) {
+		t.Error("append foreign key error", fks, want)
+	}
+}
+
+func TestTableIndex(t *testing.T) {
+	db := NewDB("demo")
+	table, err := db.Table("tindex")
+	if err != nil {
+		t.Error("Table error", err)
+		return
+	}
+	t.Log(table)
+
+	var index *ansi.DbIndex
+	for i := 0; i < len(table.Indexes); i++ {
+		if table.Indexes[i].Name == "ux_tindex" {
+			index = &table.Indexes[i]
+		}
+	}
+	if index == nil {
+		t.Error("can not find index", table.Indexes)
+		return
+	}
+	if !index.IsUnique || !reflect.DeepEqual(index.Columns, []string{"cstring", "cint"}) {
+		t.Error("index error", index)
+	}
+}
+
+func TestAppendIndex(t *testing.T) {
+	var indexes []ansi.DbIndex
+	indexes = appendIndex(indexes, "PRIMARY", "id", true)
+	indexes = appendIndex(indexes, "ux_tindex", "cstring", true)
+	indexes = appendIndex(indexes, "ux_tindex", "cint", true)
+
+	want := []ansi.DbIndex{
+		{Name: "PRIMARY", Columns: []string{"id"}, IsUnique: true},
+		{Name: "ux_tindex", Columns: []string{"cstring", "cint"}, IsUnique: true},
+	}
+	if !reflect.DeepEqual(indexes, want) {
+		t.Error("append index error", indexes, want)
+	}
+}
+
+func TestTableEnum(t *testing.T) {
+	db := NewDB("demo")
+	table, err := db.Table("tenum")
+	if err != nil {
+		t.Error("Table error", err)
+		return
+	}
+	t.Log(table)
+
+	for i := 0; i < len(table.Columns); i++ {
+		col := table.Columns[i]
+		if col.Name == "cenum" {
+			if !reflect.DeepEqual(col.Members, []string{"a", "b", "c"}) {
+				t.Error("enum members error", col.Members)
+			}
+			return
+		}
+	}
+	t.Error("can not find enum column", table.Columns)
+}
+
+func TestTableDefault(t *testing.T) {
+	db := NewDB("demo")
+	table, err := db.Table("tdefault")
+	if err != nil {
+		t.Error("Table error", err)
+		return
+	}
+	t.Log(table)
+
+	for i := 0; i < len(table.Columns); i++ {
+		col := table.Columns[i]
+		switch col.Name {
+		case "cstring":
+			if !col.HasDefault || col.Default == nil || *col.Default != "abc" {
+				t.Error("string default error", col)
+			}
+		case "cint":
+			if col.HasDefault || col.Default != nil {
+				t.Error("no default error", col)
+			}
+		}
+	}
+}
+
+// blockDriver is a database driver that block query and exec until context is done
+type blockDriver struct{}
+
+func (d blockDriver) Open(name string) (sqldriver.Conn, error) {
+	return blockConn{}, nil
+}
+
+type blockConn struct{}
+
+func (c blockConn) Prepare(query string) (sqldriver.Stmt, error) {
+	return nil, errors.New("prepare isn't supported")
+}
+
+func (c blockConn) Close() error {
+	return nil
+}
+
+func (c blockConn) Begin() (sqldriver.Tx, error) {
+	return nil, errors.New("begin isn't supported")
+}
+
+func (c blockConn) QueryContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Rows, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (c blockConn) ExecContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Result, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func init() {
+	sql.Register("kdb_block", blockDriver{})
+	RegisterDSN("kdb_block", "kdb_block", "block")
+	RegisterCompiler("kdb_block", NewSqlDriver(AnsiDialecter{}))
+}
+
+func TestDBContext(t *testing.T) {
+	db := NewDB("kdb_block")
+	defer db.Close()
+
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := db.QueryExpContext(ctx, q); err != context.DeadlineExceeded {
+		t.Error("query should be aborted by context", err)
+	}
+
+	del := NewDelete("ttable")
+	del.Where.Equals("cint", 1)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := db.ExecExpContext(ctx, del); err != context.Canceled {
+		t.Error("exec should be aborted by context", err)
+	}
+}
+
+/*
+DELIMITER //
+CREATE PROCEDURE sp_double (IN v int, OUT result int)
+BEGIN
+	SELECT cint, cstring FROM ttypes WHERE id = 1;
+	SET result = v * 2;
+END //
+DELIMITER ;
+*/
+
+func TestExecProcedure(t *testing.T) {
+	RegisterDSN("demo_multi", "mysql", "data:data@tcp(172.18.194.136:3306)/demo?multiStatements=true&interpolateParams=true")
+	db := NewDB("demo_multi")
+	defer db.Close()
+
+	sp := NewProcedure("sp_double").Set("v", 21).SetOut("result", ansi.Int)
+	sets, err := db.ExecProcedure(sp)
+	if err != nil {
+		t.Fatal("exec procedure error", err)
+	}
+
+	if len(sets) != 1 || len(sets[0]) != 1 {
+		t.Fatal("procedure result sets error", sets)
+	}
+	if v := sets[0][0]["cstring"]; v != "string" {
+		t.Error("procedure result rows error", sets[0])
+	}
+
+	p, _ := sp.FindParameter("result")
+	if v, ok := p.Value.(int64); !ok || v != 42 {
+		t.Errorf("output parameter error, want=[%v]; actual=[%v]", 42, p.Value)
+	}
+}
+
+func TestSetProcedureOutputs(t *testing.T) {
+	sp := NewProcedure("sp_test").Set("v", 1).SetDir("result", nil, ansi.DirOut).SetDir("total", 0, ansi.DirInOut)
+	setProcedureOutputs(sp, map[string]interface{}{"@result": int64(2), "total": int64(3), "v": int64(4)})
+
+	if p, _ := sp.FindParameter("result"); p.Value != int64(2) {
+		t.Error("output parameter error", p.Value)
+	}
+	if p, _ := sp.FindParameter("total"); p.Value != int64(3) {
+		t.Error("inout parameter error", p.Value)
+	}
+	if p, _ := sp.FindParameter("v"); p.Value != 1 {
+		t.Error("input parameter should not be changed", p.Value)
+	}
+}
+
+/*
+CREATE FUNCTION fn_double (v int) RETURNS int DETERMINISTIC RETURN v * 2;
+*/
+
+func TestExecProcedureReturn(t *testing.T) {
+	RegisterDSN("demo_multi", "mysql", "data:data@tcp(172.18.194.136:3306)/demo?multiStatements=true&interpolateParams=true")
+	db := NewDB("demo_multi")
+	defer db.Close()
+
+	fn := NewProcedure("fn_double").SetDir("ret", nil, ansi.DirReturn).Set("v", 21)
+	if _, err := db.ExecProcedure(fn); err != nil {
+		t.Fatal("exec function error", err)
+	}
+
+	if v, ok := fn.ReturnValue(); !ok || v != int64(42) {
+		t.Errorf("return value error, want=[%v]; actual=[%v]", 42, v)
+	}
+}
+
+func TestProcedureOutputCoerce(t *testing.T) {
+	sp := NewProcedure("sp_test").Set("v", 1).SetOut("cint", ansi.Int).SetOut("cstring", ansi.String).SetOut("craw", ansi.Zero)
+	outputs := map[string]interface{}{
+		"@cint":    []byte("42"),
+		"@cstring": []byte("string"),
+		"@craw":    []byte("raw"),
+	}
+	if err := setProcedureOutputs(sp, outputs); err != nil {
+		t.Fatal("set procedure outputs error", err)
+	}
+
+	if p, _ := sp.FindParameter("cint"); p.Value != int64(42) {
+		t.Errorf("out int error, want=[%v]; actual=[%v](%T)", 42, p.Value, p.Value)
+	}
+	if p, _ := sp.FindParameter("cstring"); p.Value != "string" {
+		t.Errorf("out varchar error, want=[%v]; actual=[%v](%T)", "string", p.Value, p.Value)
+	}
+	if p, _ := sp.FindParameter("craw"); !reflect.DeepEqual(p.Value, []byte("raw")) {
+		t.Errorf("out parameter without type should not be converted, actual=[%v](%T)", p.Value, p.Value)
+	}
+
+	outputs["@cint"] = []byte("abc")
+	if err := setProcedureOutputs(sp, outputs); err == nil {
+		t.Error("convert invalid int should return error")
+	}
+
+	cases := []struct {
+		v    interface{}
+		t    ansi.DbType
+		want interface{}
+	}{
+		{"7", ansi.Int, int64(7)},
+		{int32(7), ansi.Int, int64(7)},
+		{float64(7), ansi.Int, int64(7)},
+		{[]byte("3.5"), ansi.Float, 3.5},
+		{[]byte("12345678901234567.89"), ansi.Numeric, "12345678901234567.89"},
+		{"0.1", ansi.Numeric, "0.1"},
+		{int64(1), ansi.Boolean, true},
+		{[]byte("7"), ansi.Uint, uint64(7)},
+		{int64(7), ansi.String, "7"},
+		{nil, ansi.Int, nil},
+	}
+	for _, c := range cases {
+		if actual, err := coerceValue(c.v, c.t); err != nil || !reflect.DeepEqual(actual, c.want) {
+			t.Errorf("coerce value error, v=[%v]; type=[%v]; want=[%v]; actual=[%v]; err=%v", c.v, c.t, c.want, actual, err)
+		}
+	}
+}
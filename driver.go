@@ -7,19 +7,22 @@ import (
 	"fmt"
 	"github.com/sdming/kdb/ansi"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
-// // Queryer is a interface that query expression
-// type Queryer interface {
-// 	Query(source string, exp Expression) (sql.Rows, error)
-// }
+// Queryer is a interface that query expression
+type Queryer interface {
+	Query(source string, exp Expression) (*sql.Rows, error)
+}
 
-// // Execer is a interface that execute expression
-// type Execer interface {
-// 	Exec(source string, exp Expression) (sql.Result, error)
-// }
+// Execer is a interface that execute expression
+type Execer interface {
+	Exec(source string, exp Expression) (sql.Result, error)
+}
 
 type Driver interface {
 	Compiler
@@ -30,25 +33,148 @@ type Compiler interface {
 	Compile(source string, exp Expression) (query string, args []interface{}, err error)
 }
 
+// TableCompiler is optional interface of Compiler that compile schema of table to create table sql
+type TableCompiler interface {
+	CompileCreateTable(source string, t *ansi.DbTable) (query string, err error)
+}
+
+var _ TableCompiler = (*SqlDriver)(nil)
+
+// CompileCreateTable compile schema of table to create table sql by compiler of driver,
+// return CompileUnsupported error if the compiler doesn't implement TableCompiler
+func CompileCreateTable(driver string, source string, t *ansi.DbTable) (string, error) {
+	compiler, err := GetCompiler(driver)
+	if err != nil {
+		return "", err
+	}
+	tc, ok := compiler.(TableCompiler)
+	if !ok {
+		return "", newCompileError(CompileUnsupported, NodeTable, "", "compiler doesn't support create table:"+driver)
+	}
+	return tc.CompileCreateTable(source, t)
+}
+
+// CompileErrorCode is category of CompileError
+type CompileErrorCode int
+
+const (
+	// CompileInvalid means expression is nil or invalid
+	CompileInvalid CompileErrorCode = 1
+
+	// CompileUnsupported means expression type isn't supported
+	CompileUnsupported CompileErrorCode = 2
+
+	// CompileMissingParameter means can not find parameter of expression
+	CompileMissingParameter CompileErrorCode = 3
+
+	// CompileInvalidFormat means sql format is invalid
+	CompileInvalidFormat CompileErrorCode = 4
+)
+
+// String
+func (code CompileErrorCode) String() string {
+	switch code {
+	case CompileInvalid:
+		return "Invalid"
+	case CompileUnsupported:
+		return "Unsupported"
+	case CompileMissingParameter:
+		return "MissingParameter"
+	case CompileInvalidFormat:
+		return "InvalidFormat"
+	}
+	return "Unknow"
+}
+
+// CompileError is error returned by Compiler
+type CompileError struct {
+	// Code is category of error
+	Code CompileErrorCode
+
+	// Node is node type of the offending expression
+	Node NodeType
+
+	// Parameter is name of the offending parameter
+	Parameter string
+
+	// Message is error message
+	Message string
+}
+
+// Error return error message
+func (e *CompileError) Error() string {
+	return e.Message
+}
+
+func newCompileError(code CompileErrorCode, node NodeType, parameter string, message string) *CompileError {
+	return &CompileError{
+		Code:      code,
+		Node:      node,
+		Parameter: parameter,
+		Message:   message,
+	}
+}
+
 var _compilers = make(map[string]Compiler)
+var _compilersLock sync.RWMutex
 
 // RegisterCompiler makes a compiler available by the provided driver name.
+// If RegisterCompiler is called twice with the same name, it panics.
 func RegisterCompiler(driver string, compiler Compiler) {
 	if compiler == nil {
 		panic("register compiler is nil")
 	}
+	_compilersLock.Lock()
+	defer _compilersLock.Unlock()
+	if _, dup := _compilers[driver]; dup {
+		panic("register compiler twice for driver " + driver)
+	}
+	_compilers[driver] = compiler
+}
+
+// RegisterCompilerReplace makes a compiler available by the provided driver name, replace the existing one.
+func RegisterCompilerReplace(driver string, compiler Compiler) {
+	if compiler == nil {
+		panic("register compiler is nil")
+	}
+	_compilersLock.Lock()
 	_compilers[driver] = compiler
+	_compilersLock.Unlock()
 }
 
 // GetCompiler return a a compiler by driver name
 func GetCompiler(driver string) (Compiler, error) {
+	_compilersLock.RLock()
 	c, ok := _compilers[driver]
+	_compilersLock.RUnlock()
 	if !ok {
 		return nil, errors.New(fmt.Sprint("can not get compiler:", driver))
 	}
 	return c, nil
 }
 
+// CompileWhere compile where to sql fragment of dialecter, like a = ? AND b = ?, the fragment start with WHERE if keyword is true
+func CompileWhere(dialecter Dialecter, where *Where, keyword bool) (query string, args []interface{}, err error) {
+	return NewStmtCompiler(dialecter).CompileWhere(where, keyword)
+}
+
+// Preview compile expression by compiler of driver, so sql can be checked or logged before executing
+func Preview(driver string, exp Expression) (query string, args []interface{}, err error) {
+	var compiler Compiler
+	if compiler, err = GetCompiler(driver); err != nil {
+		return
+	}
+	return compiler.Compile("", exp)
+}
+
+// DebugString return node type and string of expression, like Query: SELECT ..., it doesn't compile expression
+func DebugString(exp Expression) string {
+	if exp == nil {
+		return _nilStr
+	}
+	return fmt.Sprint(exp.Node(), ": ", exp)
+}
+
 // Schemaer is a interface that get schema of table,view,function
 type Schemaer interface {
 	// Table return schema of table,view
@@ -59,25 +185,46 @@ type Schemaer interface {
 }
 
 var _schemaers = make(map[string]Schemaer)
+var _schemaersLock sync.RWMutex
 
 // RegisterSchemaer makes a schemaer available by the provided driver name.
+// If RegisterSchemaer is called twice with the same name, it panics.
 func RegisterSchemaer(driver string, schemaer Schemaer) {
 	if schemaer == nil {
 		panic("register schemaer is nil")
 	}
+	_schemaersLock.Lock()
+	defer _schemaersLock.Unlock()
+	if _, dup := _schemaers[driver]; dup {
+		panic("register schemaer twice for driver " + driver)
+	}
+	_schemaers[driver] = schemaer
+}
+
+// RegisterSchemaerReplace makes a schemaer available by the provided driver name, replace the existing one.
+func RegisterSchemaerReplace(driver string, schemaer Schemaer) {
+	if schemaer == nil {
+		panic("register schemaer is nil")
+	}
+	_schemaersLock.Lock()
 	_schemaers[driver] = schemaer
+	_schemaersLock.Unlock()
 }
 
 // GetSchemaer return a a schemaer by driver name
 func GetSchemaer(driver string) (Schemaer, error) {
+	_schemaersLock.RLock()
 	schema, ok := _schemaers[driver]
+	_schemaersLock.RUnlock()
 	if !ok {
 		return nil, errors.New(fmt.Sprint("can not get schemaer:", driver))
 	}
 	return schema, nil
 }
 
-// Dialecter is interface of sql dialect
+// Dialecter is interface of sql dialect.
+// a dialect may also implement optional interfaces like LimitSqler to change how a feature is compiled,
+// AnsiDialecter's implementation is used if it doesn't
 type Dialecter interface {
 	// Name return mysql,postgres,oracle,mssql,sqlite,...
 	Name() string
@@ -116,25 +263,347 @@ type Dialecter interface {
 	SplitStatement() string
 }
 
+// ArrayParameterSupporter is optional interface of Dialecter
+type ArrayParameterSupporter interface {
+	// SupportArrayParameter, like col = ANY($1), $1 is an array
+	SupportArrayParameter() bool
+}
+
+// KeySchemaSqler is optional interface of Dialecter
+type KeySchemaSqler interface {
+	// PrimaryKeySql return sql to query primary key columns of table, ordered by key position
+	PrimaryKeySql(name string) string
+
+	// ForeignKeysSql return sql to query foreign key columns of table, ordered by key name & position
+	ForeignKeysSql(name string) string
+
+	// IndexesSql return sql to query index columns of table, ordered by index name & position
+	IndexesSql(name string) string
+}
+
+// NativeTyper is optional interface of Dialecter
+type NativeTyper interface {
+	// NativeType convert ansi.DbType to native data type, return "" if doesn't support
+	NativeType(t ansi.DbType, length, precision, scale int) string
+}
+
+// LockSqler is optional interface of Dialecter
+type LockSqler interface {
+	// LockSql return locking clause of query, like FOR UPDATE; return "" if doesn't support
+	LockSql(lock *Lock) string
+}
+
+// ParameterLimiter is optional interface of Dialecter
+type ParameterLimiter interface {
+	// MaxParameters return max count of parameters of a statement, return 0 if it's unlimited
+	MaxParameters() int
+}
+
+// DualTabler is optional interface of Dialecter
+type DualTabler interface {
+	// DualTable return table that select without from should use, like DUAL; return "" if doesn't need
+	DualTable() string
+}
+
+// SetOperatorSqler is optional interface of Dialecter
+type SetOperatorSqler interface {
+	// SetOperatorSql return keyword of set operator, like UNION; return "" if doesn't support
+	SetOperatorSql(op SetOperator) string
+}
+
+// LimitSqler is optional interface of Dialecter
+type LimitSqler interface {
+	// LimitSql return paging clause of query, like OFFSET 10 ROWS FETCH NEXT 5 ROWS ONLY; return "" if doesn't support
+	LimitSql(offset, count int) string
+
+	// LimitRequiresOrderBy return true if paging clause is only allowed after ORDER BY
+	LimitRequiresOrderBy() bool
+}
+
+// NullSafeEqualSqler is optional interface of Dialecter
+type NullSafeEqualSqler interface {
+	// NullSafeEqualSql return operator of null-safe equality, like IS NOT DISTINCT FROM; return "" if doesn't support
+	NullSafeEqualSql() string
+}
+
+// DistinctOnSupporter is optional interface of Dialecter
+type DistinctOnSupporter interface {
+	// SupportDistinctOn, like SELECT DISTINCT ON (a) ...
+	SupportDistinctOn() bool
+}
+
+// CastTyper is optional interface of Dialecter
+type CastTyper interface {
+	// CastType return target type of CAST, return "" if doesn't support; NativeType is used if dialect doesn't implement it
+	CastType(t ansi.DbType, length, precision, scale int) string
+}
+
+// CastShorthandSupporter is optional interface of Dialecter
+type CastShorthandSupporter interface {
+	// SupportCastShorthand, like exp::INTEGER
+	SupportCastShorthand() bool
+}
+
+// Explainer is optional interface of Dialecter
+type Explainer interface {
+	// ExplainPrefix return statement prefix to explain a query, like EXPLAIN ANALYZE; return "" if doesn't support.
+	// format is output format of plan, like JSON, it's validated already
+	ExplainPrefix(analyze bool, format string) string
+}
+
+// GroupingSetSqler is optional interface of Dialecter
+type GroupingSetSqler interface {
+	// GroupingSetSql return keyword of grouping set, like ROLLUP; suffix is true if it's written after fields, like WITH ROLLUP.
+	// return "" if doesn't support
+	GroupingSetSql(gs GroupingSet) (sql string, suffix bool)
+}
+
+// LateralSupporter is optional interface of Dialecter
+type LateralSupporter interface {
+	// SupportLateral, like JOIN LATERAL (subquery) AS t ON ...
+	SupportLateral() bool
+}
+
+var _ interface {
+	Dialecter
+	ArrayParameterSupporter
+	KeySchemaSqler
+	NativeTyper
+	LockSqler
+	ParameterLimiter
+	DualTabler
+	SetOperatorSqler
+	LimitSqler
+	NullSafeEqualSqler
+	DistinctOnSupporter
+	CastShorthandSupporter
+	Explainer
+	GroupingSetSqler
+	LateralSupporter
+} = AnsiDialecter{}
+
+// dialectSupportArrayParameter call SupportArrayParameter of d, or AnsiDialecter if d doesn't implement it
+func dialectSupportArrayParameter(d Dialecter) bool {
+	if x, ok := d.(ArrayParameterSupporter); ok {
+		return x.SupportArrayParameter()
+	}
+	return AnsiDialecter{}.SupportArrayParameter()
+}
+
+// dialectKeySchema return d as KeySchemaSqler, or AnsiDialecter if d doesn't implement it
+func dialectKeySchema(d Dialecter) KeySchemaSqler {
+	if x, ok := d.(KeySchemaSqler); ok {
+		return x
+	}
+	return AnsiDialecter{}
+}
+
+// dialectNativeType call NativeType of d, or AnsiDialecter if d doesn't implement it
+func dialectNativeType(d Dialecter, t ansi.DbType, length, precision, scale int) string {
+	if x, ok := d.(NativeTyper); ok {
+		return x.NativeType(t, length, precision, scale)
+	}
+	return AnsiDialecter{}.NativeType(t, length, precision, scale)
+}
+
+// dialectLockSql call LockSql of d, or AnsiDialecter if d doesn't implement it
+func dialectLockSql(d Dialecter, lock *Lock) string {
+	if x, ok := d.(LockSqler); ok {
+		return x.LockSql(lock)
+	}
+	return AnsiDialecter{}.LockSql(lock)
+}
+
+// dialectMaxParameters call MaxParameters of d, or AnsiDialecter if d doesn't implement it
+func dialectMaxParameters(d Dialecter) int {
+	if x, ok := d.(ParameterLimiter); ok {
+		return x.MaxParameters()
+	}
+	return AnsiDialecter{}.MaxParameters()
+}
+
+// dialectDualTable call DualTable of d, or AnsiDialecter if d doesn't implement it
+func dialectDualTable(d Dialecter) string {
+	if x, ok := d.(DualTabler); ok {
+		return x.DualTable()
+	}
+	return AnsiDialecter{}.DualTable()
+}
+
+// dialectSetOperatorSql call SetOperatorSql of d, or AnsiDialecter if d doesn't implement it
+func dialectSetOperatorSql(d Dialecter, op SetOperator) string {
+	if x, ok := d.(SetOperatorSqler); ok {
+		return x.SetOperatorSql(op)
+	}
+	return AnsiDialecter{}.SetOperatorSql(op)
+}
+
+// dialectLimitSql call LimitSql of d, or AnsiDialecter if d doesn't implement it
+func dialectLimitSql(d Dialecter, offset, count int) string {
+	if x, ok := d.(LimitSqler); ok {
+		return x.LimitSql(offset, count)
+	}
+	return AnsiDialecter{}.LimitSql(offset, count)
+}
+
+// dialectLimitRequiresOrderBy call LimitRequiresOrderBy of d, or AnsiDialecter if d doesn't implement LimitSqler
+func dialectLimitRequiresOrderBy(d Dialecter) bool {
+	if x, ok := d.(LimitSqler); ok {
+		return x.LimitRequiresOrderBy()
+	}
+	return AnsiDialecter{}.LimitRequiresOrderBy()
+}
+
+// dialectNullSafeEqualSql call NullSafeEqualSql of d, or AnsiDialecter if d doesn't implement it
+func dialectNullSafeEqualSql(d Dialecter) string {
+	if x, ok := d.(NullSafeEqualSqler); ok {
+		return x.NullSafeEqualSql()
+	}
+	return AnsiDialecter{}.NullSafeEqualSql()
+}
+
+// dialectSupportDistinctOn call SupportDistinctOn of d, or AnsiDialecter if d doesn't implement it
+func dialectSupportDistinctOn(d Dialecter) bool {
+	if x, ok := d.(DistinctOnSupporter); ok {
+		return x.SupportDistinctOn()
+	}
+	return AnsiDialecter{}.SupportDistinctOn()
+}
+
+// dialectCastType call CastType of d, or NativeType of d if d doesn't implement it.
+// AnsiDialecter doesn't implement CastType, so it can't hide NativeType of a dialect that embed it
+func dialectCastType(d Dialecter, t ansi.DbType, length, precision, scale int) string {
+	if x, ok := d.(CastTyper); ok {
+		return x.CastType(t, length, precision, scale)
+	}
+	return dialectNativeType(d, t, length, precision, scale)
+}
+
+// dialectSupportCastShorthand call SupportCastShorthand of d, or AnsiDialecter if d doesn't implement it
+func dialectSupportCastShorthand(d Dialecter) bool {
+	if x, ok := d.(CastShorthandSupporter); ok {
+		return x.SupportCastShorthand()
+	}
+	return AnsiDialecter{}.SupportCastShorthand()
+}
+
+// dialectExplainPrefix call ExplainPrefix of d, or AnsiDialecter if d doesn't implement it
+func dialectExplainPrefix(d Dialecter, analyze bool, format string) string {
+	if x, ok := d.(Explainer); ok {
+		return x.ExplainPrefix(analyze, format)
+	}
+	return AnsiDialecter{}.ExplainPrefix(analyze, format)
+}
+
+// dialectGroupingSetSql call GroupingSetSql of d, or AnsiDialecter if d doesn't implement it
+func dialectGroupingSetSql(d Dialecter, gs GroupingSet) (string, bool) {
+	if x, ok := d.(GroupingSetSqler); ok {
+		return x.GroupingSetSql(gs)
+	}
+	return AnsiDialecter{}.GroupingSetSql(gs)
+}
+
+// dialectSupportLateral call SupportLateral of d, or AnsiDialecter if d doesn't implement it
+func dialectSupportLateral(d Dialecter) bool {
+	if x, ok := d.(LateralSupporter); ok {
+		return x.SupportLateral()
+	}
+	return AnsiDialecter{}.SupportLateral()
+}
+
 var _dialecters = make(map[string]Dialecter)
+var _dialectersLock sync.RWMutex
 
 // RegisterDialecter makes a dialecter available by the provided driver name.
+// If RegisterDialecter is called twice with the same name, it panics.
 func RegisterDialecter(driver string, dialecter Dialecter) {
 	if dialecter == nil {
 		panic("register dialecter is nil")
 	}
+	_dialectersLock.Lock()
+	defer _dialectersLock.Unlock()
+	if _, dup := _dialecters[driver]; dup {
+		panic("register dialecter twice for driver " + driver)
+	}
+	_dialecters[driver] = dialecter
+}
+
+// RegisterDialecterReplace makes a dialecter available by the provided driver name, replace the existing one.
+func RegisterDialecterReplace(driver string, dialecter Dialecter) {
+	if dialecter == nil {
+		panic("register dialecter is nil")
+	}
+	_dialectersLock.Lock()
 	_dialecters[driver] = dialecter
+	_dialectersLock.Unlock()
 }
 
 // GetDialecter return a a dialecter by driver name
 func GetDialecter(driver string) (Dialecter, error) {
+	_dialectersLock.RLock()
 	d, ok := _dialecters[driver]
+	_dialectersLock.RUnlock()
 	if !ok {
 		return nil, errors.New(fmt.Sprint("can not get dialecter:", driver))
 	}
 	return d, nil
 }
 
+// ExplainSql prepend EXPLAIN to compiled query, analyze write EXPLAIN ANALYZE if dialect support it,
+// format is output format of mysql plan, e.g. JSON, TREE; ignored by other dialects
+func ExplainSql(dialecter Dialecter, query string, analyze bool, format string) (string, error) {
+	if dialecter == nil {
+		return "", newCompileError(CompileInvalid, NodeZero, "", "explain dialecter is nil")
+	}
+
+	query = strings.TrimSpace(query)
+	if split := strings.TrimSpace(dialecter.SplitStatement()); split != "" {
+		query = strings.TrimSpace(strings.TrimSuffix(query, split))
+	}
+	if query == "" {
+		return "", newCompileError(CompileInvalid, NodeZero, "", "explain query is empty")
+	}
+
+	if format != "" && !simpleNameRegexp.MatchString(format) {
+		return "", newCompileError(CompileInvalid, NodeZero, "", fmt.Sprintf("invalid explain format %q", format))
+	}
+
+	prefix := dialectExplainPrefix(dialecter, analyze, format)
+	if prefix == "" {
+		return "", newCompileError(CompileUnsupported, NodeZero, "", dialecter.Name()+" does not support explain statement return rows")
+	}
+
+	return prefix + " " + query + dialecter.SplitStatement(), nil
+}
+
+var _aggregates = map[string]bool{
+	ansi.Count: true,
+	ansi.Sum:   true,
+	ansi.Avg:   true,
+	ansi.Min:   true,
+	ansi.Max:   true,
+}
+var _aggregatesLock sync.RWMutex
+
+// RegisterAggregate allow a custom function to be used as aggregate, like GROUP_CONCAT, STRING_AGG.
+// If name isn't a valid function name, it panics.
+func RegisterAggregate(name string) {
+	if !simpleNameRegexp.MatchString(name) {
+		panic("register aggregate name is invalid:" + name)
+	}
+	_aggregatesLock.Lock()
+	_aggregates[strings.ToUpper(name)] = true
+	_aggregatesLock.Unlock()
+}
+
+// isAggregate return true if name is a known or registered aggregate
+func isAggregate(name Func) bool {
+	_aggregatesLock.RLock()
+	ok := _aggregates[strings.ToUpper(string(name))]
+	_aggregatesLock.RUnlock()
+	return ok
+}
+
 // DefaultDialecter return AnsiDialecter
 func DefaultDialecter() Dialecter {
 	return AnsiDialecter{}
@@ -159,6 +628,26 @@ func (ad AnsiDialecter) SupportIndexedParameter() bool {
 	return false
 }
 
+// SupportArrayParameter return false
+func (ad AnsiDialecter) SupportArrayParameter() bool {
+	return false
+}
+
+// SupportDistinctOn return false
+func (ad AnsiDialecter) SupportDistinctOn() bool {
+	return false
+}
+
+// SupportCastShorthand return false
+func (ad AnsiDialecter) SupportCastShorthand() bool {
+	return false
+}
+
+// SupportLateral return false
+func (ad AnsiDialecter) SupportLateral() bool {
+	return false
+}
+
 // ParameterPlaceHolder return ?
 func (ad AnsiDialecter) ParameterPlaceHolder() string {
 	return " ? "
@@ -169,9 +658,14 @@ func (ad AnsiDialecter) QuoteString(s string) string {
 	return "'" + s + "'"
 }
 
-// Quote quote s as "s"
+// Quote quote s as "s", " in s is escaped as ""
 func (ad AnsiDialecter) Quote(s string) string {
-	return "\"" + s + "\""
+	return quoteWith(s, "\"", "\"")
+}
+
+// quoteWith quote s by open and close, close in s is doubled so s can not end quoted identifier
+func quoteWith(s, open, close string) string {
+	return open + strings.Replace(s, close, close+close, -1) + close
 }
 
 // TableSql return ""
@@ -194,6 +688,21 @@ func (ad AnsiDialecter) ParametersSql(name string) string {
 	return ""
 }
 
+// PrimaryKeySql return ""
+func (ad AnsiDialecter) PrimaryKeySql(name string) string {
+	return ""
+}
+
+// ForeignKeysSql return ""
+func (ad AnsiDialecter) ForeignKeysSql(name string) string {
+	return ""
+}
+
+// IndexesSql return ""
+func (ad AnsiDialecter) IndexesSql(name string) string {
+	return ""
+}
+
 // SplitStatement return ; 
 func (ad AnsiDialecter) SplitStatement() string {
 	return " ; "
@@ -209,9 +718,9 @@ func (ad AnsiDialecter) DbType(nativeType string) ansi.DbType {
 		return ansi.String
 	case "bit", "bool", "boolean", "yesno", "logical":
 		return ansi.Boolean
-	case "tinyint unsigned", "uint16", "smallint unsigned", "uint32", "integer unsigned", "uint64", "bigint unsigned", "unsigned, bigint", "int2", "int8":
-		return ansi.Int
-	case "tinyint", "smallint", "int", "mediumint", "bigint", "int16", "int32", "int64", "integer", "long":
+	case "tinyint unsigned", "uint16", "smallint unsigned", "mediumint unsigned", "uint32", "int unsigned", "integer unsigned", "uint64", "bigint unsigned", "unsigned, bigint":
+		return ansi.Uint
+	case "tinyint", "smallint", "int", "mediumint", "bigint", "int16", "int32", "int64", "integer", "long", "int2", "int8":
 		return ansi.Int
 	case "bigserial", "serial", "smallserial":
 		return ansi.Int
@@ -239,6 +748,142 @@ func (ad AnsiDialecter) DbType(nativeType string) ansi.DbType {
 	return ansi.Var
 }
 
+// NativeType convert ansi.DbType to ansi sql data type
+func (ad AnsiDialecter) NativeType(t ansi.DbType, length, precision, scale int) string {
+	switch t {
+	case ansi.String:
+		return nativeTypeLength("VARCHAR", length, "VARCHAR(255)")
+	case ansi.Boolean:
+		return "BOOLEAN"
+	case ansi.Bytes:
+		return nativeTypeLength("VARBINARY", length, "BLOB")
+	case ansi.Date:
+		return "DATE"
+	case ansi.DateTime:
+		return "TIMESTAMP"
+	case ansi.Guid:
+		return "CHAR(36)"
+	case ansi.Int:
+		return "INTEGER"
+	case ansi.Uint:
+		return "BIGINT"
+	case ansi.Numeric:
+		return nativeTypePrecision("DECIMAL", precision, scale)
+	case ansi.Float:
+		return "FLOAT"
+	}
+	return ""
+}
+
+// LockSql return ""
+func (ad AnsiDialecter) LockSql(lock *Lock) string {
+	return ""
+}
+
+// MaxParameters return 0
+func (ad AnsiDialecter) MaxParameters() int {
+	return 0
+}
+
+// DualTable return ""
+func (ad AnsiDialecter) DualTable() string {
+	return ""
+}
+
+// SetOperatorSql return op
+func (ad AnsiDialecter) SetOperatorSql(op SetOperator) string {
+	return op.String()
+}
+
+// LimitSql return OFFSET offset ROWS FETCH NEXT count ROWS ONLY of SQL:2008
+func (ad AnsiDialecter) LimitSql(offset, count int) string {
+	s := ""
+	if offset > 0 {
+		s = ansi.Offset + " " + strconv.Itoa(offset) + " " + ansi.Rows
+	}
+	if count > 0 {
+		if s != "" {
+			s += " "
+		}
+		s += ansi.FetchNext + " " + strconv.Itoa(count) + " " + ansi.Rows + " " + ansi.Only
+	}
+	return s
+}
+
+// LimitRequiresOrderBy return false, SQL:2008 allows OFFSET FETCH without ORDER BY
+func (ad AnsiDialecter) LimitRequiresOrderBy() bool {
+	return false
+}
+
+// NullSafeEqualSql return IS NOT DISTINCT FROM
+func (ad AnsiDialecter) NullSafeEqualSql() string {
+	return ansi.NotDistinctFrom
+}
+
+// ExplainPrefix return EXPLAIN
+func (ad AnsiDialecter) ExplainPrefix(analyze bool, format string) string {
+	return "EXPLAIN"
+}
+
+// GroupingSetSql return gs, like GROUP BY ROLLUP (a, b)
+func (ad AnsiDialecter) GroupingSetSql(gs GroupingSet) (string, bool) {
+	return gs.String(), false
+}
+
+// limitCommaSql return LIMIT offset,count
+func limitCommaSql(offset, count int) string {
+	return ansi.Limit + " " + strconv.Itoa(offset) + "," + strconv.Itoa(count)
+}
+
+// limitOffsetSql return LIMIT count OFFSET offset, LIMIT or OFFSET is omitted if it's 0
+func limitOffsetSql(offset, count int) string {
+	s := ""
+	if count > 0 {
+		s = ansi.Limit + " " + strconv.Itoa(count)
+	}
+	if offset > 0 {
+		if s != "" {
+			s += " "
+		}
+		s += ansi.Offset + " " + strconv.Itoa(offset)
+	}
+	return s
+}
+
+// lockSql return FOR UPDATE|FOR SHARE [NOWAIT|SKIP LOCKED], return "" if lock isn't valid or share isn't allowed
+func lockSql(lock *Lock, share bool) string {
+	if lock == nil || (lock.NoWait && lock.SkipLocked) {
+		return ""
+	}
+
+	switch lock.Mode {
+	case LockUpdate:
+	case LockShare:
+		if !share {
+			return ""
+		}
+	default:
+		return ""
+	}
+	return lock.String()
+}
+
+// nativeTypeLength return name(length), return dflt if length <= 0
+func nativeTypeLength(name string, length int, dflt string) string {
+	if length <= 0 {
+		return dflt
+	}
+	return fmt.Sprintf("%s(%d)", name, length)
+}
+
+// nativeTypePrecision return name(precision,scale), return name if precision <= 0
+func nativeTypePrecision(name string, precision, scale int) string {
+	if precision <= 0 {
+		return name
+	}
+	return fmt.Sprintf("%s(%d,%d)", name, precision, scale)
+}
+
 // SqliteDialecter is sqlite dialect
 type SqliteDialecter struct {
 	AnsiDialecter
@@ -249,6 +894,40 @@ func (sqlite SqliteDialecter) Name() string {
 	return "sqlite"
 }
 
+// LimitSql return LIMIT offset,count
+func (sqlite SqliteDialecter) LimitSql(offset, count int) string {
+	return limitCommaSql(offset, count)
+}
+
+// NullSafeEqualSql return IS
+func (sqlite SqliteDialecter) NullSafeEqualSql() string {
+	return ansi.Is
+}
+
+// ExplainPrefix return EXPLAIN QUERY PLAN
+func (sqlite SqliteDialecter) ExplainPrefix(analyze bool, format string) string {
+	return "EXPLAIN QUERY PLAN"
+}
+
+// GroupingSetSql return "", sqlite doesn't support grouping set
+func (sqlite SqliteDialecter) GroupingSetSql(gs GroupingSet) (string, bool) {
+	return "", false
+}
+
+// SetOperatorSql return UNION|UNION ALL|EXCEPT|INTERSECT, sqlite doesn't support EXCEPT ALL and INTERSECT ALL
+func (sqlite SqliteDialecter) SetOperatorSql(op SetOperator) string {
+	switch op {
+	case Union, UnionAll, Except, Intersect:
+		return op.String()
+	}
+	return ""
+}
+
+// MaxParameters return 32766, default SQLITE_MAX_VARIABLE_NUMBER of sqlite since 3.32
+func (sqlite SqliteDialecter) MaxParameters() int {
+	return 32766
+}
+
 // Table return schema of table,view
 func (sqlite SqliteDialecter) Table(db *sql.DB, name string) (table *ansi.DbTable, err error) {
 	query := fmt.Sprintf(`SELECT name, type FROM sqlite_master WHERE name = '%s'; `, name)
@@ -280,15 +959,21 @@ func (sqlite SqliteDialecter) Table(db *sql.DB, name string) (table *ansi.DbTabl
 		return
 	}
 
+	keys := make(map[int]string)
 	for rows.Next() {
 		col := ansi.DbColumn{}
 		var dflt sql.NullString
+		var pk int
 
-		if err = rows.Scan(&col.Position, &col.Name, &col.NativeType, &col.IsNullable, &dflt, &col.IsPrimaryKey); err != nil {
+		if err = rows.Scan(&col.Position, &col.Name, &col.NativeType, &col.IsNullable, &dflt, &pk); err != nil {
 			//
 		} else {
 			col.DbType = sqlite.DbType(col.NativeType)
 			col.IsNullable = col.IsNullable == false
+			col.IsPrimaryKey = pk > 0
+			if pk > 0 {
+				keys[pk] = col.Name
+			}
 			t.Columns = append(t.Columns, col)
 		}
 	}
@@ -296,6 +981,11 @@ func (sqlite SqliteDialecter) Table(db *sql.DB, name string) (table *ansi.DbTabl
 		return
 	}
 
+	// pk is index of column in primary key, start from 1
+	for i := 1; i <= len(keys); i++ {
+		t.PrimaryKey = append(t.PrimaryKey, keys[i])
+	}
+
 	table = t
 	return
 }
@@ -305,8 +995,25 @@ func (sqlite SqliteDialecter) Function(db *sql.DB, name string) (*ansi.DbFunctio
 	return nil, errors.New("sqlite doesn't support store procedure")
 }
 
-// MssqlDialecter is ms sql server dialect
-type MssqlDialecter struct {
+// NativeType convert ansi.DbType to sqlite data type
+func (sqlite SqliteDialecter) NativeType(t ansi.DbType, length, precision, scale int) string {
+	switch t {
+	case ansi.String, ansi.Guid:
+		return "TEXT"
+	case ansi.Boolean, ansi.Int, ansi.Uint:
+		return "INTEGER"
+	case ansi.Bytes:
+		return "BLOB"
+	case ansi.Float:
+		return "REAL"
+	case ansi.DateTime:
+		return "DATETIME"
+	}
+	return sqlite.AnsiDialecter.NativeType(t, length, precision, scale)
+}
+
+// MssqlDialecter is ms sql server dialect
+type MssqlDialecter struct {
 	AnsiDialecter
 }
 
@@ -315,9 +1022,42 @@ func (mssql MssqlDialecter) Name() string {
 	return "mssql"
 }
 
-// Quote quote s as [s]
+// SetOperatorSql return UNION|UNION ALL|EXCEPT|INTERSECT, mssql doesn't support EXCEPT ALL and INTERSECT ALL
+func (mssql MssqlDialecter) SetOperatorSql(op SetOperator) string {
+	switch op {
+	case Union, UnionAll, Except, Intersect:
+		return op.String()
+	}
+	return ""
+}
+
+// LimitSql return OFFSET offset ROWS [FETCH NEXT count ROWS ONLY], mssql requires OFFSET before FETCH even if offset is 0
+func (mssql MssqlDialecter) LimitSql(offset, count int) string {
+	s := ansi.Offset + " " + strconv.Itoa(offset) + " " + ansi.Rows
+	if count > 0 {
+		s += " " + ansi.FetchNext + " " + strconv.Itoa(count) + " " + ansi.Rows + " " + ansi.Only
+	}
+	return s
+}
+
+// LimitRequiresOrderBy return true, OFFSET FETCH of mssql is part of ORDER BY
+func (mssql MssqlDialecter) LimitRequiresOrderBy() bool {
+	return true
+}
+
+// ExplainPrefix return "", showplan of mssql is a session option rather than a statement
+func (mssql MssqlDialecter) ExplainPrefix(analyze bool, format string) string {
+	return ""
+}
+
+// MaxParameters return 2100
+func (mssql MssqlDialecter) MaxParameters() int {
+	return 2100
+}
+
+// Quote quote s as [s], ] in s is escaped as ]]
 func (mssql MssqlDialecter) Quote(s string) string {
-	return "[" + s + "]"
+	return quoteWith(s, "[", "]")
 }
 
 // TableSql return sql to query table schema
@@ -386,6 +1126,32 @@ func (mssql MssqlDialecter) ParametersSql(name string) string {
 	return fmt.Sprintf("SELECT Substring(PARAMETER_NAME,2,len(PARAMETER_NAME)-1) as [name], ORDINAL_POSITION as [position], PARAMETER_MODE as [dirmode], DATA_TYPE as [datatype],ISNULL(CHARACTER_MAXIMUM_LENGTH,0) as [length], ISNULL(NUMERIC_PRECISION,0) as [precision], ISNULL(NUMERIC_SCALE,0) as [scale] FROM information_schema.PARAMETERS WHERE SPECIFIC_NAME = '%s' ORDER BY ORDINAL_POSITION", name)
 }
 
+// PrimaryKeySql return sql to query primary key columns of table
+func (mssql MssqlDialecter) PrimaryKeySql(name string) string {
+	return fmt.Sprintf("SELECT kcu.COLUMN_NAME AS [name] FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc INNER JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu ON tc.CONSTRAINT_NAME = kcu.CONSTRAINT_NAME AND tc.TABLE_SCHEMA = kcu.TABLE_SCHEMA AND tc.TABLE_NAME = kcu.TABLE_NAME WHERE tc.CONSTRAINT_TYPE = 'PRIMARY KEY' AND tc.TABLE_NAME = '%s' ORDER BY kcu.ORDINAL_POSITION", name)
+}
+
+// NativeType convert ansi.DbType to ms sql server data type
+func (mssql MssqlDialecter) NativeType(t ansi.DbType, length, precision, scale int) string {
+	switch t {
+	case ansi.String:
+		return nativeTypeLength("NVARCHAR", length, "NVARCHAR(MAX)")
+	case ansi.Boolean:
+		return "BIT"
+	case ansi.Bytes:
+		return nativeTypeLength("VARBINARY", length, "VARBINARY(MAX)")
+	case ansi.DateTime:
+		return "DATETIME2"
+	case ansi.Guid:
+		return "UNIQUEIDENTIFIER"
+	case ansi.Int:
+		return "INT"
+	case ansi.Uint:
+		return "BIGINT"
+	}
+	return mssql.AnsiDialecter.NativeType(t, length, precision, scale)
+}
+
 // MysqlDialecter is Mysql dialect
 type MysqlDialecter struct {
 	AnsiDialecter
@@ -396,14 +1162,28 @@ func (mysql MysqlDialecter) Name() string {
 	return "mysql"
 }
 
+// SetOperatorSql return UNION|UNION ALL, mysql doesn't support EXCEPT and INTERSECT before 8.0.31
+func (mysql MysqlDialecter) SetOperatorSql(op SetOperator) string {
+	switch op {
+	case Union, UnionAll:
+		return op.String()
+	}
+	return ""
+}
+
+// MaxParameters return 65535
+func (mysql MysqlDialecter) MaxParameters() int {
+	return 65535
+}
+
 // QuoteString quote s as sql native string 
 func (mysql MysqlDialecter) QuoteString(s string) string {
 	return "\"" + s + "\""
 }
 
-// Quote quote s as 's'
+// Quote quote s as `s`, backtick in s is doubled. it used to quote s as 's', which is a string literal rather than identifier
 func (mysql MysqlDialecter) Quote(s string) string {
-	return "'" + s + "'"
+	return quoteWith(s, "`", "`")
 }
 
 // TableSql return sql to query table schema
@@ -416,7 +1196,7 @@ func (mysql MysqlDialecter) TableSql(name string) string {
 func (mysql MysqlDialecter) ColumnsSql(name string) string {
 	// http://dev.mysql.com/doc/refman/5.0/en/show-columns.html
 	// show columns from ttable 
-	return fmt.Sprintf("SELECT COLUMN_NAME as `name`, ORDINAL_POSITION as `position`, CASE IS_NULLABLE WHEN 'YES' THEN TRUE ELSE FALSE END as `nullable`, DATA_TYPE as `datatype`, IFNULL(CHARACTER_MAXIMUM_LENGTH,0) as `length`, IFNULL(NUMERIC_PRECISION,0) as `precision`, IFNULL(NUMERIC_SCALE,0) as `scale`, CASE WHEN EXTRA LIKE '%%auto_increment%%' THEN TRUE ELSE FALSE END AS `autoincrement`, CASE WHEN EXTRA LIKE '%%auto_increment%%' THEN TRUE ELSE FALSE END AS `readonly`, CASE WHEN COLUMN_KEY = 'PRI' THEN TRUE ELSE FALSE END AS `primarykey` FROM information_schema.COLUMNS WHERE TABLE_NAME = '%s' and TABLE_SCHEMA= DATABASE() ORDER BY ORDINAL_POSITION ;", name)
+	return fmt.Sprintf("SELECT COLUMN_NAME as `name`, ORDINAL_POSITION as `position`, CASE IS_NULLABLE WHEN 'YES' THEN TRUE ELSE FALSE END as `nullable`, DATA_TYPE as `datatype`, IFNULL(CHARACTER_MAXIMUM_LENGTH,0) as `length`, IFNULL(NUMERIC_PRECISION,0) as `precision`, IFNULL(NUMERIC_SCALE,0) as `scale`, CASE WHEN EXTRA LIKE '%%auto_increment%%' THEN TRUE ELSE FALSE END AS `autoincrement`, CASE WHEN EXTRA LIKE '%%auto_increment%%' THEN TRUE ELSE FALSE END AS `readonly`, CASE WHEN COLUMN_KEY = 'PRI' THEN TRUE ELSE FALSE END AS `primarykey`, COLUMN_TYPE as `columntype`, COLUMN_DEFAULT as `default` FROM information_schema.COLUMNS WHERE TABLE_NAME = '%s' and TABLE_SCHEMA= DATABASE() ORDER BY ORDINAL_POSITION ;", name)
 }
 
 // FunctionSql return sql to query procedure schema
@@ -430,6 +1210,90 @@ func (mysql MysqlDialecter) ParametersSql(name string) string {
 	return fmt.Sprintf("SELECT PARAMETER_NAME as `name`, ORDINAL_POSITION as `position`, PARAMETER_MODE as `dirmode`, DATA_TYPE as `datatype`, IFNULL(CHARACTER_MAXIMUM_LENGTH,0) as `length`, IFNULL(NUMERIC_PRECISION,0) as `precision`, IFNULL(NUMERIC_SCALE,0) as `scale` FROM information_schema.PARAMETERS WHERE SPECIFIC_NAME = '%s' and SPECIFIC_SCHEMA = DATABASE() ORDER BY ORDINAL_POSITION", name)
 }
 
+// LimitSql return LIMIT offset,count
+func (mysql MysqlDialecter) LimitSql(offset, count int) string {
+	return limitCommaSql(offset, count)
+}
+
+// NullSafeEqualSql return <=>
+func (mysql MysqlDialecter) NullSafeEqualSql() string {
+	return ansi.Spaceship
+}
+
+// ExplainPrefix return EXPLAIN ANALYZE if analyze is true, otherwise EXPLAIN FORMAT=format or EXPLAIN
+func (mysql MysqlDialecter) ExplainPrefix(analyze bool, format string) string {
+	if analyze {
+		return "EXPLAIN ANALYZE"
+	} else if format != "" {
+		return "EXPLAIN FORMAT=" + strings.ToUpper(format)
+	}
+	return "EXPLAIN"
+}
+
+// GroupingSetSql return WITH ROLLUP as suffix, mysql doesn't support CUBE
+func (mysql MysqlDialecter) GroupingSetSql(gs GroupingSet) (string, bool) {
+	if gs != Rollup {
+		return "", false
+	}
+	return ansi.With + " " + gs.String(), true
+}
+
+// LockSql return FOR UPDATE|FOR SHARE [NOWAIT|SKIP LOCKED]
+func (mysql MysqlDialecter) LockSql(lock *Lock) string {
+	return lockSql(lock, true)
+}
+
+// PrimaryKeySql return sql to query primary key columns of table
+func (mysql MysqlDialecter) PrimaryKeySql(name string) string {
+	return fmt.Sprintf("SELECT COLUMN_NAME as `name` FROM information_schema.KEY_COLUMN_USAGE WHERE CONSTRAINT_NAME = 'PRIMARY' AND TABLE_NAME = '%s' AND TABLE_SCHEMA = DATABASE() ORDER BY ORDINAL_POSITION", name)
+}
+
+// ForeignKeysSql return sql to query foreign key columns of table
+func (mysql MysqlDialecter) ForeignKeysSql(name string) string {
+	// http://dev.mysql.com/doc/refman/5.1/en/referential-constraints-table.html
+	return fmt.Sprintf("SELECT kcu.CONSTRAINT_NAME as `name`, kcu.COLUMN_NAME as `column`, kcu.REFERENCED_TABLE_NAME as `reftable`, kcu.REFERENCED_COLUMN_NAME as `refcolumn`, rc.DELETE_RULE as `ondelete`, rc.UPDATE_RULE as `onupdate` FROM information_schema.KEY_COLUMN_USAGE kcu INNER JOIN information_schema.REFERENTIAL_CONSTRAINTS rc ON rc.CONSTRAINT_SCHEMA = kcu.CONSTRAINT_SCHEMA AND rc.CONSTRAINT_NAME = kcu.CONSTRAINT_NAME AND rc.TABLE_NAME = kcu.TABLE_NAME WHERE kcu.TABLE_NAME = '%s' AND kcu.TABLE_SCHEMA = DATABASE() ORDER BY kcu.CONSTRAINT_NAME, kcu.ORDINAL_POSITION", name)
+}
+
+// IndexesSql return sql to query index columns of table
+func (mysql MysqlDialecter) IndexesSql(name string) string {
+	// http://dev.mysql.com/doc/refman/5.1/en/statistics-table.html
+	return fmt.Sprintf("SELECT INDEX_NAME as `name`, COLUMN_NAME as `column`, CASE NON_UNIQUE WHEN 0 THEN TRUE ELSE FALSE END as `unique` FROM information_schema.STATISTICS WHERE TABLE_NAME = '%s' AND TABLE_SCHEMA = DATABASE() ORDER BY INDEX_NAME, SEQ_IN_INDEX", name)
+}
+
+// NativeType convert ansi.DbType to mysql data type
+func (mysql MysqlDialecter) NativeType(t ansi.DbType, length, precision, scale int) string {
+	switch t {
+	case ansi.String:
+		return nativeTypeLength("VARCHAR", length, "TEXT")
+	case ansi.Boolean:
+		return "TINYINT(1)"
+	case ansi.DateTime:
+		return "DATETIME"
+	case ansi.Int:
+		return "INT"
+	case ansi.Uint:
+		return "BIGINT UNSIGNED"
+	case ansi.Float:
+		return "DOUBLE"
+	case ansi.Json:
+		return "JSON"
+	}
+	return mysql.AnsiDialecter.NativeType(t, length, precision, scale)
+}
+
+// CastType return target type of mysql CAST, like SIGNED, CHAR(10)
+func (mysql MysqlDialecter) CastType(t ansi.DbType, length, precision, scale int) string {
+	return mysqlCastType(t, length, precision, scale)
+}
+
+// DbType convert mysql data type to ansi.DbType
+func (mysql MysqlDialecter) DbType(nativeType string) ansi.DbType {
+	if strings.EqualFold(strings.TrimSpace(nativeType), "json") {
+		return ansi.Json
+	}
+	return mysql.AnsiDialecter.DbType(nativeType)
+}
+
 // PostgreSQLDialecter is PostgreSQL dialect
 type PostgreSQLDialecter struct {
 	AnsiDialecter
@@ -440,11 +1304,36 @@ func (pgsql PostgreSQLDialecter) Name() string {
 	return "postgres"
 }
 
+// MaxParameters return 65535
+func (pgsql PostgreSQLDialecter) MaxParameters() int {
+	return 65535
+}
+
 // SupportIndexedParameter regturn true
 func (pgsql PostgreSQLDialecter) SupportIndexedParameter() bool {
 	return true
 }
 
+// SupportArrayParameter return true
+func (pgsql PostgreSQLDialecter) SupportArrayParameter() bool {
+	return true
+}
+
+// SupportDistinctOn return true
+func (pgsql PostgreSQLDialecter) SupportDistinctOn() bool {
+	return true
+}
+
+// SupportCastShorthand return true
+func (pgsql PostgreSQLDialecter) SupportCastShorthand() bool {
+	return true
+}
+
+// SupportLateral return true
+func (pgsql PostgreSQLDialecter) SupportLateral() bool {
+	return true
+}
+
 // ParameterPlaceHolder return $
 func (pgsql PostgreSQLDialecter) ParameterPlaceHolder() string {
 	return "$"
@@ -455,9 +1344,9 @@ func (pgsql PostgreSQLDialecter) QuoteString(s string) string {
 	return "'" + s + "'"
 }
 
-// Quote quote s as 's'
+// Quote quote s as "s", " in s is escaped as ""
 func (pgsql PostgreSQLDialecter) Quote(s string) string {
-	return "\"" + s + "\""
+	return quoteWith(s, "\"", "\"")
 }
 
 // Table return sql to query table schema
@@ -535,6 +1424,81 @@ order by
 `, name)
 }
 
+// LimitSql return LIMIT count OFFSET offset
+func (pgsql PostgreSQLDialecter) LimitSql(offset, count int) string {
+	return limitOffsetSql(offset, count)
+}
+
+// ExplainPrefix return EXPLAIN ANALYZE if analyze is true, otherwise EXPLAIN
+func (pgsql PostgreSQLDialecter) ExplainPrefix(analyze bool, format string) string {
+	if analyze {
+		return "EXPLAIN ANALYZE"
+	}
+	return "EXPLAIN"
+}
+
+// LockSql return FOR UPDATE|FOR SHARE [NOWAIT|SKIP LOCKED]
+func (pgsql PostgreSQLDialecter) LockSql(lock *Lock) string {
+	return lockSql(lock, true)
+}
+
+// PrimaryKeySql return sql to query primary key columns of table
+func (pgsql PostgreSQLDialecter) PrimaryKeySql(name string) string {
+	return fmt.Sprintf(`
+select 
+	kc.column_name as "name"
+from 
+	information_schema.table_constraints tc,
+	information_schema.key_column_usage kc
+where 
+	tc.constraint_type = 'PRIMARY KEY'
+	and kc.table_name = tc.table_name and kc.table_schema = tc.table_schema and kc.constraint_name = tc.constraint_name
+	and tc.table_name = '%s' and tc.table_schema = current_schema()
+order by 
+	kc.ordinal_position ;
+`, name)
+}
+
+// DbType convert postgres data type and its alias to ansi.DbType
+func (pgsql PostgreSQLDialecter) DbType(nativeType string) ansi.DbType {
+	switch strings.ToLower(strings.TrimSpace(nativeType)) {
+	case "int2", "int4", "int8", "smallint", "integer", "bigint", "serial2", "serial4", "serial8", "oid":
+		return ansi.Int
+	case "float4", "float8":
+		return ansi.Float
+	case "bool":
+		return ansi.Boolean
+	case "bpchar", "varchar", "name", "citext", "inet", "cidr", "macaddr", "interval":
+		return ansi.String
+	case "json", "jsonb":
+		return ansi.Json
+	case "timestamptz", "timestamp", "timetz", "time without time zone", "time with time zone":
+		return ansi.DateTime
+	case "uuid":
+		return ansi.Guid
+	case "bytea":
+		return ansi.Bytes
+	}
+	return pgsql.AnsiDialecter.DbType(nativeType)
+}
+
+// NativeType convert ansi.DbType to postgres data type
+func (pgsql PostgreSQLDialecter) NativeType(t ansi.DbType, length, precision, scale int) string {
+	switch t {
+	case ansi.String:
+		return nativeTypeLength("VARCHAR", length, "TEXT")
+	case ansi.Bytes:
+		return "BYTEA"
+	case ansi.Guid:
+		return "UUID"
+	case ansi.Float:
+		return "DOUBLE PRECISION"
+	case ansi.Json:
+		return "JSONB"
+	}
+	return pgsql.AnsiDialecter.NativeType(t, length, precision, scale)
+}
+
 // OracleSQLDialecter is oracle dialect
 type OracleSQLDialecter struct {
 	AnsiDialecter
@@ -545,6 +1509,37 @@ func (oracle OracleSQLDialecter) Name() string {
 	return "oracle"
 }
 
+// MaxParameters return 65535
+func (oracle OracleSQLDialecter) MaxParameters() int {
+	return 65535
+}
+
+// DualTable return DUAL
+func (oracle OracleSQLDialecter) DualTable() string {
+	return "DUAL"
+}
+
+// SetOperatorSql return UNION|UNION ALL|MINUS|INTERSECT, oracle doesn't support EXCEPT ALL and INTERSECT ALL
+func (oracle OracleSQLDialecter) SetOperatorSql(op SetOperator) string {
+	switch op {
+	case Union, UnionAll, Intersect:
+		return op.String()
+	case Except:
+		return ansi.MinusSet
+	}
+	return ""
+}
+
+// NullSafeEqualSql return "", oracle doesn't support null-safe equality operator
+func (oracle OracleSQLDialecter) NullSafeEqualSql() string {
+	return ""
+}
+
+// ExplainPrefix return "", EXPLAIN PLAN of oracle doesn't return rows
+func (oracle OracleSQLDialecter) ExplainPrefix(analyze bool, format string) string {
+	return ""
+}
+
 // ParameterPlaceHolder return :
 func (oracle OracleSQLDialecter) ParameterPlaceHolder() string {
 	return ":"
@@ -560,9 +1555,13 @@ func (oracle OracleSQLDialecter) SupportIndexedParameter() bool {
 	return true
 }
 
-// Quote doesn't quote identifier 
+// Quote doesn't quote plain identifier, because quoted identifier of oracle is case sensitive;
+// other names are quoted as "s" and " in s is escaped as ""
 func (oracle OracleSQLDialecter) Quote(s string) string {
-	return s
+	if plainIdentifierRegexp.MatchString(s) {
+		return s
+	}
+	return quoteWith(s, "\"", "\"")
 }
 
 // Table return sql to query table schema
@@ -635,14 +1634,184 @@ order by
 `, name)
 }
 
+// LockSql return FOR UPDATE [NOWAIT|SKIP LOCKED], oracle doesn't support FOR SHARE
+func (oracle OracleSQLDialecter) LockSql(lock *Lock) string {
+	return lockSql(lock, false)
+}
+
+// PrimaryKeySql return sql to query primary key columns of table
+func (oracle OracleSQLDialecter) PrimaryKeySql(name string) string {
+	return fmt.Sprintf(`
+select 
+	cc.COLUMN_NAME as name
+from 
+	all_cons_columns cc inner join all_constraints cs on cs.CONSTRAINT_NAME = cc.CONSTRAINT_NAME and cc.OWNER = cs.OWNER
+where 
+	cs.CONSTRAINT_TYPE = 'P' and cs.TABLE_NAME = '%s' and cs.OWNER = (select sys_context('USERENV','SESSION_USER') from dual)
+order by 
+	cc.POSITION
+`, name)
+}
+
 // SplitStatement return nothing 
 func (oracle OracleSQLDialecter) SplitStatement() string {
 	return " "
 }
 
+// NativeType convert ansi.DbType to oracle data type
+func (oracle OracleSQLDialecter) NativeType(t ansi.DbType, length, precision, scale int) string {
+	switch t {
+	case ansi.String:
+		return nativeTypeLength("VARCHAR2", length, "CLOB")
+	case ansi.Boolean:
+		return "NUMBER(1)"
+	case ansi.Bytes:
+		return "BLOB"
+	case ansi.Int:
+		return "NUMBER(19)"
+	case ansi.Uint:
+		return "NUMBER(20)"
+	case ansi.Numeric:
+		return nativeTypePrecision("NUMBER", precision, scale)
+	case ansi.Float:
+		return "BINARY_DOUBLE"
+	}
+	return oracle.AnsiDialecter.NativeType(t, length, precision, scale)
+}
+
+// ClickHouseDialecter is ClickHouse dialect
+type ClickHouseDialecter struct {
+	AnsiDialecter
+}
+
+// Name return "clickhouse"
+func (ch ClickHouseDialecter) Name() string {
+	return "clickhouse"
+}
+
+// ParameterPlaceHolder return ?
+func (ch ClickHouseDialecter) ParameterPlaceHolder() string {
+	return "?"
+}
+
+// LimitSql return LIMIT count OFFSET offset
+func (ch ClickHouseDialecter) LimitSql(offset, count int) string {
+	return limitOffsetSql(offset, count)
+}
+
+// NullSafeEqualSql return "", clickhouse doesn't support null-safe equality operator
+func (ch ClickHouseDialecter) NullSafeEqualSql() string {
+	return ""
+}
+
+// GroupingSetSql return WITH gs as suffix, like GROUP BY a, b WITH CUBE
+func (ch ClickHouseDialecter) GroupingSetSql(gs GroupingSet) (string, bool) {
+	return ansi.With + " " + gs.String(), true
+}
+
+// Quote quote s as `s`, backtick in s is doubled
+func (ch ClickHouseDialecter) Quote(s string) string {
+	return quoteWith(s, "`", "`")
+}
+
+// DbType convert ClickHouse data type to ansi.DbType, Nullable(T) and LowCardinality(T) are treated as T
+func (ch ClickHouseDialecter) DbType(nativeType string) ansi.DbType {
+	t := strings.TrimSpace(nativeType)
+	for unwrap := true; unwrap; {
+		unwrap = false
+		for _, wrapper := range []string{"Nullable(", "LowCardinality("} {
+			if strings.HasPrefix(t, wrapper) && strings.HasSuffix(t, ")") {
+				t = t[len(wrapper) : len(t)-1]
+				unwrap = true
+			}
+		}
+	}
+	if i := strings.Index(t, "("); i > 0 {
+		t = t[:i]
+	}
+
+	switch strings.ToLower(t) {
+	case "uint8", "uint16", "uint32", "uint64":
+		return ansi.Uint
+	case "int8", "int16", "int32", "int64":
+		return ansi.Int
+	case "float32", "float64":
+		return ansi.Float
+	case "decimal", "decimal32", "decimal64", "decimal128":
+		return ansi.Numeric
+	case "string", "fixedstring", "enum8", "enum16":
+		return ansi.String
+	case "date", "date32":
+		return ansi.Date
+	case "datetime", "datetime64":
+		return ansi.DateTime
+	case "bool":
+		return ansi.Boolean
+	case "uuid":
+		return ansi.Guid
+	}
+	return ch.AnsiDialecter.DbType(t)
+}
+
+// NativeType convert ansi.DbType to ClickHouse data type
+func (ch ClickHouseDialecter) NativeType(t ansi.DbType, length, precision, scale int) string {
+	switch t {
+	case ansi.String, ansi.Bytes:
+		return "String"
+	case ansi.Boolean:
+		return "UInt8"
+	case ansi.Date:
+		return "Date"
+	case ansi.DateTime:
+		return "DateTime"
+	case ansi.Guid:
+		return "UUID"
+	case ansi.Int:
+		return "Int64"
+	case ansi.Uint:
+		return "UInt64"
+	case ansi.Numeric:
+		return nativeTypePrecision("Decimal", precision, scale)
+	case ansi.Float:
+		return "Float64"
+	}
+	return ""
+}
+
+// InNullMode is how to compile nil element of IN list, col IN (NULL) never match
+type InNullMode int
+
+const (
+	// InNullDrop drop nil elements, col IN (1, nil) is col IN (1), col NOT IN (1, nil) is col NOT IN (1)
+	InNullDrop InNullMode = 0
+
+	// InNullMatch match NULL, col IN (1, nil) is (col IN (1) OR col IS NULL),
+	// col NOT IN (1, nil) is (col NOT IN (1) AND col IS NOT NULL)
+	InNullMatch InNullMode = 1
+)
+
 // SqlDriver is ansi sql compiler
 type SqlDriver struct {
 	Dialecter Dialecter
+
+	// Compact collapse whitespace of compiled sql to single space
+	Compact bool
+
+	// MaxInItems split IN list into chunks of MaxInItems items, 0 means doesn't split
+	MaxInItems int
+
+	// ArrayIn pass IN list as a single array parameter if dialect support it, the parameter is a driver.Valuer
+	// that encode the list as text of postgres array, like {1,2,3}
+	ArrayIn bool
+
+	// InNull is how to compile nil element of IN list
+	InNull InNullMode
+
+	// OmitTerminator suppress trailing statement terminator, like ;
+	OmitTerminator bool
+
+	// OmitDefaultAsc omit direction of order by field if it's unset, explicit ASC is still written
+	OmitDefaultAsc bool
 }
 
 // NewSqlDriver return a SqlDriver
@@ -653,10 +1822,17 @@ func NewSqlDriver(dialecter Dialecter) Compiler {
 // Compile compile expression to ansi sql
 func (c *SqlDriver) Compile(source string, exp Expression) (query string, args []interface{}, err error) {
 	if exp == nil {
-		err = errors.New("compile expression is nil")
+		err = newCompileError(CompileInvalid, NodeZero, "", "compile expression is nil")
 		return
 	}
 
+	defer func() {
+		if r := recover(); r != nil {
+			query, args = "", nil
+			err = newCompileError(CompileInvalid, exp.Node(), "", fmt.Sprint("compile panic:", r))
+		}
+	}()
+
 	switch exp.Node() {
 	case NodeText:
 		t, _ := exp.(*Text)
@@ -664,17 +1840,157 @@ func (c *SqlDriver) Compile(source string, exp Expression) (query string, args [
 	case NodeProcedure:
 		p, _ := exp.(*Procedure)
 		return c.compileProcedure(p, source)
-	case NodeQuery, NodeUpdate, NodeInsert, NodeDelete:
-		return NewStmtCompiler(c.Dialecter).Compile(exp, source)
+	case NodeQuery, NodeUpdate, NodeInsert, NodeDelete, NodeDropTable, NodeTruncate:
+		sc := c.newStmtCompiler()
+		return sc.Compile(exp, source)
+	}
+
+	err = newCompileError(CompileUnsupported, exp.Node(), "", fmt.Sprint("compile expression does support type:", exp.Node()))
+	return
+}
+
+// newStmtCompiler return *StmtCompiler with options of c
+func (c *SqlDriver) newStmtCompiler() *StmtCompiler {
+	sc := NewStmtCompiler(c.Dialecter)
+	sc.Compact = c.Compact
+	sc.MaxInItems = c.MaxInItems
+	sc.ArrayIn = c.ArrayIn
+	sc.InNull = c.InNull
+	sc.OmitTerminator = c.OmitTerminator
+	sc.OmitDefaultAsc = c.OmitDefaultAsc
+	return sc
+}
+
+// CompileNamed compile expression like Compile, names[i] is parameter name of args[i] if dialect support named parameter
+func (c *SqlDriver) CompileNamed(source string, exp Expression) (query string, args []interface{}, names []string, err error) {
+	if exp == nil {
+		err = newCompileError(CompileInvalid, NodeZero, "", "compile expression is nil")
+		return
+	}
+
+	switch exp.Node() {
+	case NodeQuery, NodeUpdate, NodeInsert, NodeDelete, NodeDropTable, NodeTruncate:
+		sc := c.newStmtCompiler()
+		if query, args, err = sc.Compile(exp, source); err == nil {
+			names = sc.ParameterNames()
+		}
+		return
+	}
+
+	err = newCompileError(CompileUnsupported, exp.Node(), "", fmt.Sprint("compile named expression doesn't support type:", exp.Node()))
+	return
+}
+
+// CompileCreateTable compile schema of table to native create table sql
+func (c *SqlDriver) CompileCreateTable(source string, t *ansi.DbTable) (query string, err error) {
+	if t == nil || t.Name == "" || len(t.Columns) == 0 {
+		err = newCompileError(CompileInvalid, NodeTable, "", "table is nil or columns of table is empty")
+		return
+	}
+
+	name, ok := quoteIdentifier(c.Dialecter, t.Name)
+	if !ok {
+		err = newCompileError(CompileInvalid, NodeTable, "", "invalid identifier:"+t.Name)
+		return
+	}
+
+	dialect := c.Dialecter.Name()
+	w := &sqlWriter{}
+	w.Print(ansi.CreateTable, " ", name, " ")
+	w.OpenParentheses()
+
+	keys := make([]string, 0, len(t.Columns))
+	inlineKey := false
+	for i := 0; i < len(t.Columns); i++ {
+		col := t.Columns[i]
+		if i > 0 {
+			w.WriteString(ansi.Comma)
+		}
+		w.WriteString("\n\t")
+
+		colName, ok := quoteIdentifier(c.Dialecter, col.Name)
+		if !ok {
+			err = newCompileError(CompileInvalid, NodeColumn, "", "invalid identifier:"+col.Name)
+			return
+		}
+
+		nativeType := dialectNativeType(c.Dialecter, col.DbType, col.Size, col.Precision, col.Scale)
+		if nativeType == "" {
+			nativeType = col.NativeType
+		}
+		if nativeType == "" {
+			err = newCompileError(CompileUnsupported, NodeColumn, "", "can not get native type of column:"+col.Name)
+			return
+		}
+
+		if col.IsAutoIncrement {
+			switch dialect {
+			case "postgres":
+				nativeType = serialType(col, nativeType)
+			case "sqlite":
+				nativeType = "INTEGER"
+			}
+		}
+		w.Print(colName, " ", nativeType)
+
+		if col.IsNullable && !col.IsPrimaryKey {
+			w.Print(" ", ansi.Null)
+		} else {
+			w.Print(" ", ansi.NotNull)
+		}
+
+		if col.IsAutoIncrement {
+			switch dialect {
+			case "mysql":
+				w.WriteString(" AUTO_INCREMENT")
+			case "mssql":
+				w.WriteString(" IDENTITY(1,1)")
+			case "sqlite":
+				w.WriteString(" PRIMARY KEY AUTOINCREMENT")
+				inlineKey = true
+			case "postgres":
+			default:
+				w.WriteString(" GENERATED BY DEFAULT AS IDENTITY")
+			}
+		}
+
+		if col.IsPrimaryKey {
+			keys = append(keys, colName)
+		}
+	}
+
+	if len(keys) > 0 && !inlineKey {
+		w.Print(ansi.Comma, "\n\t", ansi.PrimaryKey, " ")
+		w.OpenParentheses()
+		w.PrintSplit(", ", keys...)
+		w.CloseParentheses()
+	}
+
+	w.WriteString("\n")
+	w.CloseParentheses()
+	if !c.OmitTerminator {
+		w.WriteString(c.Dialecter.SplitStatement())
 	}
 
-	err = errors.New(fmt.Sprint("compile expression does support type:", exp.Node()))
+	query = w.String()
 	return
 }
 
+// serialType return BIGSERIAL of postgres for 64-bit integer column, otherwise SERIAL
+func serialType(col ansi.DbColumn, nativeType string) string {
+	switch strings.ToUpper(strings.TrimSpace(col.NativeType)) {
+	case "BIGINT", "INT8", "BIGSERIAL", "SERIAL8":
+		return "BIGSERIAL"
+	}
+	if col.DbType == ansi.Uint || strings.ToUpper(nativeType) == "BIGINT" {
+		return "BIGSERIAL"
+	}
+	return "SERIAL"
+}
+
 func (c *SqlDriver) compileText(text *Text, source string) (query string, args []interface{}, err error) {
 	if text == nil || text.Sql == "" {
-		err = errors.New("text is nil or sql of text is empty")
+		err = newCompileError(CompileInvalid, NodeText, "", "text is nil or sql of text is empty")
 		return
 	}
 
@@ -715,7 +2031,7 @@ func (c *SqlDriver) compileText(text *Text, source string) (query string, args [
 				name := string(bytes.TrimSpace((b[:index])))
 				p, ok := text.FindParameter(name)
 				if !ok {
-					err = errors.New("text can not find parameter:" + name)
+					err = newCompileError(CompileMissingParameter, NodeText, name, "text can not find parameter:"+name)
 					return
 				}
 				buffer.WriteString(placeHolder)
@@ -734,7 +2050,7 @@ func (c *SqlDriver) compileText(text *Text, source string) (query string, args [
 				b = b[index+1:]
 				state = 0
 			} else {
-				err = errors.New("text sql format is invalid")
+				err = newCompileError(CompileInvalidFormat, NodeText, "", "text sql format is invalid")
 				return
 			}
 		}
@@ -757,7 +2073,7 @@ func (c *SqlDriver) compileMysqlProcedure(sp *Procedure, source string) (query s
 	for i := 0; i < l; i++ {
 		p := sp.Parameters[i]
 		if p.Dir == ansi.DirInOut {
-			buffer.Print("SET @", p.Name, " = ?; \n")
+			buffer.Print("SET @", c.Dialecter.Quote(p.Name), " = ?; \n")
 			paramters = append(paramters, p.Value)
 		}
 	}
@@ -765,23 +2081,28 @@ func (c *SqlDriver) compileMysqlProcedure(sp *Procedure, source string) (query s
 	if returnName == "" {
 		buffer.WriteString("CALL ")
 	} else {
-		buffer.WriteString("SET @" + returnName)
+		buffer.Print("SET @", c.Dialecter.Quote(returnName), " = ")
 	}
-	buffer.WriteString(sp.Name)
+	buffer.WriteString(procedureName(c.Dialecter, sp.Name))
 	buffer.WriteString(" ( ")
+	split := false
 	for i := 0; i < l; i++ {
-		if i > 0 {
+		p := sp.Parameters[i]
+		if p.Dir == ansi.DirReturn {
+			continue
+		}
+		if split {
 			buffer.WriteString(", ")
 		}
-		p := sp.Parameters[i]
+		split = true
 		if p.Dir == ansi.DirIn {
 			buffer.WriteString("?")
 			paramters = append(paramters, p.Value)
 		} else if p.Dir == ansi.DirInOut {
-			buffer.Print("@", p.Name)
+			buffer.Print("@", c.Dialecter.Quote(p.Name))
 			hasOut = true
 		} else if p.Dir == ansi.DirOut {
-			buffer.Print("@", p.Name)
+			buffer.Print("@", c.Dialecter.Quote(p.Name))
 			hasOut = true
 		}
 	}
@@ -796,7 +2117,7 @@ func (c *SqlDriver) compileMysqlProcedure(sp *Procedure, source string) (query s
 				if delimit {
 					buffer.WriteString(", ")
 				}
-				buffer.Print("@", p.Name)
+				buffer.Print("@", c.Dialecter.Quote(p.Name), " ", ansi.As, " ", c.Dialecter.Quote(p.Name))
 				delimit = true
 			}
 		}
@@ -815,7 +2136,7 @@ func (c *SqlDriver) compileOracleProcedure(sp *Procedure, source string) (query
 
 	// no parameter
 	if l == 0 {
-		w.WriteString("begin " + sp.Name + "(); end; ")
+		w.WriteString("begin " + procedureName(c.Dialecter, sp.Name) + "(); end; ")
 		query = w.String()
 		args = paramters
 		return
@@ -825,9 +2146,9 @@ func (c *SqlDriver) compileOracleProcedure(sp *Procedure, source string) (query
 	split := false
 	retName := sp.ReturnParameterName()
 	if retName == "" {
-		w.WriteString("begin " + sp.Name + "( ")
+		w.WriteString("begin " + procedureName(c.Dialecter, sp.Name) + "( ")
 	} else {
-		w.WriteString("begin :" + retName + ":= " + sp.Name + "( ")
+		w.WriteString("begin :" + retName + ":= " + procedureName(c.Dialecter, sp.Name) + "( ")
 	}
 
 	for i := 0; i < l; i++ {
@@ -875,7 +2196,7 @@ func (c *SqlDriver) compileMssqlProcedure(sp *Procedure, source string) (query s
 	w := &sqlWriter{}
 
 	if !sp.HasOutParameter() {
-		w.Print("exec ", sp.Name, " ")
+		w.Print("exec ", procedureName(c.Dialecter, sp.Name), " ")
 
 		for i := 0; i < l; i++ {
 			p := sp.Parameters[i]
@@ -917,7 +2238,7 @@ func (c *SqlDriver) compileMssqlProcedure(sp *Procedure, source string) (query s
 	}
 
 	split = false
-	w.Print("exec ", sp.Name, " ")
+	w.Print("exec ", procedureName(c.Dialecter, sp.Name), " ")
 	for i := 0; i < l; i++ {
 		p := sp.Parameters[i]
 		if p.Dir == ansi.DirReturn {
@@ -969,7 +2290,7 @@ func (c *SqlDriver) compilePostgresProcedure(sp *Procedure, source string) (quer
 	index := 1
 
 	w.WriteString("SELECT * FROM ")
-	w.WriteString(sp.Name)
+	w.WriteString(procedureName(c.Dialecter, sp.Name))
 	w.OpenParentheses()
 
 	for i := 0; i < l; i++ {
@@ -987,19 +2308,53 @@ func (c *SqlDriver) compilePostgresProcedure(sp *Procedure, source string) (quer
 	}
 
 	w.CloseParentheses()
-	w.WriteString(ansi.StatementSplit)
+	if !c.OmitTerminator {
+		w.WriteString(ansi.StatementSplit)
+	}
 
 	query = w.String()
 	args = paramters
 	return
 }
 
+// procedureName return quoted name of procedure, schema qualified name is quoted by part, like "schema"."proc";
+// return "" if name is invalid
+func procedureName(d Dialecter, name string) string {
+	for _, part := range strings.Split(name, ansi.Split) {
+		if strings.TrimSpace(part) == "" {
+			return ""
+		}
+	}
+	s, ok := quoteIdentifier(d, name)
+	if !ok {
+		return ""
+	}
+	return s
+}
+
 func (c *SqlDriver) compileProcedure(sp *Procedure, source string) (query string, args []interface{}, err error) {
 	if sp == nil || sp.Name == "" {
-		err = errors.New("procedure is nil or name of procedure is empty")
+		err = newCompileError(CompileInvalid, NodeProcedure, "", "procedure is nil or name of procedure is empty")
 		return
 	}
-
+	if procedureName(c.Dialecter, sp.Name) == "" {
+		err = newCompileError(CompileInvalid, NodeProcedure, "", "invalid procedure name:"+sp.Name)
+		return
+	}
+	if err = sp.validate(); err != nil {
+		return
+	}
+
+	// parameter names are embedded in sql except postgres, like @name or name=>:name
+	if c.Dialecter.Name() != "postgres" {
+		for _, p := range sp.Parameters {
+			if p == nil || !simpleNameRegexp.MatchString(p.Name) {
+				err = newCompileError(CompileInvalid, NodeProcedure, "", "invalid parameter name of procedure:"+sp.Name)
+				return
+			}
+		}
+	}
+
 	switch c.Dialecter.Name() {
 	case "mysql":
 		return c.compileMysqlProcedure(sp, source)
@@ -1010,38 +2365,165 @@ func (c *SqlDriver) compileProcedure(sp *Procedure, source string) (query string
 	case "oracle":
 		return c.compileOracleProcedure(sp, source)
 	}
-	err = errors.New("driver dones't support procedure:" + c.Dialecter.Name())
+	err = newCompileError(CompileUnsupported, NodeProcedure, "", "driver dones't support procedure:"+c.Dialecter.Name())
 	return
 }
 
 // StmtCompiler can compile Update, Insert, Delete, Query
 type StmtCompiler struct {
 	// Dialecter is a provided Dialecter
-	Dialecter   Dialecter
+	Dialecter Dialecter
+
+	// Compact collapse whitespace of compiled sql to single space, pretty output with line breaks if false
+	Compact bool
+
+	// MaxInItems split IN list into chunks, like (a IN (...) OR a IN (...)), 0 means doesn't split
+	MaxInItems int
+
+	// ArrayIn write IN list as a = ANY(?) with a single array parameter if dialect support it
+	ArrayIn bool
+
+	// InNull is how to compile nil element of IN list, nil elements are dropped by default
+	InNull InNullMode
+
+	// ParamOffset is count of parameters bound before compiled sql, numbered placeholder start at ParamOffset+1
+	ParamOffset int
+
+	// OmitTerminator suppress trailing statement terminator, so compiled sql can be composed into a larger statement
+	OmitTerminator bool
+
+	// OmitDefaultAsc omit direction of order by field if it's unset, otherwise unset direction is written as ASC
+	OmitDefaultAsc bool
+
 	exp         Expression
 	source      string
 	w           *sqlWriter
 	args        []interface{}
+	names       []string
 	paraIndex   int
+	paraNames   map[string]int
 	placeHolder string
+	node        NodeType
+	err         error
+	capHint     int
+
+	// quoteColumn is true while visiting where & order by of single table update/delete,
+	// columns are quoted and validated like columns of SET
+	quoteColumn bool
+
+	// expect is sql that visit with discard is expected to write, see matchArgs
+	expect string
+
+	// planning is true while CompileCache compile a statement first time, sources of args are recorded to plan,
+	// planBroken is set if sql depends on values in a way that plan can't replay, see CompileCache
+	planning   bool
+	plan       []planStep
+	planBroken bool
 }
 
 // NewStmtCompiler return  *StmtCompiler with provided Dialecter
 func NewStmtCompiler(dialecter Dialecter) *StmtCompiler {
 	return &StmtCompiler{
 		Dialecter: dialecter,
-		args:      make([]interface{}, 0, _defaultCapicity),
 	}
 }
 
+// NewStmtCompilerSized return *StmtCompiler with provided Dialecter, capHint is initial bytes of sql buffer
+func NewStmtCompilerSized(dialecter Dialecter, capHint int) *StmtCompiler {
+	sc := NewStmtCompiler(dialecter)
+	sc.capHint = capHint
+	return sc
+}
+
+// WithParamOffset set ParamOffset, so sql can be appended after n bound parameters, like $4 follows $3
+func (sc *StmtCompiler) WithParamOffset(n int) *StmtCompiler {
+	if n < 0 {
+		n = 0
+	}
+	sc.ParamOffset = n
+	return sc
+}
+
+// CompileWhere compile where to sql fragment and args, like a = ? AND b = ?, the fragment start with WHERE if keyword is true.
+// empty where return "", use ParamOffset if fragment is composed after other parameters
+func (sc *StmtCompiler) CompileWhere(where *Where, keyword bool) (query string, args []interface{}, err error) {
+	if where == nil {
+		err = newCompileError(CompileInvalid, NodeWhere, "", "compile where is nil")
+		return
+	}
+	if err = sc.visit(where, "", false); err != nil {
+		return
+	}
+
+	query = strings.TrimSpace(sc.w.String())
+	if sc.Compact {
+		query = compactSql(query)
+	}
+	if keyword && query != "" {
+		query = ansi.Where + " " + query
+	}
+	args = sc.args
+	return
+}
+
 // Compile compile expression to ansi sql
 func (sc *StmtCompiler) Compile(exp Expression, source string) (query string, args []interface{}, err error) {
+	if err = sc.visit(exp, source, false); err != nil {
+		return
+	}
+
+	query = sc.w.String()
+	if sc.Compact {
+		query = compactSql(query)
+	}
+	args = sc.args
+
+	return
+}
+
+// CollectArgs visit expression and return args only, sql isn't generated.
+// args are same as args returned by Compile with same expression
+func (sc *StmtCompiler) CollectArgs(exp Expression, source string) (args []interface{}, err error) {
+	if err = sc.visit(exp, source, true); err != nil {
+		return
+	}
+	return sc.args, nil
+}
+
+// matchArgs visit expression and return args only like CollectArgs, ok is true if sql of expression is same as query.
+// query should be sql written by Compile without Compact
+func (sc *StmtCompiler) matchArgs(exp Expression, source string, query string) (args []interface{}, ok bool, err error) {
+	sc.expect = query
+	defer func() {
+		sc.expect = ""
+	}()
+
+	if err = sc.visit(exp, source, true); err != nil {
+		return
+	}
+	return sc.args, sc.w.matched(), nil
+}
+
+// visit walk expression, write sql to sc.w unless discard is true, args are collected to sc.args
+func (sc *StmtCompiler) visit(exp Expression, source string, discard bool) (err error) {
 	if exp == nil {
-		err = errors.New("compile expression is nil")
+		err = newCompileError(CompileInvalid, NodeZero, "", "compile expression is nil")
+		return
 	}
 
-	sc.w = &sqlWriter{}
+	// convert panic of visitor to error, sc.node is the last visited node
+	defer func() {
+		if r := recover(); r != nil {
+			err = newCompileError(CompileInvalid, sc.node, "", fmt.Sprint("compile panic:", r))
+		}
+	}()
+
+	sc.Reset()
+	sc.w.discard = discard
+	sc.w.expect, sc.w.mismatch = sc.expect, false
 	sc.source = source
+	sc.exp = exp
+	sc.node = exp.Node()
 	sc.placeHolder = sc.Dialecter.ParameterPlaceHolder()
 
 	switch exp.Node() {
@@ -1053,38 +2535,110 @@ func (sc *StmtCompiler) Compile(exp Expression, source string) (query string, ar
 		sc.visitInsert(exp)
 	case NodeDelete:
 		sc.visitDelete(exp)
+	case NodeDropTable:
+		sc.visitDropTable(exp)
+	case NodeTruncate:
+		sc.visitTruncate(exp)
+	case NodeWhere:
+		// fragment of where, without WHERE keyword
+		if where, _ := exp.(*Where); where != nil && !where.isEmpty() {
+			sc.visitConditions(where.Conditions)
+		}
 	default:
-		err = errors.New("doesn't support expression type:" + exp.Node().String())
+		return newCompileError(CompileUnsupported, exp.Node(), "", "doesn't support expression type:"+exp.Node().String())
 	}
 
-	if err != nil {
-		return
+	if sc.err != nil {
+		return sc.err
 	}
 
-	query = sc.w.String()
-	args = sc.args
+	// parameters bound before compiled sql count in the limit of composed statement
+	if max, n := dialectMaxParameters(sc.Dialecter), sc.ParamOffset+len(sc.args); max > 0 && n > max {
+		return newCompileError(CompileInvalid, exp.Node(), "", fmt.Sprintf("count of parameters %d exceeds max %d of %s", n, max, sc.Dialecter.Name()))
+	}
+	return nil
+}
 
-	return
+// ParameterNames return names of args of last compile without placeholder prefix, like pv1, names[i] is name of args[i].
+// return nil if dialect doesn't support named parameter
+func (sc *StmtCompiler) ParameterNames() []string {
+	return sc.names
+}
+
+// Reset clear state of last compile, buffer of sql writer is reused
+func (sc *StmtCompiler) Reset() {
+	if sc.w == nil {
+		sc.w = newSqlWriter(sc.capHint)
+	} else {
+		sc.w.Reset()
+		sc.w.depth = 0
+		sc.w.discard = false
+	}
+	// args returned by last compile may be still in use, so don't reuse it
+	sc.args = nil
+	sc.names = nil
+	sc.paraIndex = sc.ParamOffset
+	sc.paraNames = nil
+	sc.node = NodeZero
+	sc.source = ""
+	sc.exp = nil
+	sc.err = nil
+	sc.quoteColumn = false
+	sc.plan = nil
+	sc.planBroken = false
+}
+
+// setError keep the first error found while visiting expression
+func (sc *StmtCompiler) setError(code CompileErrorCode, node NodeType, message string) {
+	if sc.err == nil {
+		sc.err = newCompileError(code, node, "", message)
+	}
 }
 
 func (sc *StmtCompiler) writeQuote(s string) {
 	sc.w.WriteString(sc.Dialecter.Quote(s))
 }
 
+// writeIdentifier validate name by IdentifierRegexp, then quote each part of name like schema.table
+func (sc *StmtCompiler) writeIdentifier(name string) {
+	s, ok := quoteIdentifier(sc.Dialecter, name)
+	if !ok {
+		sc.setError(CompileInvalid, NodeTable, "invalid identifier:"+name)
+		return
+	}
+	sc.w.WriteString(s)
+}
+
+// quoteIdentifier quote each part of name, return false if name doesn't match IdentifierRegexp
+func quoteIdentifier(d Dialecter, name string) (string, bool) {
+	if IdentifierRegexp != nil && !IdentifierRegexp.MatchString(name) {
+		return "", false
+	}
+
+	parts := strings.Split(name, ansi.Split)
+	for i := 0; i < len(parts); i++ {
+		parts[i] = d.Quote(parts[i])
+	}
+	return strings.Join(parts, ansi.Split), true
+}
+
 func (sc *StmtCompiler) visitExp(exp Expression) {
 	if exp == nil {
 		return
 	}
+	sc.node = exp.Node()
 
 	switch exp.Node() {
 	case NodeZero:
 		return
-	case NodeText, NodeProcedure, NodeParameter, NodeOutput:
-		panic("doesn't support this expression type:" + exp.Node().String())
+	case NodeText, NodeProcedure, NodeOutput:
+		sc.setError(CompileUnsupported, exp.Node(), "doesn't support this expression type:"+exp.Node().String())
+		return
 	case NodeNull, NodeSql, NodeOperator:
 		sql, ok := exp.(RawSqler)
 		if !ok {
-			panic("should be a RawSqler:" + exp.Node().String())
+			sc.setError(CompileInvalid, exp.Node(), "should be a RawSqler:"+exp.Node().String())
+			return
 		}
 		sc.w.WriteString(sql.ToSql())
 		return
@@ -1094,27 +2648,48 @@ func (sc *StmtCompiler) visitExp(exp Expression) {
 	case *Insert:
 		sc.visitInsert(exp)
 	case *Query:
-		sc.visitQuery(exp)
+		// columns of subquery belong to its own tables, which are written as given
+		quoteColumn := sc.quoteColumn
+		sc.quoteColumn = false
+		sc.visitQueryBody(exp)
+		sc.quoteColumn = quoteColumn
 	case *Update:
 		sc.visitUpdate(exp)
 	case *Delete:
 		sc.visitDelete(exp)
 	case *Value:
 		sc.visitValue(exp)
+	case *ValuesList:
+		sc.visitValuesList(exp)
+	case *RawSql:
+		sc.visitRaw(exp)
+	case *Parameter:
+		sc.writeParameter(exp)
 	case *Table:
 		sc.visitTable(exp)
 	case *Column:
 		sc.visitColumn(*exp)
 	case Column:
 		sc.visitColumn(exp)
+	case *TableColumn:
+		sc.visitTableColumn(exp)
+	case AliasRef:
+		// quote alias as same as visitField
+		sc.writeQuote(string(exp))
 	// case *Alias:
 	// 	sc.visitAlias(exp)
 	case *Condition:
 		sc.visitCondition(exp)
 	// case *Set:
 	// 	sc.visitSet(exp)
+	case *Case:
+		sc.visitCase(exp)
 	case *Aggregate:
 		sc.visitAggregate(exp)
+	case *Window:
+		sc.visitWindow(exp)
+	case *Arith:
+		sc.visitArith(exp)
 	case *Select:
 		sc.visitSelect(exp)
 	case *From:
@@ -1129,19 +2704,227 @@ func (sc *StmtCompiler) visitExp(exp Expression) {
 		sc.visitHaving(exp)
 	case *OrderBy:
 		sc.visitOrderBy(exp)
-		// case *Func:
-		// 	sc.visitFunc(exp)
+	case *FuncCall:
+		sc.visitFuncCall(exp)
+	case *Cast:
+		sc.visitCast(exp)
+	case *Collate:
+		sc.visitExp(exp.Exp)
+		sc.writeCollate(exp.Collation)
 	}
 }
 
 func (sc *StmtCompiler) visitAggregate(a *Aggregate) {
-	if a == nil || a.Exp == nil || a.Name == "" {
+	if a == nil || a.Name == "" {
+		return
+	}
+
+	if !isAggregate(a.Name) {
+		sc.setError(CompileUnsupported, NodeAggregate, "unknown aggregate:"+a.Name.String())
+		return
+	}
+
+	exp := a.Exp
+	if exp == nil {
+		if a.Name != Count {
+			sc.setError(CompileInvalid, NodeAggregate, "aggregate expression is nil:"+a.Name.String())
+			return
+		}
+		exp = Wildcard
+	} else if exp == Wildcard && a.Name != Count {
+		sc.setError(CompileInvalid, NodeAggregate, "only count support *:"+a.Name.String())
+		return
+	}
+
+	if a.Filter.isEmpty() {
+		sc.w.WriteString(a.Name.String())
+		sc.w.OpenParentheses()
+		sc.visitExp(exp)
+		sc.w.CloseParentheses()
+		return
+	}
+
+	// postgres & sqlite write FILTER (WHERE ...), others write name(CASE WHEN ... THEN exp END)
+	switch sc.Dialecter.Name() {
+	case "postgres", "sqlite":
+		sc.w.WriteString(a.Name.String())
+		sc.w.OpenParentheses()
+		sc.visitExp(exp)
+		sc.w.CloseParentheses()
+		sc.w.Print(" ", ansi.Filter, " ")
+		sc.w.OpenParentheses()
+		sc.w.Print(ansi.Where, " ")
+		sc.visitConditions(a.Filter)
+		sc.w.CloseParentheses()
+	default:
+		sc.w.WriteString(a.Name.String())
+		sc.w.OpenParentheses()
+		sc.w.Print(ansi.Case, " ", ansi.When, " ")
+		sc.visitConditions(a.Filter)
+		sc.w.Print(" ", ansi.Then, " ")
+		if exp == Wildcard {
+			sc.w.WriteString("1")
+		} else {
+			sc.visitExp(exp)
+		}
+		sc.w.Print(" ", ansi.End)
+		sc.w.CloseParentheses()
+	}
+}
+
+func (sc *StmtCompiler) visitCase(c *Case) {
+	if c == nil {
+		return
+	}
+	if len(c.Whens) == 0 {
+		sc.setError(CompileInvalid, NodeCase, "case has no when")
+		return
+	}
+
+	sc.w.WriteString(ansi.Case)
+	for _, w := range c.Whens {
+		if w == nil || w.Conditions.isEmpty() {
+			sc.setError(CompileInvalid, NodeCase, "when conditions of case is empty")
+			return
+		}
+		sc.w.Print(" ", ansi.When, " ")
+		sc.visitConditions(w.Conditions)
+		sc.w.Print(" ", ansi.Then, " ")
+		sc.visitExp(w.Then)
+	}
+	if c.Else != nil {
+		sc.w.Print(" ", ansi.Else, " ")
+		sc.visitExp(c.Else)
+	}
+	sc.w.Print(" ", ansi.End)
+}
+
+// visitFuncCall write name(arg1, arg2), name should be a valid function name
+func (sc *StmtCompiler) visitFuncCall(fc *FuncCall) {
+	if fc == nil {
+		return
+	}
+
+	if !simpleNameRegexp.MatchString(fc.Name.String()) {
+		sc.setError(CompileInvalid, NodeFunc, "invalid function name:"+fc.Name.String())
+		return
+	}
+
+	sc.w.WriteString(fc.Name.String())
+	sc.w.OpenParentheses()
+	for i := 0; i < len(fc.Args); i++ {
+		if i > 0 {
+			sc.w.Comma()
+		}
+		sc.visitExp(fc.Args[i])
+	}
+	sc.w.CloseParentheses()
+}
+
+// visitCast write CAST(exp AS type) or exp::type, type is native type of dialect
+func (sc *StmtCompiler) visitCast(c *Cast) {
+	if c == nil {
+		return
+	}
+
+	t := dialectCastType(sc.Dialecter, c.Type, c.Length, c.Precision, c.Scale)
+	if t == "" {
+		sc.setError(CompileUnsupported, NodeCast, "doesn't support cast to:"+c.Type.String())
+		return
+	}
+
+	if c.Shorthand && dialectSupportCastShorthand(sc.Dialecter) {
+		// :: binds tighter than arithmetic operators
+		if _, ok := c.Exp.(*Arith); ok {
+			sc.w.OpenParentheses()
+			sc.visitExp(c.Exp)
+			sc.w.CloseParentheses()
+		} else {
+			sc.visitExp(c.Exp)
+		}
+		sc.w.Print("::", t)
+		return
+	}
+
+	sc.w.WriteString(ansi.Cast)
+	sc.w.OpenParentheses()
+	sc.visitExp(c.Exp)
+	sc.w.Print(" ", ansi.As, " ", t)
+	sc.w.CloseParentheses()
+}
+
+// writeCollate write COLLATE collation, postgres quote collation as identifier
+func (sc *StmtCompiler) writeCollate(collation string) {
+	if !collationRegexp.MatchString(collation) {
+		sc.setError(CompileInvalid, NodeCollate, "invalid collation:"+collation)
+		return
+	}
+
+	sc.w.Print(" ", ansi.Collate, " ")
+	if sc.Dialecter.Name() == "postgres" {
+		sc.writeQuote(collation)
+	} else {
+		sc.w.WriteString(collation)
+	}
+}
+
+// mysqlCastType return target type of mysql CAST, it doesn't accept types like INT, VARCHAR
+func mysqlCastType(t ansi.DbType, length, precision, scale int) string {
+	switch t {
+	case ansi.Int, ansi.Boolean:
+		return "SIGNED"
+	case ansi.Uint:
+		return "UNSIGNED"
+	case ansi.String:
+		return nativeTypeLength("CHAR", length, "CHAR")
+	case ansi.Guid:
+		return "CHAR(36)"
+	case ansi.Bytes:
+		return nativeTypeLength("BINARY", length, "BINARY")
+	case ansi.Date:
+		return "DATE"
+	case ansi.DateTime:
+		return "DATETIME"
+	case ansi.Numeric:
+		return nativeTypePrecision("DECIMAL", precision, scale)
+	case ansi.Float:
+		return "DOUBLE"
+	case ansi.Json:
+		return "JSON"
+	}
+	return ""
+}
+
+func (sc *StmtCompiler) visitWindow(w *Window) {
+	if w == nil || w.Name == "" {
 		return
 	}
 
-	sc.w.WriteString(a.Name.String())
+	sc.w.WriteString(w.Name.String())
+	sc.w.OpenParentheses()
+	sc.visitExp(w.Exp)
+	sc.w.CloseParentheses()
+
+	sc.w.Print(" ", ansi.Over, " ")
 	sc.w.OpenParentheses()
-	sc.visitExp(a.Exp)
+	split := false
+	if len(w.Partitions) > 0 {
+		sc.w.Print(ansi.PartitionBy, " ")
+		for i := 0; i < len(w.Partitions); i++ {
+			if i > 0 {
+				sc.w.Comma()
+			}
+			sc.visitExp(w.Partitions[i])
+		}
+		split = true
+	}
+	if w.OrderBy != nil && !w.OrderBy.isEmpty() {
+		if split {
+			sc.w.Blank()
+		}
+		sc.w.Print(ansi.OrderBy, " ")
+		sc.visitOrderByFields(w.OrderBy.Fields)
+	}
 	sc.w.CloseParentheses()
 }
 
@@ -1155,62 +2938,366 @@ func (sc *StmtCompiler) writeValue(v interface{}) {
 		sc.args = make([]interface{}, 0, _defaultCapicity)
 	}
 
-	mode := 0
-	switch {
-	case sc.Dialecter.SupportNamedParameter():
-		mode = 1
-	case sc.Dialecter.SupportIndexedParameter():
-		mode = 2
-	}
-
 	p := sc.placeHolder
-	switch mode {
+	switch sc.parameterMode() {
 	case 0:
 		sc.w.WriteString(p)
 	case 1:
 		sc.paraIndex++
-		sc.w.WriteString(p + "pv" + strconv.Itoa(sc.paraIndex))
+		name := "pv" + strconv.Itoa(sc.paraIndex)
+		sc.w.WriteString(p + name)
+		sc.names = append(sc.names, name)
 	case 2:
 		sc.paraIndex++
 		sc.w.WriteString(p + strconv.Itoa(sc.paraIndex))
 	}
-	sc.args = append(sc.args, v)
+	sc.args = append(sc.args, v)
+
+}
+
+// parameterMode return 1 if dialect support named parameter, 2 if support indexed parameter, else 0
+func (sc *StmtCompiler) parameterMode() int {
+	switch {
+	case sc.Dialecter.SupportNamedParameter():
+		return 1
+	case sc.Dialecter.SupportIndexedParameter():
+		return 2
+	}
+	return 0
+}
+
+// writeParameter write placeholder of named parameter, value of a name is collected once if dialect support named or indexed parameter
+func (sc *StmtCompiler) writeParameter(p *Parameter) {
+	if p == nil {
+		sc.w.WriteString(ansi.Null)
+		return
+	}
+
+	if !p.IsIn() {
+		sc.setError(CompileUnsupported, NodeParameter, "output or return parameter can not be embedded in expression:"+p.Name)
+		return
+	}
+
+	mode := sc.parameterMode()
+	if p.Name == "" || mode == 0 {
+		sc.writeValue(p.Value)
+		sc.planArg(p, "Value", -1, p.Value)
+		return
+	}
+
+	if !simpleNameRegexp.MatchString(p.Name) {
+		sc.setError(CompileInvalid, NodeParameter, "invalid parameter name:"+p.Name)
+		return
+	}
+	if mode == 1 && generatedNameRegexp.MatchString(p.Name) {
+		sc.setError(CompileInvalid, NodeParameter, "parameter name is reserved for generated parameter:"+p.Name)
+		return
+	}
+
+	index, ok := sc.paraNames[p.Name]
+	if !ok {
+		if sc.paraNames == nil {
+			sc.paraNames = make(map[string]int)
+		}
+		if sc.args == nil {
+			sc.args = make([]interface{}, 0, _defaultCapicity)
+		}
+		sc.paraIndex++
+		index = sc.paraIndex
+		sc.paraNames[p.Name] = index
+		sc.args = append(sc.args, p.Value)
+		sc.planParameter(p)
+		if mode == 1 {
+			sc.names = append(sc.names, p.Name)
+		}
+	}
+
+	if mode == 1 {
+		sc.w.WriteString(sc.placeHolder + p.Name)
+	} else {
+		sc.w.WriteString(sc.placeHolder + strconv.Itoa(index))
+	}
+}
+
+func (sc *StmtCompiler) visitArith(a *Arith) {
+	if a == nil {
+		return
+	}
+
+	switch a.Op {
+	case Plus, Minus, Multiply, Divide, Modulo:
+	default:
+		sc.setError(CompileUnsupported, NodeArith, "doesn't support arithmetic operator:"+a.Op.String())
+		return
+	}
+
+	sc.visitArithOperand(a.Left, a.Op.precedence())
+	sc.w.Print(" ", a.Op.String(), " ")
+	// right operand of same precedence need parentheses, a - (b - c)
+	sc.visitArithOperand(a.Right, a.Op.precedence()+1)
+}
+
+// visitArithOperand wrap operand with parentheses if it binds looser than min
+func (sc *StmtCompiler) visitArithOperand(exp Expression, min int) {
+	if inner, ok := exp.(*Arith); ok && inner != nil && inner.Op.precedence() < min {
+		sc.w.OpenParentheses()
+		sc.visitArith(inner)
+		sc.w.CloseParentheses()
+		return
+	}
+	sc.visitExp(exp)
+}
+
+func (sc *StmtCompiler) visitValuesList(vl *ValuesList) {
+	if vl == nil || len(vl.Rows) == 0 {
+		sc.setError(CompileInvalid, NodeValuesList, "values list is empty")
+		return
+	}
+
+	l := len(vl.Rows[0])
+	sc.w.Print(ansi.Values, " ")
+	for i := 0; i < len(vl.Rows); i++ {
+		row := vl.Rows[i]
+		if len(row) == 0 || len(row) != l {
+			sc.setError(CompileInvalid, NodeValuesList, "rows of values list should have same count of values")
+			return
+		}
+
+		if i > 0 {
+			sc.w.Comma()
+		}
+		sc.w.OpenParentheses()
+		for j := 0; j < len(row); j++ {
+			if j > 0 {
+				sc.w.Comma()
+			}
+			sc.visitExp(row[j])
+		}
+		sc.w.CloseParentheses()
+	}
+}
+
+func (sc *StmtCompiler) visitValue(v *Value) {
+	if v == nil || v.Value == nil {
+		sc.noPlan()
+		sc.w.WriteString(ansi.Null)
+		return
+	}
+	sc.writeValue(v.Value)
+	sc.planArg(v, "Value", -1, v.Value)
+}
+
+// visitRaw write r.Sql, replace ? outside quoted string with placeholder of r.Args in order
+func (sc *StmtCompiler) visitRaw(r *RawSql) {
+	if r == nil {
+		return
+	}
+
+	n := 0
+	var quote rune
+	for _, ch := range r.Sql {
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '?':
+			if n >= len(r.Args) {
+				sc.setError(CompileMissingParameter, NodeRaw, "raw sql doesn't have enough arguments:"+r.Sql)
+				return
+			}
+			sc.writeValue(r.Args[n])
+			sc.planArg(r, "Args", n, r.Args[n])
+			n++
+			continue
+		}
+		sc.w.WriteRune(ch)
+	}
+
+	if n != len(r.Args) {
+		sc.setError(CompileInvalid, NodeRaw, "raw sql has too many arguments:"+r.Sql)
+	}
+}
+
+func (sc *StmtCompiler) visitColumn(c Column) {
+	if sc.quoteColumn {
+		sc.writeIdentifier(string(c))
+		return
+	}
+	sc.w.WriteString(c.String())
+
+	// table, column := c.Split()
+	// if table == "" {
+	// 	sc.WriteQuote(column)
+	// } else {
+	// 	sc.WriteQuote(table)
+	// 	sc.w.WriteString(".")
+	// 	sc.WriteQuote(column)
+	// }
+
+}
+// visitTableColumn write quoted table.column, * is not quoted
+func (sc *StmtCompiler) visitTableColumn(c *TableColumn) {
+	if c == nil {
+		return
+	}
+	if c.Table != "" {
+		sc.writeIdentifier(c.Table)
+		sc.w.WriteString(ansi.Split)
+	}
+	if c.Name == ansi.WildcardAll {
+		sc.w.WriteString(c.Name)
+		return
+	}
+	sc.writeIdentifier(c.Name)
+}
+
+// writeTableName write name of query table as given, like table or schema.table,
+// schema and catalog should be plain identifiers because they aren't quoted either
+func (sc *StmtCompiler) writeTableName(t *Table) {
+	if !t.isQualified() {
+		sc.w.WriteString(t.Name)
+		return
+	}
+	if t.Catalog != "" && sc.Dialecter.Name() == "mysql" {
+		sc.setError(CompileUnsupported, NodeTable, "mysql doesn't support catalog of table, use schema as database:"+t.Name)
+		return
+	}
+	for _, part := range []string{t.Catalog, t.Schema} {
+		if part != "" && !plainIdentifierRegexp.MatchString(part) {
+			sc.setError(CompileInvalid, NodeTable, "invalid schema or catalog of table:"+part)
+			return
+		}
+	}
+	sc.w.WriteString(t.qualifiedName())
+}
+
+// writeTableIdentifier write quoted catalog.schema.name of t
+func (sc *StmtCompiler) writeTableIdentifier(t *Table) {
+	if t.Catalog != "" && sc.Dialecter.Name() == "mysql" {
+		sc.setError(CompileUnsupported, NodeTable, "mysql doesn't support catalog of table, use schema as database:"+t.Name)
+		return
+	}
+	sc.writeIdentifier(t.qualifiedName())
+}
+
+func (sc *StmtCompiler) visitTable(t *Table) {
+	if t == nil {
+		return
+	} else if t.Source != nil {
+		if t.Alias == "" {
+			sc.setError(CompileInvalid, NodeTable, "derived table should have alias")
+			return
+		}
+		sc.w.OpenParentheses()
+		sc.visitExp(t.Source)
+		sc.w.CloseParentheses()
+		sc.w.Print(" ", ansi.As, " ", t.Alias)
+	} else if t.Name == "" && t.Alias == "" {
+		return
+	} else if t.Name != "" && t.Alias != "" {
+		sc.writeTableName(t)
+		sc.w.Print(" ", ansi.As, " ", t.Alias)
+	} else if t.Alias == "" {
+		sc.writeTableName(t)
+	} else if t.Name == "" {
+		sc.w.WriteString(t.Alias)
+	}
 
-}
+	if len(t.Columns) > 0 {
+		if t.Alias == "" {
+			sc.setError(CompileInvalid, NodeTable, "table with column alias list should have alias:"+t.Name)
+			return
+		}
+		sc.w.Blank()
+		sc.w.OpenParentheses()
+		sc.w.PrintSplit(", ", t.Columns...)
+		sc.w.CloseParentheses()
+	}
 
-func (sc *StmtCompiler) visitValue(v *Value) {
-	if v == nil || v.Value == nil {
-		sc.w.WriteString(ansi.Null)
-		return
+	if len(t.Hints) > 0 {
+		sc.visitIndexHints(t)
 	}
-	sc.writeValue(v.Value)
+	if t.Sample != nil {
+		sc.visitTableSample(t)
+	}
+	return
 }
 
-func (sc *StmtCompiler) visitColumn(c Column) {
-	sc.w.WriteString(c.String())
+// visitIndexHints write USE INDEX (idx), only mysql support index hint
+func (sc *StmtCompiler) visitIndexHints(t *Table) {
+	if sc.Dialecter.Name() != "mysql" {
+		sc.setError(CompileUnsupported, NodeTable, sc.Dialecter.Name()+" doesn't support index hint")
+		return
+	}
+	if t.Source != nil {
+		sc.setError(CompileInvalid, NodeTable, "derived table doesn't support index hint:"+t.Alias)
+		return
+	}
 
-	// table, column := c.Split()
-	// if table == "" {
-	// 	sc.WriteQuote(column)
-	// } else {
-	// 	sc.WriteQuote(table)
-	// 	sc.w.WriteString(".")
-	// 	sc.WriteQuote(column)
-	// }
+	for _, hint := range t.Hints {
+		if hint == nil {
+			continue
+		}
+		if !hint.Type.IsValid() {
+			sc.setError(CompileInvalid, NodeTable, "invalid index hint:"+hint.Type.String())
+			return
+		}
+		if len(hint.Indexes) == 0 {
+			sc.setError(CompileInvalid, NodeTable, "index hint doesn't have any index:"+hint.Type.String())
+			return
+		}
 
+		sc.w.Print(" ", hint.Type.String(), " ")
+		sc.w.OpenParentheses()
+		for i, index := range hint.Indexes {
+			if i > 0 {
+				sc.w.Comma()
+			}
+			sc.writeIdentifier(index)
+		}
+		sc.w.CloseParentheses()
+	}
 }
-func (sc *StmtCompiler) visitTable(t *Table) {
-	if t == nil || (t.Name == "" && t.Alias == "") {
+
+// visitTableSample write TABLESAMPLE method (percent) of postgres, mssql write TABLESAMPLE SYSTEM (percent PERCENT)
+func (sc *StmtCompiler) visitTableSample(t *Table) {
+	sample := t.Sample
+	if t.Source != nil {
+		sc.setError(CompileInvalid, NodeTable, "derived table doesn't support table sample:"+t.Alias)
+		return
+	}
+	if sample.Percent <= 0 || sample.Percent > 100 {
+		sc.setError(CompileInvalid, NodeTable, fmt.Sprint("table sample percent should be in (0, 100]:", sample.Percent))
 		return
-	} else if t.Name != "" && t.Alias != "" {
-		sc.w.Print(t.Name, " ", ansi.As, " ", t.Alias)
-	} else if t.Alias == "" {
-		sc.w.WriteString(t.Name)
-	} else if t.Name == "" {
-		sc.w.WriteString(t.Alias)
 	}
 
-	return
+	method := strings.ToUpper(sample.Method)
+	percent := strconv.FormatFloat(sample.Percent, 'f', -1, 64)
+	sc.planGuard(sample, "Percent", sample.Percent)
+	switch sc.Dialecter.Name() {
+	case "postgres":
+		if method != ansi.System && method != ansi.Bernoulli {
+			sc.setError(CompileInvalid, NodeTable, "invalid table sample method:"+sample.Method)
+			return
+		}
+	case "mssql":
+		if method != ansi.System {
+			sc.setError(CompileInvalid, NodeTable, "invalid table sample method:"+sample.Method)
+			return
+		}
+		percent += " " + ansi.Percent
+	default:
+		sc.setError(CompileUnsupported, NodeTable, sc.Dialecter.Name()+" doesn't support table sample")
+		return
+	}
+
+	sc.w.Print(" ", ansi.TableSample, " ", method, " ")
+	sc.w.OpenParentheses()
+	sc.w.WriteString(percent)
+	sc.w.CloseParentheses()
 }
 
 func (sc *StmtCompiler) visitCondition(c *Condition) {
@@ -1218,7 +3305,11 @@ func (sc *StmtCompiler) visitCondition(c *Condition) {
 		return
 	}
 
-	if c.Right == nil && c.Left == nil {
+	if c.Op == Exists || c.Op == NotExists {
+		sc.visitExists(c)
+	} else if c.Op == IsNull || c.Op == IsNotNull {
+		sc.visitIsNull(c)
+	} else if c.Right == nil && c.Left == nil {
 		sc.w.WriteString(c.Op.String())
 	} else if c.Left == nil {
 		sc.w.Print(c.Op.String(), "(")
@@ -1230,6 +3321,8 @@ func (sc *StmtCompiler) visitCondition(c *Condition) {
 	} else {
 		if c.Op == In || c.Op == NotIn {
 			sc.visitIn(c)
+		} else if c.Op == NullSafeEqual {
+			sc.visitNullSafeEqual(c)
 		} else {
 			sc.visitExp(c.Left)
 			sc.w.Print(" ", c.Op.String(), " ")
@@ -1238,7 +3331,85 @@ func (sc *StmtCompiler) visitCondition(c *Condition) {
 	}
 }
 
+// visitNullSafeEqual write a <=> b of mysql, a IS b of sqlite, a IS NOT DISTINCT FROM b of others
+func (sc *StmtCompiler) visitNullSafeEqual(c *Condition) {
+	op := dialectNullSafeEqualSql(sc.Dialecter)
+	if op == "" {
+		sc.setError(CompileUnsupported, NodeCondition, sc.Dialecter.Name()+" doesn't support null-safe equality")
+		return
+	}
+
+	sc.visitExp(c.Left)
+	sc.w.Print(" ", op, " ")
+	sc.visitExp(c.Right)
+}
+
+func (sc *StmtCompiler) visitExists(c *Condition) {
+	if c.Left != nil {
+		sc.setError(CompileInvalid, NodeCondition, c.Op.String()+" can not have left operand")
+		return
+	}
+
+	switch c.Right.(type) {
+	case *Query, Sql:
+	default:
+		sc.setError(CompileInvalid, NodeCondition, c.Op.String()+" should have a subquery")
+		return
+	}
+
+	sc.w.Print(c.Op.String(), " ")
+	sc.w.OpenParentheses()
+	sc.visitExp(c.Right)
+	sc.w.CloseParentheses()
+}
+
+func (sc *StmtCompiler) visitIsNull(c *Condition) {
+	if c.Left == nil || c.Right != nil {
+		sc.setError(CompileInvalid, NodeCondition, c.Op.String()+" should have left operand only")
+		return
+	}
+
+	sc.visitExp(c.Left)
+	sc.w.Print(" ", c.Op.String())
+}
+
 func (sc *StmtCompiler) visitIn(c *Condition) {
+	// IN list is written by its length and elements
+	if _, ok := c.Right.(*Value); ok {
+		sc.noPlan()
+	}
+
+	if v, ok := c.Right.(*Value); ok && v != nil {
+		if values, hasNil := removeNil(v.Value); hasNil {
+			sc.visitInNull(c, values)
+			return
+		}
+	}
+
+	// IN () is invalid, empty IN list never match and empty NOT IN list always match
+	if v, ok := c.Right.(*Value); ok && v != nil && isEmptySlice(v.Value) {
+		if c.Op == NotIn {
+			sc.w.WriteString("1=1")
+		} else {
+			sc.w.WriteString("1=0")
+		}
+		return
+	}
+
+	if v, ok := c.Right.(*Value); ok && sc.ArrayIn && dialectSupportArrayParameter(sc.Dialecter) && v != nil {
+		if array, ok := asSlice(v.Value); ok {
+			sc.visitInArray(c, array)
+			return
+		}
+	}
+
+	if v, ok := c.Right.(*Value); ok && sc.MaxInItems > 0 && v != nil && v.Value != nil {
+		if chunks := splitSlice(v.Value, sc.MaxInItems); len(chunks) > 1 {
+			sc.visitInChunks(c, chunks)
+			return
+		}
+	}
+
 	sc.visitExp(c.Left)
 	sc.w.Print(" ", c.Op.String(), " ")
 
@@ -1255,6 +3426,175 @@ func (sc *StmtCompiler) visitIn(c *Condition) {
 	sc.w.CloseParentheses()
 }
 
+// visitInNull write IN list without nil elements; if InNull is InNullMatch, it writes (a IN (...) OR a IS NULL),
+// or (a NOT IN (...) AND a IS NOT NULL)
+func (sc *StmtCompiler) visitInNull(c *Condition, values []interface{}) {
+	in := &Condition{Left: c.Left, Op: c.Op, Right: &Value{Value: values}}
+	if sc.InNull != InNullMatch {
+		sc.visitIn(in)
+		return
+	}
+
+	logic, null := ansi.Or, ansi.IsNull
+	if c.Op == NotIn {
+		logic, null = ansi.And, ansi.IsNotNull
+	}
+
+	if len(values) == 0 {
+		sc.visitExp(c.Left)
+		sc.w.Print(" ", null)
+		return
+	}
+
+	sc.w.OpenParentheses()
+	sc.visitIn(in)
+	sc.w.Print(" ", logic, " ")
+	sc.visitExp(c.Left)
+	sc.w.Print(" ", null)
+	sc.w.CloseParentheses()
+}
+
+// visitInChunks write (a IN (...) OR a IN (...)), or (a NOT IN (...) AND a NOT IN (...))
+func (sc *StmtCompiler) visitInChunks(c *Condition, chunks []interface{}) {
+	logic := ansi.Or
+	if c.Op == NotIn {
+		logic = ansi.And
+	}
+
+	sc.w.OpenParentheses()
+	for i := 0; i < len(chunks); i++ {
+		if i > 0 {
+			sc.w.Print(" ", logic, " ")
+		}
+		sc.visitExp(c.Left)
+		sc.w.Print(" ", c.Op.String(), " ")
+		sc.w.OpenParentheses()
+		sc.visitSlice(chunks[i])
+		sc.w.CloseParentheses()
+	}
+	sc.w.CloseParentheses()
+}
+
+// visitInArray write a = ANY(?) or a <> ALL(?), array is a single parameter
+func (sc *StmtCompiler) visitInArray(c *Condition, array interface{}) {
+	sc.visitExp(c.Left)
+	if c.Op == NotIn {
+		sc.w.Print(" ", ansi.NotEquals, " ", ansi.All)
+	} else {
+		sc.w.Print(" ", ansi.Equals, " ", ansi.Any)
+	}
+	sc.w.OpenParentheses()
+	sc.writeValue(arrayValue{array})
+	sc.w.CloseParentheses()
+}
+
+// removeNil return elements of slice or array v without nil elements, nil pointer is treated as nil.
+// it returns (nil, false) if v isn't slice or array of interface or pointer, hasNil is true if any element is removed
+func removeNil(v interface{}) (values []interface{}, hasNil bool) {
+	if v == nil {
+		return nil, false
+	}
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	if k := rv.Type().Elem().Kind(); k != reflect.Interface && k != reflect.Ptr {
+		return nil, false
+	}
+
+	l := rv.Len()
+	values = make([]interface{}, 0, l)
+	for i := 0; i < l; i++ {
+		item := rv.Index(i)
+		if item.IsNil() || (item.Kind() == reflect.Interface && isNilPointer(item.Elem())) {
+			hasNil = true
+			continue
+		}
+		values = append(values, item.Interface())
+	}
+	return values, hasNil
+}
+
+// isNilPointer return true if rv is nil pointer
+func isNilPointer(rv reflect.Value) bool {
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// isEmptySlice return true if v (or the value v points to) is slice, array or map without any element,
+// nil and other kinds are not empty slice
+func isEmptySlice(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len() == 0
+	}
+	return false
+}
+
+// asSlice return v as slice, array is copied to slice, keys of map are returned as sorted slice
+func asSlice(v interface{}) (interface{}, bool) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Slice:
+		return rv.Interface(), true
+	case reflect.Array:
+		s := reflect.MakeSlice(reflect.SliceOf(rv.Type().Elem()), rv.Len(), rv.Len())
+		reflect.Copy(s, rv)
+		return s.Interface(), true
+	case reflect.Map:
+		return sortedMapKeys(rv).Interface(), true
+	}
+	return nil, false
+}
+
+// sortedMapKeys return keys of map as slice, sorted for determinism
+func sortedMapKeys(rv reflect.Value) reflect.Value {
+	keys := rv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		switch a.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return a.Int() < b.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return a.Uint() < b.Uint()
+		case reflect.Float32, reflect.Float64:
+			return a.Float() < b.Float()
+		case reflect.String:
+			return a.String() < b.String()
+		}
+		return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+	})
+
+	s := reflect.MakeSlice(reflect.SliceOf(rv.Type().Key()), len(keys), len(keys))
+	for i, key := range keys {
+		s.Index(i).Set(key)
+	}
+	return s
+}
+
+// splitSlice split slice or array v into slices of size items, element type is kept; return nil if v isn't slice or array
+func splitSlice(v interface{}, size int) []interface{} {
+	s, ok := asSlice(v)
+	if !ok {
+		return nil
+	}
+
+	rv := reflect.ValueOf(s)
+	l := rv.Len()
+	chunks := make([]interface{}, 0, (l+size-1)/size)
+	for i := 0; i < l; i += size {
+		j := i + size
+		if j > l {
+			j = l
+		}
+		chunks = append(chunks, rv.Slice(i, j).Interface())
+	}
+	return chunks
+}
+
 func (sc *StmtCompiler) visitSlice(v interface{}) {
 	switch v := v.(type) {
 	case []int:
@@ -1271,6 +3611,20 @@ func (sc *StmtCompiler) visitSlice(v interface{}) {
 			}
 			sc.w.WriteString(strconv.FormatInt(v[i], 10))
 		}
+	case []int32:
+		for i := 0; i < len(v); i++ {
+			if i > 0 {
+				sc.w.Comma()
+			}
+			sc.w.WriteString(strconv.FormatInt(int64(v[i]), 10))
+		}
+	case []uint64:
+		for i := 0; i < len(v); i++ {
+			if i > 0 {
+				sc.w.Comma()
+			}
+			sc.w.WriteString(strconv.FormatUint(v[i], 10))
+		}
 	case []float32:
 		for i := 0; i < len(v); i++ {
 			if i > 0 {
@@ -1293,6 +3647,20 @@ func (sc *StmtCompiler) visitSlice(v interface{}) {
 			}
 			sc.writeValue(v[i])
 		}
+	case []bool:
+		for i := 0; i < len(v); i++ {
+			if i > 0 {
+				sc.w.Comma()
+			}
+			sc.writeValue(v[i])
+		}
+	case []time.Time:
+		for i := 0; i < len(v); i++ {
+			if i > 0 {
+				sc.w.Comma()
+			}
+			sc.writeValue(v[i])
+		}
 	case []interface{}:
 		for i := 0; i < len(v); i++ {
 			if i > 0 {
@@ -1303,7 +3671,10 @@ func (sc *StmtCompiler) visitSlice(v interface{}) {
 		}
 	default:
 		rv := reflect.Indirect(reflect.ValueOf(v))
-		if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		if rv.Kind() == reflect.Map {
+			// keys of map is a set, like map[int]struct{}
+			sc.visitSlice(sortedMapKeys(rv).Interface())
+		} else if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
 			for i := 0; i < rv.Len(); i++ {
 				if i > 0 {
 					sc.w.Comma()
@@ -1323,7 +3694,41 @@ func (sc *StmtCompiler) visitConditions(c *Conditions) {
 		return
 	}
 
+	if !c.isBalanced() {
+		sc.setError(CompileInvalid, NodeCondition, "parentheses of conditions are unbalanced:"+c.String())
+		return
+	}
+
 	deep := 0
+	written := false
+	write := func(item Expression) {
+		if item == CloseParentheses && deep > 0 {
+			deep--
+		}
+
+		// '(' stays on the line of previous item
+		if item == OpenParentheses {
+			if written {
+				sc.w.Blank()
+			}
+		} else {
+			if written {
+				sc.w.LineBreak()
+			}
+			if deep > 0 {
+				sc.w.WriteString(strings.Repeat("\t", deep))
+			}
+		}
+
+		sc.visitExp(item)
+		written = true
+		if item == OpenParentheses {
+			deep++
+		}
+	}
+
+	// operand is true if last item is a condition or ')', then next item should be AND, OR or ')'
+	operand := false
 	l := len(c.Conditions)
 
 	for i := 0; i < l; i++ {
@@ -1332,22 +3737,31 @@ func (sc *StmtCompiler) visitConditions(c *Conditions) {
 			continue
 		}
 
-		if i > 0 {
-			sc.w.LineBreak()
-		}
-
-		if item == CloseParentheses {
-			deep--
-		}
-
-		if deep > 0 {
-			sc.w.WriteString(strings.Repeat("\t", deep))
+		switch item {
+		case And, Or:
+			if !operand {
+				sc.setError(CompileInvalid, NodeCondition, "logic operator should follow a condition:"+c.String())
+				return
+			}
+			operand = false
+		case CloseParentheses:
+			if !operand {
+				sc.setError(CompileInvalid, NodeCondition, "parentheses should enclose a condition:"+c.String())
+				return
+			}
+		default:
+			// conditions without logic operator between them are joined by AND
+			if operand {
+				write(And)
+			}
+			operand = item != OpenParentheses
 		}
+		write(item)
+	}
 
-		sc.visitExp(item)
-		if item == OpenParentheses {
-			deep++
-		}
+	if written && !operand {
+		sc.setError(CompileInvalid, NodeCondition, "conditions should end with a condition:"+c.String())
+		return
 	}
 	sc.w.Blank()
 }
@@ -1357,24 +3771,76 @@ func (sc *StmtCompiler) visitJoin(j *Join) {
 		return
 	}
 
+	if !j.JoinType.IsValid() {
+		sc.setError(CompileUnsupported, NodeJoin, "doesn't support join type:"+j.JoinType.String())
+		return
+	}
+
 	sc.w.WriteString(j.JoinType.String())
 	sc.w.Blank()
+	if j.Lateral {
+		if !dialectSupportLateral(sc.Dialecter) {
+			sc.setError(CompileUnsupported, NodeJoin, sc.Dialecter.Name()+" doesn't support lateral join")
+			return
+		}
+		if j.Right == nil || j.Right.Source == nil {
+			sc.setError(CompileInvalid, NodeJoin, "lateral join need a subquery:"+j.Right.String())
+			return
+		}
+		sc.w.WriteString(ansi.Lateral)
+		sc.w.Blank()
+	}
 	sc.visitTable(j.Right)
 	sc.w.Blank()
 
-	if !j.Conditions.isEmpty() {
-		sc.w.WriteString(ansi.On)
-		for i := 0; i < len(j.Conditions.Conditions); i++ {
-			sc.w.Blank()
-			sc.visitExp(j.Conditions.Conditions[i])
-			sc.w.Blank()
+	if j.JoinType == CrossJoin {
+		if !j.Conditions.isEmpty() || len(j.Using) > 0 {
+			sc.setError(CompileInvalid, NodeJoin, "cross join can not have conditions:"+j.Right.String())
+		}
+		return
+	}
+
+	if len(j.Using) > 0 {
+		if !j.Conditions.isEmpty() {
+			sc.setError(CompileInvalid, NodeJoin, "join can not have both on and using:"+j.Right.String())
+			return
+		}
+
+		sc.w.WriteString(ansi.Using)
+		sc.w.Blank()
+		sc.w.OpenParentheses()
+		for i := 0; i < len(j.Using); i++ {
+			if i > 0 {
+				sc.w.Comma()
+			}
+			sc.writeQuote(j.Using[i])
+		}
+		sc.w.CloseParentheses()
+		sc.w.Blank()
+		return
+	}
+
+	if j.Conditions.isEmpty() {
+		// lateral subquery usually correlates in its where, so ON TRUE is enough
+		if j.Lateral {
+			sc.w.Print(ansi.On, ansi.Blank, ansi.True, ansi.Blank)
+			return
 		}
+		sc.setError(CompileInvalid, NodeJoin, "join conditions is empty:"+j.Right.String())
+		return
+	}
+
+	sc.w.WriteString(ansi.On)
+	for i := 0; i < len(j.Conditions.Conditions); i++ {
+		sc.w.Blank()
+		sc.visitExp(j.Conditions.Conditions[i])
+		sc.w.Blank()
 	}
 
 }
 
 func (sc *StmtCompiler) visitFrom(f *From) {
-	if f == nil {
+	if f.isEmpty() {
 		return
 	}
 
@@ -1398,7 +3864,19 @@ func (sc *StmtCompiler) visitFrom(f *From) {
 		sc.w.LineBreak()
 		sc.visitJoin(f.Joins[i])
 	}
-	sc.w.Blank()
+	sc.w.Blank()
+}
+
+// visitQueryFrom write from of query, write dual table of dialect if query has no table
+func (sc *StmtCompiler) visitQueryFrom(f *From) {
+	if !f.isEmpty() {
+		sc.visitFrom(f)
+		return
+	}
+
+	if dual := dialectDualTable(sc.Dialecter); dual != "" {
+		sc.w.Print("\n", ansi.From, " ", dual, " ")
+	}
 }
 
 func (sc *StmtCompiler) visitWhere(where *Where) {
@@ -1463,9 +3941,28 @@ func (sc *StmtCompiler) visitGroupBy(groupBy *GroupBy) {
 		return
 	}
 
+	// mysql & clickhouse write GROUP BY a, b WITH ROLLUP, others write GROUP BY ROLLUP (a, b)
+	grouping, suffix := "", false
+	switch groupBy.Grouping {
+	case "":
+	case Rollup, Cube:
+		grouping, suffix = dialectGroupingSetSql(sc.Dialecter, groupBy.Grouping)
+		if grouping == "" {
+			sc.setError(CompileUnsupported, NodeGroupBy, sc.Dialecter.Name()+" doesn't support group by "+groupBy.Grouping.String())
+			return
+		}
+	default:
+		sc.setError(CompileInvalid, NodeGroupBy, "invalid grouping set:"+groupBy.Grouping.String())
+		return
+	}
+
 	sc.w.LineBreak()
 	sc.w.WriteString(ansi.GroupBy)
 	sc.w.Blank()
+	if grouping != "" && !suffix {
+		sc.w.Print(grouping, " ")
+		sc.w.OpenParentheses()
+	}
 
 	split := false
 	for i := 0; i < l; i++ {
@@ -1476,6 +3973,14 @@ func (sc *StmtCompiler) visitGroupBy(groupBy *GroupBy) {
 		split = true
 		sc.visitExp(item)
 	}
+
+	if grouping != "" {
+		if suffix {
+			sc.w.Print(" ", grouping)
+		} else {
+			sc.w.CloseParentheses()
+		}
+	}
 	sc.w.Blank()
 }
 
@@ -1492,52 +3997,226 @@ func (sc *StmtCompiler) visitOrderBy(orderBy *OrderBy) {
 	sc.w.LineBreak()
 	sc.w.WriteString(ansi.OrderBy)
 	sc.w.Blank()
-	split := false
+	sc.visitOrderByFields(orderBy.Fields)
+	sc.w.Blank()
+}
 
-	for i := 0; i < l; i++ {
-		item := orderBy.Fields[i]
-		if split {
+func (sc *StmtCompiler) visitOrderByFields(fields []*OrderByField) {
+	for i := 0; i < len(fields); i++ {
+		item := fields[i]
+		if i > 0 {
 			sc.w.Comma()
 		}
-		split = true
 		sc.visitExp(item.Exp)
+		if item.Collation != "" {
+			sc.writeCollate(item.Collation)
+		}
+
+		direction := item.Direction
+		if direction == SortDefault {
+			if sc.OmitDefaultAsc {
+				continue
+			}
+			direction = Asc
+		}
+		sc.w.Blank()
+		sc.w.WriteString(direction.String())
+	}
+}
+
+func (sc *StmtCompiler) visitWith(with *With) {
+	if with == nil || len(with.Ctes) == 0 {
+		return
+	}
+
+	sc.w.WriteString(ansi.With)
+	sc.w.Blank()
+	if with.Recursive {
+		sc.w.WriteString(ansi.Recursive)
 		sc.w.Blank()
-		sc.w.WriteString(item.Direction.String())
 	}
+
+	for i := 0; i < len(with.Ctes); i++ {
+		cte := with.Ctes[i]
+		if cte == nil || cte.Query == nil {
+			sc.setError(CompileInvalid, NodeWith, "query of cte is nil")
+			return
+		}
+		if !plainIdentifierRegexp.MatchString(cte.Name) {
+			sc.setError(CompileInvalid, NodeWith, "invalid cte name:"+cte.Name)
+			return
+		}
+		for _, column := range cte.Columns {
+			if !plainIdentifierRegexp.MatchString(column) {
+				sc.setError(CompileInvalid, NodeWith, "invalid cte column:"+column)
+				return
+			}
+		}
+
+		if i > 0 {
+			sc.w.Comma()
+			sc.w.LineBreak()
+		}
+
+		sc.w.WriteString(cte.Name)
+		if len(cte.Columns) > 0 {
+			sc.w.Blank()
+			sc.w.OpenParentheses()
+			sc.w.PrintSplit(", ", cte.Columns...)
+			sc.w.CloseParentheses()
+		}
+		sc.w.Print(" ", ansi.As, " ")
+		sc.w.OpenParentheses()
+		sc.w.LineBreak()
+		sc.visitQueryBody(cte.Query)
+		sc.w.LineBreak()
+		sc.w.CloseParentheses()
+	}
+	sc.w.LineBreak()
+}
+
+// visitDistinctOn write DISTINCT ON (a, b), columns are quoted
+func (sc *StmtCompiler) visitDistinctOn(exps []Expression) {
+	if !dialectSupportDistinctOn(sc.Dialecter) {
+		sc.setError(CompileUnsupported, NodeQuery, "driver doesn't support distinct on:"+sc.Dialecter.Name())
+		return
+	}
+
+	sc.w.Print(ansi.Distinct, " ", ansi.On, " ")
+	sc.w.OpenParentheses()
+	for i := 0; i < len(exps); i++ {
+		if i > 0 {
+			sc.w.Comma()
+		}
+		switch exp := exps[i].(type) {
+		case Column:
+			sc.writeIdentifier(string(exp))
+		case *Column:
+			sc.writeIdentifier(string(*exp))
+		default:
+			sc.visitExp(exp)
+		}
+	}
+	sc.w.CloseParentheses()
 	sc.w.Blank()
 }
 
+func (sc *StmtCompiler) visitSetOps(setOps []*SetOperation) {
+	for _, so := range setOps {
+		if so == nil || so.Query == nil {
+			sc.setError(CompileInvalid, NodeQuery, "query of set operation is nil")
+			return
+		}
+		if !so.Operator.IsValid() {
+			sc.setError(CompileInvalid, NodeQuery, "invalid set operator:"+so.Operator.String())
+			return
+		}
+		if so.Query.With != nil {
+			sc.setError(CompileInvalid, NodeQuery, "query of set operation can not have with clause, use with of first query")
+			return
+		}
+
+		op := dialectSetOperatorSql(sc.Dialecter, so.Operator)
+		if op == "" {
+			sc.setError(CompileUnsupported, NodeQuery, sc.Dialecter.Name()+" doesn't support set operator:"+so.Operator.String())
+			return
+		}
+
+		sc.w.LineBreak()
+		sc.w.WriteString(op)
+		sc.w.LineBreak()
+
+		// order by, limit and lock of combined query need parentheses, otherwise they apply to whole result
+		q := so.Query
+		if (q.OrderBy != nil && len(q.OrderBy.Fields) > 0) || q.Offset > 0 || q.Count > 0 || q.Lock != nil || len(q.SetOps) > 0 {
+			sc.w.OpenParentheses()
+			sc.visitQueryBody(q)
+			sc.w.CloseParentheses()
+		} else {
+			sc.visitQueryBody(q)
+		}
+	}
+}
+
 func (sc *StmtCompiler) visitQuery(exp Expression) {
 	query, _ := exp.(*Query)
 
+	sc.visitQueryBody(query)
+	sc.visitEndStatement()
+}
+
+// visitQueryBody write query without statement split, so it can be used as subquery
+func (sc *StmtCompiler) visitQueryBody(query *Query) {
+	if query == nil {
+		return
+	}
+	sc.planGuard(query, "Offset", query.Offset)
+	sc.planGuard(query, "Count", query.Count)
+
+	sc.visitWith(query.With)
 	sc.w.WriteString(ansi.Select)
 	sc.w.Blank()
-	if query.IsDistinct {
+	if len(query.DistinctOn) > 0 {
+		sc.visitDistinctOn(query.DistinctOn)
+	} else if query.IsDistinct {
 		sc.w.WriteString(ansi.Distinct)
 		sc.w.Blank()
 	}
 
 	sc.visitSelect(query.Select)
-	sc.visitFrom(query.From)
+	sc.visitQueryFrom(query.From)
 	sc.visitWhere(query.Where)
 	sc.visitGroupBy(query.GroupBy)
-	if query.GroupBy != nil && len(query.GroupBy.Fields) > 0 {
-		sc.visitHaving(query.Having)
-	}
+	sc.visitHaving(query.Having)
+	sc.visitSetOps(query.SetOps)
 	sc.visitOrderBy(query.OrderBy)
 
-	// limit, mssql doesn't support limit, need change to select * from (ROW_NUMBER(),...) where ...
+	// limit, paging clause of some dialects like mssql is only allowed after ORDER BY
 	if query.Offset > 0 || query.Count > 0 {
+		if dialectLimitRequiresOrderBy(sc.Dialecter) && (query.OrderBy == nil || len(query.OrderBy.Fields) == 0) {
+			sc.setError(CompileInvalid, NodeQuery, sc.Dialecter.Name()+" requires ORDER BY when query has limit")
+			return
+		}
 		sc.w.LineBreak()
-		sc.w.Print(ansi.Limit, " ", strconv.Itoa(query.Offset), ",", strconv.Itoa(query.Count))
+		sc.visitLimit(query.Offset, query.Count)
 	}
-	sc.visitEndStatement()
+
+	if query.Lock != nil {
+		lock := dialectLockSql(sc.Dialecter, query.Lock)
+		if lock == "" {
+			sc.setError(CompileUnsupported, NodeQuery, sc.Dialecter.Name()+" doesn't support lock:"+query.Lock.String())
+			return
+		}
+		sc.w.LineBreak()
+		sc.w.WriteString(lock)
+	}
+}
+
+// visitLimit write paging clause of dialect, like LIMIT offset,count or OFFSET offset ROWS FETCH NEXT count ROWS ONLY
+func (sc *StmtCompiler) visitLimit(offset, count int) {
+	limit := dialectLimitSql(sc.Dialecter, offset, count)
+	if limit == "" {
+		sc.setError(CompileUnsupported, NodeQuery, sc.Dialecter.Name()+" doesn't support limit")
+		return
+	}
+	sc.w.WriteString(limit)
 }
 
 func (sc *StmtCompiler) visitInsert(exp Expression) {
 	insert, _ := exp.(*Insert)
 
-	sc.w.Print(ansi.InsertInto, ansi.Blank, insert.Table.Name)
+	if insert.Query != nil {
+		sc.visitInsertQuery(insert)
+		return
+	}
+
+	if len(insert.Sets) == 0 {
+		sc.setError(CompileInvalid, NodeInsert, "insert doesn't have any set:"+insert.Table.Name)
+		return
+	}
+
+	sc.w.Print(ansi.InsertInto, ansi.Blank)
+	sc.writeTableIdentifier(insert.Table)
 
 	l := len(insert.Sets)
 	sc.w.OpenParentheses()
@@ -1547,7 +4226,7 @@ func (sc *StmtCompiler) visitInsert(exp Expression) {
 		}
 
 		set := insert.Sets[i]
-		sc.visitColumn(set.Column)
+		sc.writeIdentifier(set.Column.String())
 	}
 	sc.w.CloseParentheses()
 
@@ -1566,23 +4245,117 @@ func (sc *StmtCompiler) visitInsert(exp Expression) {
 	sc.visitEndStatement()
 }
 
+// visitInsertQuery write INSERT INTO table (columns) SELECT ...
+func (sc *StmtCompiler) visitInsertQuery(insert *Insert) {
+	if len(insert.Sets) > 0 {
+		sc.setError(CompileInvalid, NodeInsert, "insert can not have both sets and query:"+insert.Table.Name)
+		return
+	}
+
+	if n := projectionCount(insert.Query); len(insert.Columns) > 0 && n >= 0 && n != len(insert.Columns) {
+		sc.setError(CompileInvalid, NodeInsert, fmt.Sprintf("insert into %s has %d columns but select returns %d values", insert.Table.Name, len(insert.Columns), n))
+		return
+	}
+
+	sc.w.Print(ansi.InsertInto, ansi.Blank)
+	sc.writeTableIdentifier(insert.Table)
+
+	if len(insert.Columns) > 0 {
+		sc.w.OpenParentheses()
+		for i := 0; i < len(insert.Columns); i++ {
+			if i > 0 {
+				sc.w.Comma()
+			}
+			sc.writeIdentifier(insert.Columns[i])
+		}
+		sc.w.CloseParentheses()
+	}
+
+	sc.w.LineBreak()
+	sc.visitQueryBody(insert.Query)
+	sc.visitEndStatement()
+}
+
+// projectionCount return count of values returned by q, return -1 if it's unknown like SELECT *
+func projectionCount(q *Query) int {
+	if q == nil {
+		return -1
+	}
+
+	if q.Select == nil || len(q.Select.Fields) == 0 {
+		if q.From == nil || q.From.Table == nil || len(q.From.Tables) > 0 || len(q.From.Joins) > 0 {
+			return -1
+		}
+		t := q.From.Table
+		if len(t.Columns) > 0 {
+			return len(t.Columns)
+		}
+		if vl, ok := t.Source.(*ValuesList); ok && len(vl.Rows) > 0 {
+			return len(vl.Rows[0])
+		}
+		return -1
+	}
+
+	for i := 0; i < len(q.Select.Fields); i++ {
+		f := q.Select.Fields[i]
+		if f == nil {
+			continue
+		}
+		switch exp := f.Exp.(type) {
+		case Column:
+			if strings.HasSuffix(string(exp), ansi.WildcardAll) {
+				return -1
+			}
+		case *TableColumn:
+			if exp.Name == ansi.WildcardAll {
+				return -1
+			}
+		}
+	}
+	return len(q.Select.Fields)
+}
+
+// isFullTable return true if where is empty and joins of from don't have conditions
+func isFullTable(from *From, where *Where) bool {
+	if where != nil && !where.Conditions.isEmpty() {
+		return false
+	}
+	if from != nil {
+		for i := 0; i < len(from.Joins); i++ {
+			if j := from.Joins[i]; j != nil && (!j.Conditions.isEmpty() || len(j.Using) > 0) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func (sc *StmtCompiler) visitUpdate(exp Expression) {
 	u, _ := exp.(*Update)
+	sc.planGuard(u, "Count", u.Count)
 
-	sc.w.PrintSplit(ansi.Blank, ansi.Update, u.Table.Name, ansi.Set, ansi.LineBreak)
-	l := len(u.Sets)
-	for i := 0; i < l; i++ {
-		if i > 0 {
-			sc.w.Comma()
-		}
+	if len(u.Sets) == 0 {
+		sc.setError(CompileInvalid, NodeUpdate, "update doesn't have any set:"+u.Table.Name)
+		return
+	}
 
-		set := u.Sets[i]
-		sc.visitColumn(set.Column)
-		sc.w.WriteString(ansi.Equals)
-		sc.visitExp(set.Value)
+	if !u.AllowEmptyWhere && isFullTable(u.From, u.Where) {
+		sc.setError(CompileInvalid, NodeUpdate, "update without where should set AllowEmptyWhere:"+u.Table.Name)
+		return
+	}
+
+	if u.From != nil {
+		sc.visitUpdateFrom(u)
+		return
 	}
+
+	sc.w.Print(ansi.Update, ansi.Blank)
+	sc.writeTableIdentifier(u.Table)
+	sc.visitSets(u.Sets)
+	sc.quoteColumn = true
 	sc.visitWhere(u.Where)
 	sc.visitOrderBy(u.OrderBy)
+	sc.quoteColumn = false
 	if u.Count > 0 {
 		sc.w.LineBreak()
 		sc.w.PrintSplit(" ", ansi.Limit, strconv.Itoa(u.Count))
@@ -1591,12 +4364,98 @@ func (sc *StmtCompiler) visitUpdate(exp Expression) {
 
 }
 
+// visitUpdateFrom write update joined with other tables,
+// mysql: UPDATE t1 JOIN t2 ON ... SET ...; mssql: UPDATE t1 SET ... FROM t1 JOIN t2 ON ...; postgres: UPDATE t1 SET ... FROM t2 WHERE ...
+func (sc *StmtCompiler) visitUpdateFrom(u *Update) {
+	if (u.OrderBy != nil && !u.OrderBy.isEmpty()) || u.Count > 0 {
+		sc.setError(CompileUnsupported, NodeUpdate, "multi-table update doesn't support order by or limit")
+		return
+	}
+	if u.From.Table == nil {
+		sc.setError(CompileInvalid, NodeUpdate, "update from table is nil")
+		return
+	}
+
+	switch sc.Dialecter.Name() {
+	case "mysql":
+		sc.w.Print(ansi.Update, ansi.Blank)
+		sc.visitTable(u.From.Table)
+		for i := 0; i < len(u.From.Tables); i++ {
+			sc.w.Comma()
+			sc.visitTable(u.From.Tables[i])
+		}
+		for i := 0; i < len(u.From.Joins); i++ {
+			sc.w.LineBreak()
+			sc.visitJoin(u.From.Joins[i])
+		}
+		sc.visitSets(u.Sets)
+		sc.visitWhere(u.Where)
+	case "mssql":
+		target := u.Table.Name
+		if t := u.From.FindTable(u.Table.Name); t != nil && t.Alias != "" {
+			target = t.Alias
+		}
+
+		sc.w.Print(ansi.Update, ansi.Blank)
+		sc.writeIdentifier(target)
+		sc.visitSets(u.Sets)
+		sc.visitFrom(u.From)
+		sc.visitWhere(u.Where)
+	case "postgres":
+		if !strings.EqualFold(u.From.Table.Name, u.Table.Name) {
+			sc.setError(CompileInvalid, NodeUpdate, "update table should be the first table of from:"+u.Table.Name)
+			return
+		}
+
+		sc.w.Print(ansi.Update, ansi.Blank)
+		sc.visitTable(u.From.Table)
+		sc.visitSets(u.Sets)
+		sc.w.Print(ansi.LineBreak, ansi.From, ansi.Blank)
+		sc.visitJoinedTables(u.From)
+		sc.visitJoinedWhere(u.From, u.Where)
+	default:
+		sc.setError(CompileUnsupported, NodeUpdate, "driver doesn't support multi-table update:"+sc.Dialecter.Name())
+		return
+	}
+	sc.visitEndStatement()
+}
+
+// visitSets write SET column = value, ...
+func (sc *StmtCompiler) visitSets(sets []*Set) {
+	sc.w.Print(ansi.Blank, ansi.Set, ansi.LineBreak)
+	l := len(sets)
+	for i := 0; i < l; i++ {
+		if i > 0 {
+			sc.w.Comma()
+		}
+
+		set := sets[i]
+		sc.writeIdentifier(set.Column.String())
+		sc.w.WriteString(ansi.Equals)
+		sc.visitExp(set.Value)
+	}
+}
+
 func (sc *StmtCompiler) visitDelete(exp Expression) {
 	d, _ := exp.(*Delete)
+	sc.planGuard(d, "Count", d.Count)
+
+	if !d.AllowEmptyWhere && isFullTable(d.From, d.Where) {
+		sc.setError(CompileInvalid, NodeDelete, "delete without where should set AllowEmptyWhere:"+d.Table.Name)
+		return
+	}
+
+	if d.From != nil {
+		sc.visitDeleteFrom(d)
+		return
+	}
 
-	sc.w.PrintSplit(ansi.Blank, ansi.Delete, ansi.From, d.Table.Name)
+	sc.w.PrintSplit(ansi.Blank, ansi.Delete, ansi.From, "")
+	sc.writeTableIdentifier(d.Table)
+	sc.quoteColumn = true
 	sc.visitWhere(d.Where)
 	sc.visitOrderBy(d.OrderBy)
+	sc.quoteColumn = false
 	if d.Count > 0 {
 		sc.w.LineBreak()
 		sc.w.PrintSplit(" ", ansi.Limit, strconv.Itoa(d.Count))
@@ -1604,7 +4463,144 @@ func (sc *StmtCompiler) visitDelete(exp Expression) {
 	sc.visitEndStatement()
 }
 
+// visitDeleteFrom write multi-table delete,
+// mysql & mssql: DELETE t1 FROM t1 JOIN t2 ON ... WHERE ...; postgres: DELETE FROM t1 USING t2 WHERE ...
+func (sc *StmtCompiler) visitDeleteFrom(d *Delete) {
+	if (d.OrderBy != nil && !d.OrderBy.isEmpty()) || d.Count > 0 {
+		sc.setError(CompileUnsupported, NodeDelete, "multi-table delete doesn't support order by or limit")
+		return
+	}
+	if d.From.Table == nil {
+		sc.setError(CompileInvalid, NodeDelete, "delete from table is nil")
+		return
+	}
+
+	switch sc.Dialecter.Name() {
+	case "mysql", "mssql":
+		target := d.Table.Name
+		if t := d.From.FindTable(d.Table.Name); t != nil && t.Alias != "" {
+			target = t.Alias
+		}
+
+		sc.w.Print(ansi.Delete, ansi.Blank)
+		sc.writeIdentifier(target)
+		sc.visitFrom(d.From)
+		sc.visitWhere(d.Where)
+	case "postgres":
+		if !strings.EqualFold(d.From.Table.Name, d.Table.Name) {
+			sc.setError(CompileInvalid, NodeDelete, "delete table should be the first table of from:"+d.Table.Name)
+			return
+		}
+
+		sc.w.Print(ansi.Delete, ansi.Blank, ansi.From, ansi.Blank)
+		sc.visitTable(d.From.Table)
+		sc.w.Print(ansi.LineBreak, ansi.Using, ansi.Blank)
+		sc.visitJoinedTables(d.From)
+		sc.visitJoinedWhere(d.From, d.Where)
+	default:
+		sc.setError(CompileUnsupported, NodeDelete, "driver doesn't support multi-table delete:"+sc.Dialecter.Name())
+		return
+	}
+	sc.visitEndStatement()
+}
+
+// visitJoinedTables write tables except f.Table as a list, like t2, t3; only inner or cross join is allowed
+func (sc *StmtCompiler) visitJoinedTables(f *From) {
+	tables := make([]*Table, 0, len(f.Tables)+len(f.Joins))
+	tables = append(tables, f.Tables...)
+	for i := 0; i < len(f.Joins); i++ {
+		j := f.Joins[i]
+		if (j.JoinType != InnerJoin && j.JoinType != CrossJoin) || len(j.Using) > 0 || j.Lateral {
+			sc.setError(CompileUnsupported, NodeJoin, "only inner join with on conditions can be converted to table list:"+j.Right.String())
+			return
+		}
+		tables = append(tables, j.Right)
+	}
+
+	if len(tables) == 0 {
+		sc.setError(CompileInvalid, NodeFrom, "from has no joined table")
+		return
+	}
+
+	for i := 0; i < len(tables); i++ {
+		if i > 0 {
+			sc.w.Comma()
+		}
+		sc.visitTable(tables[i])
+	}
+	sc.w.Blank()
+}
+
+// visitJoinedWhere write join conditions of f and where as one where clause, like WHERE (join) AND (where)
+func (sc *StmtCompiler) visitJoinedWhere(f *From, where *Where) {
+	conditions := make([]*Conditions, 0, len(f.Joins)+1)
+	for i := 0; i < len(f.Joins); i++ {
+		if !f.Joins[i].Conditions.isEmpty() {
+			conditions = append(conditions, f.Joins[i].Conditions)
+		}
+	}
+	if where != nil && !where.isEmpty() {
+		conditions = append(conditions, where.Conditions)
+	}
+
+	if len(conditions) == 0 {
+		return
+	}
+
+	sc.w.Print("\n", ansi.Where, "\n")
+	for i := 0; i < len(conditions); i++ {
+		if i > 0 {
+			sc.w.Print(ansi.LineBreak, ansi.And, ansi.Blank)
+		}
+		sc.w.OpenParentheses()
+		sc.visitConditions(conditions[i])
+		sc.w.CloseParentheses()
+	}
+}
+
+func (sc *StmtCompiler) visitDropTable(exp Expression) {
+	d, _ := exp.(*DropTable)
+
+	sc.w.WriteString(ansi.DropTable)
+	if d.IfExists {
+		if sc.Dialecter.Name() == "oracle" {
+			sc.setError(CompileUnsupported, NodeDropTable, "driver doesn't support drop table if exists:"+sc.Dialecter.Name())
+			return
+		}
+		sc.w.Print(" ", ansi.IfExists)
+	}
+	sc.w.Blank()
+	sc.writeTableIdentifier(d.Table)
+	sc.visitEndStatement()
+}
+
+func (sc *StmtCompiler) visitTruncate(exp Expression) {
+	t, _ := exp.(*Truncate)
+
+	if sc.Dialecter.Name() == "sqlite" {
+		sc.setError(CompileUnsupported, NodeTruncate, "driver doesn't support truncate:"+sc.Dialecter.Name())
+		return
+	}
+	if (t.RestartIdentity || t.Cascade) && sc.Dialecter.Name() != "postgres" {
+		sc.setError(CompileUnsupported, NodeTruncate, "driver doesn't support truncate restart identity or cascade:"+sc.Dialecter.Name())
+		return
+	}
+
+	sc.w.Print(ansi.TruncateTable, " ")
+	sc.writeTableIdentifier(t.Table)
+	if t.RestartIdentity {
+		sc.w.Print(" ", ansi.RestartIdentity)
+	}
+	if t.Cascade {
+		sc.w.Print(" ", ansi.Cascade)
+	}
+	sc.visitEndStatement()
+}
+
 func (sc *StmtCompiler) visitEndStatement() {
+	if sc.OmitTerminator {
+		return
+	}
 	sc.w.WriteString(sc.Dialecter.SplitStatement())
 }
 
@@ -1638,6 +4634,11 @@ func Oracle() Driver {
 	return NewSqlDriver(OracleSQLDialecter{})
 }
 
+// ClickHouse return ClickHouse driver
+func ClickHouse() Driver {
+	return NewSqlDriver(ClickHouseDialecter{})
+}
+
 func init() {
 	RegisterDialecter("ansi", AnsiDialecter{})
 	RegisterCompiler("ansi", DefaultSQL())
@@ -1660,4 +4661,7 @@ func init() {
 	RegisterDialecter("goracle", OracleSQLDialecter{})
 	RegisterCompiler("goracle", Oracle())
 
+	RegisterDialecter("clickhouse", ClickHouseDialecter{})
+	RegisterCompiler("clickhouse", ClickHouse())
+
 }
@@ -0,0 +1,572 @@
+package kdb
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/sdming/kdb/ansi"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNativeType(t *testing.T) {
+	dialecters := []interface {
+		Dialecter
+		NativeTyper
+	}{MysqlDialecter{}, PostgreSQLDialecter{}}
+
+	for _, d := range dialecters {
+		if s := d.NativeType(ansi.String, 100, 0, 0); s != "VARCHAR(100)" {
+			t.Error("native type of string error", d.Name(), s)
+		}
+		if s := d.NativeType(ansi.Numeric, 0, 18, 2); s != "DECIMAL(18,2)" {
+			t.Error("native type of numeric error", d.Name(), s)
+		}
+		if s := d.NativeType(ansi.Var, 0, 0, 0); s != "" {
+			t.Error("native type of var should be empty", d.Name(), s)
+		}
+	}
+
+	if s := (MysqlDialecter{}).NativeType(ansi.Int, 0, 0, 0); s != "INT" {
+		t.Error("mysql native type of int error", s)
+	}
+	if s := (PostgreSQLDialecter{}).NativeType(ansi.Int, 0, 0, 0); s != "INTEGER" {
+		t.Error("postgres native type of int error", s)
+	}
+	if s := (PostgreSQLDialecter{}).NativeType(ansi.String, 0, 0, 0); s != "TEXT" {
+		t.Error("postgres native type of string without length error", s)
+	}
+}
+
+func TestCompileCreateTable(t *testing.T) {
+	table := ansi.NewTable()
+	table.Name = "ttable"
+	table.Columns = append(table.Columns,
+		ansi.DbColumn{Name: "id", DbType: ansi.Int, IsPrimaryKey: true, IsAutoIncrement: true},
+		ansi.DbColumn{Name: "cstring", DbType: ansi.String, Size: 100, IsNullable: true},
+		ansi.DbColumn{Name: "cnumeric", DbType: ansi.Numeric, Precision: 18, Scale: 2},
+	)
+
+	tests := []struct {
+		driver string
+		want   string
+	}{
+		{"mysql", "CREATE TABLE `ttable` (\n\t`id` INT NOT NULL AUTO_INCREMENT,\n\t`cstring` VARCHAR(100) NULL,\n\t`cnumeric` DECIMAL(18,2) NOT NULL,\n\tPRIMARY KEY (`id`)\n) ; "},
+		{"postgres", "CREATE TABLE \"ttable\" (\n\t\"id\" SERIAL NOT NULL,\n\t\"cstring\" VARCHAR(100) NULL,\n\t\"cnumeric\" DECIMAL(18,2) NOT NULL,\n\tPRIMARY KEY (\"id\")\n) ; "},
+	}
+
+	for _, test := range tests {
+		query, err := CompileCreateTable(test.driver, "source", table)
+		t.Log(query)
+		if err != nil {
+			t.Error("compile create table error", test.driver, err)
+		}
+		if query != test.want {
+			t.Error("compiled create table sql error", test.driver, "\n", query, "\n", test.want)
+		}
+	}
+
+	compiler, _ := GetCompiler("mysql")
+	if _, err := compiler.(*SqlDriver).CompileCreateTable("source", ansi.NewTable()); err == nil {
+		t.Error("compile create table without columns should return error")
+	}
+
+	big := ansi.NewTable()
+	big.Name = "tbig"
+	big.Columns = append(big.Columns,
+		ansi.DbColumn{Name: "id", DbType: ansi.Int, NativeType: "bigint", IsPrimaryKey: true, IsAutoIncrement: true},
+		ansi.DbColumn{Name: "uid", DbType: ansi.Uint, IsAutoIncrement: true},
+	)
+	query, err := CompileCreateTable("postgres", "source", big)
+	want := "CREATE TABLE \"tbig\" (\n\t\"id\" BIGSERIAL NOT NULL,\n\t\"uid\" BIGSERIAL NOT NULL,\n\tPRIMARY KEY (\"id\")\n) ; "
+	if err != nil || query != want {
+		t.Error("compile create table with bigint autoincrement error", "\n", query, "\n", want, err)
+	}
+
+	if _, err := CompileCreateTable("kdb_block_unknown", "source", table); err == nil {
+		t.Error("compile create table of unknown driver should return error")
+	}
+}
+
+type testSchemaer struct{}
+
+func (testSchemaer) Table(db *sql.DB, name string) (*ansi.DbTable, error) {
+	return nil, nil
+}
+
+func (testSchemaer) Function(db *sql.DB, name string) (*ansi.DbFunction, error) {
+	return nil, nil
+}
+
+func TestRegisterConcurrent(t *testing.T) {
+	// go test -race -run TestRegisterConcurrent
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			driver := fmt.Sprint("race_", i)
+			RegisterCompilerReplace(driver, NewSqlDriver(AnsiDialecter{}))
+			RegisterDialecterReplace(driver, AnsiDialecter{})
+			RegisterSchemaerReplace(driver, testSchemaer{})
+
+			if _, err := GetCompiler("ansi"); err != nil {
+				t.Error("get compiler error", err)
+			}
+			if _, err := GetDialecter(driver); err != nil {
+				t.Error("get dialecter error", err)
+			}
+			if _, err := GetSchemaer(driver); err != nil {
+				t.Error("get schemaer error", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestRegisterCompilerDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("register compiler twice should panic")
+		}
+	}()
+	RegisterCompiler("ansi", NewSqlDriver(AnsiDialecter{}))
+}
+
+func TestRegisterDialecterDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("register dialecter twice should panic")
+		}
+	}()
+	RegisterDialecter("ansi", AnsiDialecter{})
+}
+
+func TestRegisterSchemaerDuplicate(t *testing.T) {
+	RegisterSchemaerReplace("schemaer_dup", testSchemaer{})
+	defer func() {
+		_schemaersLock.Lock()
+		delete(_schemaers, "schemaer_dup")
+		_schemaersLock.Unlock()
+		if recover() == nil {
+			t.Error("register schemaer twice should panic")
+		}
+	}()
+	RegisterSchemaer("schemaer_dup", testSchemaer{})
+}
+
+func TestRegisterDialecterReplace(t *testing.T) {
+	RegisterDialecterReplace("replace_test", AnsiDialecter{})
+	RegisterDialecterReplace("replace_test", MysqlDialecter{})
+
+	d, err := GetDialecter("replace_test")
+	if err != nil {
+		t.Fatal("get dialecter error", err)
+	}
+	if d.Name() != "mysql" {
+		t.Error("dialecter should be replaced", d.Name())
+	}
+}
+
+func TestRegisterCompilerReplace(t *testing.T) {
+	compiler := NewSqlDriver(MysqlDialecter{})
+	RegisterCompilerReplace("replace_test", NewSqlDriver(AnsiDialecter{}))
+	RegisterCompilerReplace("replace_test", compiler)
+
+	c, err := GetCompiler("replace_test")
+	if err != nil {
+		t.Fatal("get compiler error", err)
+	}
+	if c != compiler {
+		t.Error("compiler should be replaced")
+	}
+}
+
+func TestClickHouseDbType(t *testing.T) {
+	d := ClickHouseDialecter{}
+	types := map[string]ansi.DbType{
+		"UInt8":                           ansi.Uint,
+		"Int64":                           ansi.Int,
+		"Float64":                         ansi.Float,
+		"String":                          ansi.String,
+		"FixedString(16)":                 ansi.String,
+		"DateTime":                        ansi.DateTime,
+		"DateTime64(3)":                   ansi.DateTime,
+		"Date":                            ansi.Date,
+		"UUID":                            ansi.Guid,
+		"Decimal(18, 2)":                  ansi.Numeric,
+		"Nullable(Int32)":                 ansi.Int,
+		"LowCardinality(String)":          ansi.String,
+		"LowCardinality(Nullable(UInt8))": ansi.Uint,
+		"Array(String)":                   ansi.Var,
+	}
+
+	for native, want := range types {
+		if actual := d.DbType(native); actual != want {
+			t.Errorf("clickhouse DbType error, native=[%v]; want=[%v]; actual=[%v]", native, want, actual)
+		}
+	}
+}
+
+func TestClickHouseLimit(t *testing.T) {
+	comiler, err := GetCompiler("clickhouse")
+	if err != nil {
+		t.Fatal("can not find clickhouse compiler", err)
+	}
+
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint")
+	q.Where.Equals("cint", 1)
+	q.Limit(20, 10)
+
+	formatedSql, args, err := comiler.Compile("source", q)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Error("compile clickhouse query error", err)
+	}
+	want := "SELECT cint FROM ttable WHERE cint = ? LIMIT 10 OFFSET 20 ;"
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled clickhouse sql error", "\n", formatedSql, "\n", want)
+	}
+
+	insert := NewInsert("ttable").Set("cint", 1)
+	formatedSql, _, err = comiler.Compile("source", insert)
+	if err != nil || !strings.Contains(formatedSql, "`ttable`(`cint`)") {
+		t.Error("compiled clickhouse insert error", formatedSql, err)
+	}
+}
+
+func TestPostgreSQLDbType(t *testing.T) {
+	d := PostgreSQLDialecter{}
+	types := map[string]ansi.DbType{
+		"int2":        ansi.Int,
+		"int4":        ansi.Int,
+		"int8":        ansi.Int,
+		"float4":      ansi.Float,
+		"float8":      ansi.Float,
+		"bool":        ansi.Boolean,
+		"bpchar":      ansi.String,
+		"text":        ansi.String,
+		"timestamptz": ansi.DateTime,
+		"date":        ansi.Date,
+		"jsonb":       ansi.Json,
+		"uuid":        ansi.Guid,
+		"bytea":       ansi.Bytes,
+		"numeric":     ansi.Numeric,
+	}
+
+	for native, want := range types {
+		if actual := d.DbType(native); actual != want {
+			t.Errorf("postgres DbType error, native=[%v]; want=[%v]; actual=[%v]", native, want, actual)
+		}
+	}
+}
+
+func TestJsonDbType(t *testing.T) {
+	cases := []struct {
+		d      Dialecter
+		native string
+		want   ansi.DbType
+	}{
+		{PostgreSQLDialecter{}, "json", ansi.Json},
+		{PostgreSQLDialecter{}, "JSONB", ansi.Json},
+		{PostgreSQLDialecter{}, "text", ansi.String},
+		{MysqlDialecter{}, "json", ansi.Json},
+		{MysqlDialecter{}, "varchar", ansi.String},
+		{MysqlDialecter{}, "longtext", ansi.String},
+		{AnsiDialecter{}, "json", ansi.Var},
+	}
+
+	for _, c := range cases {
+		if actual := c.d.DbType(c.native); actual != c.want {
+			t.Errorf("DbType error, dialecter=[%v]; native=[%v]; want=[%v]; actual=[%v]", c.d.Name(), c.native, c.want, actual)
+		}
+	}
+
+	if s := (MysqlDialecter{}).NativeType(ansi.Json, 0, 0, 0); s != "JSON" {
+		t.Error("mysql native type of json error", s)
+	}
+	if s := (PostgreSQLDialecter{}).NativeType(ansi.Json, 0, 0, 0); s != "JSONB" {
+		t.Error("postgres native type of json error", s)
+	}
+}
+
+func TestUintDbType(t *testing.T) {
+	cases := map[string]ansi.DbType{
+		"bigint unsigned": ansi.Uint,
+		"int unsigned":    ansi.Uint,
+		"bigint":          ansi.Int,
+		"int":             ansi.Int,
+	}
+
+	d := MysqlDialecter{}
+	for native, want := range cases {
+		if actual := d.DbType(native); actual != want {
+			t.Errorf("DbType error, native=[%v]; want=[%v]; actual=[%v]", native, want, actual)
+		}
+	}
+
+	if !ansi.DbType(ansi.Uint).IsInteger() {
+		t.Error("uint should be integer")
+	}
+	if s := d.NativeType(ansi.Uint, 0, 0, 0); s != "BIGINT UNSIGNED" {
+		t.Error("mysql native type of uint error", s)
+	}
+}
+
+func TestExplainSql(t *testing.T) {
+	q := NewQuery("ta", "")
+	q.Select.Column("a")
+	q.Where.Equals("a", 1)
+
+	cases := []struct {
+		driver  string
+		analyze bool
+		format  string
+		prefix  string
+	}{
+		{"sqlite3", false, "", "EXPLAIN QUERY PLAN SELECT"},
+		{"sqlite3", true, "", "EXPLAIN QUERY PLAN SELECT"},
+		{"postgres", false, "", "EXPLAIN SELECT"},
+		{"postgres", true, "", "EXPLAIN ANALYZE SELECT"},
+		{"mysql", false, "json", "EXPLAIN FORMAT=JSON SELECT"},
+		{"mysql", true, "", "EXPLAIN ANALYZE SELECT"},
+	}
+
+	for _, c := range cases {
+		compiler, _ := GetCompiler(c.driver)
+		dialecter, _ := GetDialecter(c.driver)
+		query, _, err := compiler.Compile("source", q)
+		if err != nil {
+			t.Fatal(c.driver, "compile error", err)
+		}
+
+		explain, err := ExplainSql(dialecter, query+" \n", c.analyze, c.format)
+		if err != nil {
+			t.Error(c.driver, "explain error", err)
+			continue
+		}
+		if !strings.HasPrefix(explain, c.prefix) {
+			t.Errorf("%s explain prefix error, want=[%s]; actual=[%s]", c.driver, c.prefix, explain)
+		}
+		if !strings.HasSuffix(strings.TrimSpace(explain), ";") || strings.Count(explain, ";") != 1 {
+			t.Errorf("%s explain terminator error, actual=[%s]", c.driver, explain)
+		}
+	}
+
+	if _, err := ExplainSql(OracleSQLDialecter{}, "SELECT 1 FROM dual", false, ""); err == nil {
+		t.Error("oracle explain should return error")
+	} else if ce, ok := err.(*CompileError); !ok || ce.Code != CompileUnsupported {
+		t.Error("oracle explain error code error", err)
+	}
+	if _, err := ExplainSql(MysqlDialecter{}, "SELECT 1", false, "json;"); err == nil {
+		t.Error("invalid explain format should return error")
+	} else if ce, ok := err.(*CompileError); !ok || ce.Code != CompileInvalid {
+		t.Error("invalid explain format error code error", err)
+	}
+	if explain, err := explainQuery("mysql", "SELECT 1", false, "json"); err != nil || explain != "EXPLAIN FORMAT=JSON SELECT 1"+(MysqlDialecter{}).SplitStatement() {
+		t.Error("explain query of driver error", explain, err)
+	}
+	if _, err := explainQuery("unknown", "SELECT 1", false, ""); err == nil {
+		t.Error("explain query of unknown driver should return error")
+	} else if ce, ok := err.(*CompileError); !ok || ce.Code != CompileUnsupported {
+		t.Error("explain query of unknown driver error code error", err)
+	}
+	if _, err := ExplainSql(SqliteDialecter{}, " ; ", false, ""); err == nil {
+		t.Error("empty explain query should return error")
+	} else if ce, ok := err.(*CompileError); !ok || ce.Code != CompileInvalid {
+		t.Error("empty explain query error code error", err)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint")
+	q.Where.Equals("cint", 1).Equals("cstring", "a")
+
+	query, args, err := Preview("mysql", q)
+	if err != nil {
+		t.Fatal("preview error", err)
+	}
+
+	compiler, _ := GetCompiler("mysql")
+	want, wantArgs, _ := compiler.Compile("source", q)
+	if query != want || fmt.Sprint(args) != fmt.Sprint(wantArgs) {
+		t.Error("preview error", "want:", want, wantArgs, "actual:", query, args)
+	}
+	if fmt.Sprint(args) != "[1 a]" {
+		t.Error("preview args error", args)
+	}
+
+	if _, _, err = Preview("unknown", q); err == nil {
+		t.Error("preview of unknown driver should return error")
+	}
+
+	if s := DebugString(q); !strings.HasPrefix(s, "Query: ") {
+		t.Error("debug string should start with node type", s)
+	}
+	if s := DebugString(nil); s != "<nil>" {
+		t.Error("debug string of nil error", s)
+	}
+}
+
+func TestInterpolateParams(t *testing.T) {
+	tm := time.Date(2013, 1, 2, 3, 4, 5, 0, time.UTC)
+	args := []interface{}{42, 3.5, "it's", tm, []byte{0x01, 0xab}, nil, true}
+
+	query, err := InterpolateParams("mysql", "SELECT * FROM t WHERE a = ? AND b = ? AND c = ? AND d = ? AND e = ? AND f = ? AND g = ? AND h = '?'", args)
+	if err != nil {
+		t.Fatal("interpolate mysql params error", err)
+	}
+	want := `SELECT * FROM t WHERE a = 42 AND b = 3.5 AND c = 'it''s' AND d = '2013-01-02 03:04:05' AND e = X'01ab' AND f = NULL AND g = TRUE AND h = '?'`
+	if query != want {
+		t.Error("interpolate mysql params error", "\nwant:  ", want, "\nactual:", query)
+	}
+
+	query, err = InterpolateParams("postgres", "SELECT * FROM t WHERE a = $2 AND b = $1 AND c = $2 AND d = $3", []interface{}{"a", 7, []byte{0xff}})
+	if err != nil {
+		t.Fatal("interpolate postgres params error", err)
+	}
+	want = `SELECT * FROM t WHERE a = 7 AND b = 'a' AND c = 7 AND d = '\xff'`
+	if query != want {
+		t.Error("interpolate postgres params error", "\nwant:  ", want, "\nactual:", query)
+	}
+
+	if _, err = InterpolateParams("mysql", "SELECT ? , ?", []interface{}{1}); err == nil {
+		t.Error("interpolate with too few args should return error")
+	}
+	if _, err = InterpolateParams("postgres", "SELECT $1", []interface{}{1, 2}); err == nil {
+		t.Error("interpolate with unused args should return error")
+	}
+	if _, err = InterpolateParams("postgres", "SELECT $3", []interface{}{1}); err == nil {
+		t.Error("interpolate with out of range placeholder should return error")
+	}
+
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint")
+	q.Where.Equals("cint", 1).Equals("cstring", "a")
+	compiler, _ := GetCompiler("goracle")
+	compiled, compiledArgs, _ := compiler.Compile("source", q)
+	if query, err = InterpolateParams("goracle", compiled, compiledArgs); err != nil || !strings.Contains(query, "cstring = 'a'") {
+		t.Error("interpolate oracle params error", query, err)
+	}
+
+	q = NewQuery("ttable", "")
+	q.Select.Column("cint")
+	q.Where.Equals("cint", &Parameter{Name: "id", Value: 7}).Equals("cstring", "a").LessThan("cbigint", &Parameter{Name: "id", Value: 7})
+	compiled, compiledArgs, names, err := compiler.(*SqlDriver).CompileNamed("source", q)
+	if err != nil {
+		t.Fatal("compile named error", err)
+	}
+	query, err = InterpolateNamedParams("goracle", compiled, compiledArgs, names)
+	if err != nil || !strings.Contains(query, "cint = 7") || !strings.Contains(query, "cstring = 'a'") || !strings.Contains(query, "cbigint < 7") {
+		t.Error("interpolate oracle named params error", query, err)
+	}
+	if _, err = InterpolateNamedParams("goracle", "SELECT :x FROM DUAL", []interface{}{1}, []string{"id"}); err == nil {
+		t.Error("interpolate unknown named placeholder should return error")
+	}
+}
+
+func TestCompileWhere(t *testing.T) {
+	where := NewWhere()
+	where.Equals("cint", 1).Like("cstring", "a%")
+
+	cases := []struct {
+		dialecter Dialecter
+		keyword   bool
+		want      string
+	}{
+		{MysqlDialecter{}, false, `cint = ? AND cstring LIKE ?`},
+		{MysqlDialecter{}, true, `WHERE cint = ? AND cstring LIKE ?`},
+		{PostgreSQLDialecter{}, false, `cint = $1 AND cstring LIKE $2`},
+	}
+
+	for _, c := range cases {
+		fragment, args, err := CompileWhere(c.dialecter, where, c.keyword)
+		t.Log(fragment, args)
+		if err != nil {
+			t.Error("compile where error", c.dialecter.Name(), err)
+			continue
+		}
+		if !strings.EqualFold(removeSpace(fragment), removeSpace(c.want)) {
+			t.Error("compile where error", c.dialecter.Name(), "want:", c.want, "actual:", fragment)
+		}
+		if !reflect.DeepEqual(args, []interface{}{1, "a%"}) {
+			t.Error("compile where args error", c.dialecter.Name(), args)
+		}
+	}
+
+	sc := NewStmtCompiler(PostgreSQLDialecter{}).WithParamOffset(2)
+	sc.Compact = true
+	fragment, _, err := sc.CompileWhere(where, true)
+	if want := `WHERE cint = $3 AND cstring LIKE $4`; err != nil || removeSpace(fragment) != removeSpace(want) {
+		t.Error("compile where with param offset error", "want:", want, "actual:", fragment, err)
+	}
+
+	if fragment, args, err := CompileWhere(MysqlDialecter{}, NewWhere(), true); err != nil || fragment != "" || len(args) != 0 {
+		t.Error("compile empty where error", fragment, args, err)
+	}
+	if _, _, err := CompileWhere(MysqlDialecter{}, nil, false); err == nil {
+		t.Error("compile nil where should fail")
+	}
+}
+
+func TestQuoteIdentifierEscape(t *testing.T) {
+	cases := []struct {
+		dialecter Dialecter
+		name      string
+		want      string
+	}{
+		{AnsiDialecter{}, `users"; DROP TABLE x; --`, `"users""; DROP TABLE x; --"`},
+		{PostgreSQLDialecter{}, `users"; DROP TABLE x; --`, `"users""; DROP TABLE x; --"`},
+		{OracleSQLDialecter{}, `users"; DROP TABLE x; --`, `"users""; DROP TABLE x; --"`},
+		{OracleSQLDialecter{}, `users`, `users`},
+		{MysqlDialecter{}, "users`; DROP TABLE x; --", "`users``; DROP TABLE x; --`"},
+		{ClickHouseDialecter{}, "users`; DROP TABLE x; --", "`users``; DROP TABLE x; --`"},
+		{MssqlDialecter{}, `users]; DROP TABLE x; --`, `[users]]; DROP TABLE x; --]`},
+		{PostgreSQLDialecter{}, `dbo.sp"x`, `"dbo"."sp""x"`},
+	}
+
+	for _, c := range cases {
+		actual, ok := quoteIdentifier(c.dialecter, c.name)
+		if !ok || actual != c.want {
+			t.Errorf("quote identifier error, dialecter=%s; want=[%v]; actual=[%v]", c.dialecter.Name(), c.want, actual)
+		}
+	}
+}
+
+// baseDialecter only has methods of Dialecter, optional interfaces of the embedded dialecter aren't promoted
+type baseDialecter struct {
+	Dialecter
+}
+
+func TestOptionalDialecter(t *testing.T) {
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint")
+	q.Where.NullSafeEquals("cstring", "a")
+	q.UseOrderBy().Asc("cint")
+	q.Limit(20, 10)
+
+	sc := NewStmtCompiler(baseDialecter{MysqlDialecter{}})
+	formatedSql, _, err := sc.Compile(q, "source")
+	t.Log(formatedSql)
+	if err != nil {
+		t.Fatal("compile with base dialecter error", err)
+	}
+	want := `SELECT cint FROM ttable WHERE cstring IS NOT DISTINCT FROM ? ORDER BY cint ASC OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("base dialecter should fallback to ansi", "\n", formatedSql, "\n", want)
+	}
+
+	if _, ok := Dialecter(baseDialecter{PostgreSQLDialecter{}}).(ArrayParameterSupporter); ok {
+		t.Error("base dialecter should not implement optional interface")
+	}
+	if dialectSupportArrayParameter(baseDialecter{PostgreSQLDialecter{}}) || !dialectSupportArrayParameter(PostgreSQLDialecter{}) {
+		t.Error("support array parameter should fallback to ansi")
+	}
+	if s := dialectKeySchema(baseDialecter{MysqlDialecter{}}).PrimaryKeySql("ttable"); s != "" {
+		t.Error("primary key sql should fallback to ansi", s)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/sdming/kdb/ansi"
+	"sort"
 	"strings"
 )
 
@@ -50,8 +51,10 @@ func (sd SortDir) ToSql() string {
 }
 
 const (
-	Asc  SortDir = ansi.Asc
-	Desc SortDir = ansi.Desc
+	// SortDefault means direction is unset, it's compiled as ASC
+	SortDefault SortDir = ""
+	Asc         SortDir = ansi.Asc
+	Desc        SortDir = ansi.Desc
 )
 
 // JoinType is type of sql table join
@@ -72,7 +75,52 @@ const (
 	InnerJoin JoinType = ansi.InnerJoin
 	LeftJoin  JoinType = ansi.LeftJoin
 	RightJoin JoinType = ansi.RightJoin
-	//FullJoin  JoinType = ansi.FullJoin
+	FullJoin  JoinType = ansi.FullJoin
+)
+
+// IsValid return true if j is cross, inner, left, right or full join
+func (j JoinType) IsValid() bool {
+	switch j {
+	case CrossJoin, InnerJoin, LeftJoin, RightJoin, FullJoin:
+		return true
+	}
+	return false
+}
+
+// IndexHintType is type of mysql index hint, like USE INDEX
+type IndexHintType string
+
+// String
+func (iht IndexHintType) String() string {
+	return string(iht)
+}
+
+const (
+	UseIndex    IndexHintType = ansi.UseIndex
+	ForceIndex  IndexHintType = ansi.ForceIndex
+	IgnoreIndex IndexHintType = ansi.IgnoreIndex
+)
+
+// IsValid return true if iht is use, force or ignore index
+func (iht IndexHintType) IsValid() bool {
+	switch iht {
+	case UseIndex, ForceIndex, IgnoreIndex:
+		return true
+	}
+	return false
+}
+
+// GroupingSet is grouping mode of group by, like ROLLUP, CUBE
+type GroupingSet string
+
+// String
+func (gs GroupingSet) String() string {
+	return string(gs)
+}
+
+const (
+	Rollup GroupingSet = ansi.Rollup
+	Cube   GroupingSet = ansi.Cube
 )
 
 // Func is sql function
@@ -94,9 +142,48 @@ const (
 	Avg         Func = ansi.Avg
 	Min         Func = ansi.Min
 	Max         Func = ansi.Max
+	RowNumber   Func = ansi.RowNumber
+	Rank        Func = ansi.Rank
+	DenseRank   Func = ansi.DenseRank
 	CurrentTime Func = "currenttime"
+
+	Coalesce Func = ansi.Coalesce
+	Lower    Func = ansi.Lower
+	Upper    Func = ansi.Upper
+	Concat   Func = ansi.Concat
 )
 
+// FuncCall is call of scalar function, like COALESCE(a, ?)
+type FuncCall struct {
+	Name Func
+	Args []Expression
+}
+
+// String
+func (fc *FuncCall) String() string {
+	if fc == nil {
+		return _nilStr
+	}
+	return fmt.Sprintf("%v%v", fc.Name, fc.Args)
+}
+
+// Node return NodeFunc
+func (fc *FuncCall) Node() NodeType {
+	return NodeFunc
+}
+
+// NewFuncCall return *FuncCall, args are Expression or value
+func NewFuncCall(name Func, args ...interface{}) *FuncCall {
+	fc := &FuncCall{
+		Name: name,
+		Args: make([]Expression, len(args)),
+	}
+	for i := 0; i < len(args); i++ {
+		fc.Args[i] = asExpression(args[i])
+	}
+	return fc
+}
+
 // Operator is operator in sql
 type Operator string
 
@@ -124,6 +211,7 @@ const (
 	GreaterOrEquals  Operator = ansi.GreaterOrEquals
 	Equals           Operator = ansi.Equals
 	NotEquals        Operator = ansi.NotEquals
+	NullSafeEqual    Operator = ansi.NotDistinctFrom
 	Like             Operator = ansi.Like
 	NotLike          Operator = ansi.NotLike
 	In               Operator = ansi.In
@@ -150,17 +238,26 @@ const (
 	NodeQuery     NodeType = 4
 	NodeUpdate    NodeType = 5
 	NodeDelete    NodeType = 6
+	NodeDropTable NodeType = 7
+	NodeTruncate  NodeType = 8
 
 	NodeNull  NodeType = 11
 	NodeValue NodeType = 12
 	NodeSql   NodeType = 13
 
+	NodeValuesList NodeType = 14
+	NodeRaw        NodeType = 15
+
 	NodeTable     NodeType = 31
 	NodeColumn    NodeType = 32
 	NodeAlias     NodeType = 33
 	NodeCondition NodeType = 34
 	NodeSet       NodeType = 35
 	NodeAggregate NodeType = 36
+	NodeWindow    NodeType = 37
+	NodeArith     NodeType = 38
+	NodeCast      NodeType = 39
+	NodeCollate   NodeType = 40
 
 	NodeSelect  NodeType = 41
 	NodeFrom    NodeType = 42
@@ -170,6 +267,8 @@ const (
 	NodeHaving  NodeType = 46
 	NodeOrderBy NodeType = 47
 	NodeOutput  NodeType = 48
+	NodeWith    NodeType = 49
+	NodeCase    NodeType = 50
 
 	NodeOperator  = 61
 	NodeFunc      = 62
@@ -193,12 +292,20 @@ func (n NodeType) String() string {
 		return "Update"
 	case NodeDelete:
 		return "Delete"
+	case NodeDropTable:
+		return "DropTable"
+	case NodeTruncate:
+		return "Truncate"
 	case NodeNull:
 		return "Null"
 	case NodeValue:
 		return "Value"
 	case NodeSql:
 		return "Sql"
+	case NodeValuesList:
+		return "ValuesList"
+	case NodeRaw:
+		return "Raw"
 	case NodeTable:
 		return "Table"
 	case NodeColumn:
@@ -211,6 +318,14 @@ func (n NodeType) String() string {
 		return "Set"
 	case NodeAggregate:
 		return "Aggregate"
+	case NodeWindow:
+		return "Window"
+	case NodeArith:
+		return "Arith"
+	case NodeCast:
+		return "Cast"
+	case NodeCollate:
+		return "Collate"
 	case NodeSelect:
 		return "Select"
 	case NodeFrom:
@@ -227,6 +342,10 @@ func (n NodeType) String() string {
 		return "OrderBy"
 	case NodeOutput:
 		return "Output "
+	case NodeWith:
+		return "With"
+	case NodeCase:
+		return "Case"
 	case NodeOperator:
 		return "Operator"
 	case NodeFunc:
@@ -275,6 +394,46 @@ func (s Sql) Node() NodeType {
 	return NodeSql
 }
 
+// RawSql is native sql fragment with arguments, ? in Sql is placeholder of Args
+type RawSql struct {
+	Sql  string
+	Args []interface{}
+}
+
+// Raw return *RawSql, ? in sql is replaced by placeholder of dialect, args are appended in order
+func Raw(sql string, args ...interface{}) *RawSql {
+	return &RawSql{
+		Sql:  sql,
+		Args: args,
+	}
+}
+
+// String
+func (r *RawSql) String() string {
+	if r == nil {
+		return _nilStr
+	}
+	return r.Sql
+}
+
+// Node return NodeRaw
+func (r *RawSql) Node() NodeType {
+	return NodeRaw
+}
+
+// AliasRef is reference to alias of select field, like ORDER BY total
+type AliasRef string
+
+// String
+func (a AliasRef) String() string {
+	return string(a)
+}
+
+// Node return NodeAlias
+func (a AliasRef) Node() NodeType {
+	return NodeAlias
+}
+
 // Column is an column, like, table.coumn, column, table.*, *
 type Column string
 
@@ -301,6 +460,31 @@ func (c Column) Node() NodeType {
 	return NodeColumn
 }
 
+// Wildcard is *, like COUNT(*)
+const Wildcard Column = ansi.WildcardAll
+
+// TableColumn is column qualified with table name or alias, compiled as quoted table.column
+type TableColumn struct {
+	Table string
+	Name  string
+}
+
+// String
+func (c *TableColumn) String() string {
+	if c == nil {
+		return _nilStr
+	}
+	if c.Table == "" {
+		return c.Name
+	}
+	return c.Table + "." + c.Name
+}
+
+// Node return NodeColumn
+func (c *TableColumn) Node() NodeType {
+	return NodeColumn
+}
+
 // Value is raw value
 type Value struct {
 	// Value is embed value
@@ -320,6 +504,39 @@ func (v *Value) Node() NodeType {
 	return NodeValue
 }
 
+// ValuesList is row source of literal values, like VALUES (?, ?), (?, ?)
+type ValuesList struct {
+	Rows [][]Expression
+}
+
+// String
+func (vl *ValuesList) String() string {
+	if vl == nil {
+		return _nilStr
+	}
+	return fmt.Sprint(ansi.Values, " ", vl.Rows)
+}
+
+// Node return NodeValuesList
+func (vl *ValuesList) Node() NodeType {
+	return NodeValuesList
+}
+
+// Row append a row, values can be Expression or value
+func (vl *ValuesList) Row(values ...interface{}) *ValuesList {
+	row := make([]Expression, len(values))
+	for i := 0; i < len(values); i++ {
+		row[i] = asExpression(values[i])
+	}
+	vl.Rows = append(vl.Rows, row)
+	return vl
+}
+
+// NewValuesList return *ValuesList
+func NewValuesList() *ValuesList {
+	return &ValuesList{}
+}
+
 // Set is set clause in update or insert
 type Set struct {
 	Column Column
@@ -374,7 +591,7 @@ func (c *Condition) Node() NodeType {
 	return NodeCondition
 }
 
-// Conditions is collection of condition
+// Conditions is collection of condition, conditions are connected by And or Or, AND is used if there is no logic operator between two conditions
 type Conditions struct {
 	Conditions        []Expression
 	needLogicOperator bool
@@ -399,16 +616,21 @@ func (c *Conditions) String() string {
 
 	for i := 0; i < len(c.Conditions); i++ {
 		item := c.Conditions[i]
-		if i > 0 {
-			buf.WriteString("\n")
-		}
-
-		if item == CloseParentheses {
+		if item == CloseParentheses && deep > 0 {
 			deep--
 		}
 
-		if deep > 0 {
-			buf.WriteString(strings.Repeat("\t", deep))
+		if item == OpenParentheses {
+			if i > 0 {
+				buf.WriteString(" ")
+			}
+		} else {
+			if i > 0 {
+				buf.WriteString("\n")
+			}
+			if deep > 0 {
+				buf.WriteString(strings.Repeat("\t", deep))
+			}
 		}
 
 		buf.WriteString(fmt.Sprint(item))
@@ -477,18 +699,62 @@ func (c *Conditions) CloseParentheses() *Conditions {
 	return c
 }
 
+// Raw append native sql fragment with arguments
+func (c *Conditions) Raw(sql string, args ...interface{}) *Conditions {
+	c.set(Raw(sql, args...))
+	return c
+}
+
+// Group append conditions built by fn in parentheses, parentheses are always balanced, skip if group is empty
+func (c *Conditions) Group(fn func(g *Conditions)) *Conditions {
+	if fn == nil {
+		return c
+	}
+
+	g := &Conditions{}
+	fn(g)
+	if g.isEmpty() {
+		return c
+	}
+
+	c.OpenParentheses()
+	c.Conditions = append(c.Conditions, g.Conditions...)
+	return c.CloseParentheses()
+}
+
+// isBalanced return true if parentheses in conditions are balanced
+func (c *Conditions) isBalanced() bool {
+	if c == nil {
+		return true
+	}
+
+	deep := 0
+	for i := 0; i < len(c.Conditions); i++ {
+		switch c.Conditions[i] {
+		case OpenParentheses:
+			deep++
+		case CloseParentheses:
+			deep--
+			if deep < 0 {
+				return false
+			}
+		}
+	}
+	return deep == 0
+}
+
 // Sql append raw sql
 func (c *Conditions) Sql(sqlStr string) *Conditions {
 	c.set(Sql(sqlStr))
 	return c
 }
 
-// Exists append operation Exists
+// Exists append operation Exists, exp should be a *Query or Sql
 func (c *Conditions) Exists(exp Expression) *Conditions {
 	return c.Condition(Exists, nil, exp)
 }
 
-// NotExists append operation NotExists
+// NotExists append operation NotExists, exp should be a *Query or Sql
 func (c *Conditions) NotExists(exp Expression) *Conditions {
 	return c.Condition(NotExists, nil, exp)
 }
@@ -498,6 +764,31 @@ func (c *Conditions) Compare(op Operator, column string, value interface{}) *Con
 	return c.Condition(op, Column(column), asExpression(value))
 }
 
+// CompareColumn append compare operation between two columns, like a.x = b.y, no parameter is used
+func (c *Conditions) CompareColumn(op Operator, leftColumn, rightColumn string) *Conditions {
+	return c.Condition(op, Column(leftColumn), Column(rightColumn))
+}
+
+// CompareCollate append compare operation, column is compared with collation
+func (c *Conditions) CompareCollate(op Operator, column string, value interface{}, collation string) *Conditions {
+	return c.Condition(op, NewCollate(Column(column), collation), asExpression(value))
+}
+
+// NullSafeEquals append null-safe equality, NULL equals NULL, like a IS NOT DISTINCT FROM ? or a <=> ? of mysql
+func (c *Conditions) NullSafeEquals(column string, value interface{}) *Conditions {
+	return c.Condition(NullSafeEqual, Column(column), asExpression(value))
+}
+
+// NullSafeEqualsColumn append null-safe equality between two columns
+func (c *Conditions) NullSafeEqualsColumn(leftColumn, rightColumn string) *Conditions {
+	return c.CompareColumn(NullSafeEqual, leftColumn, rightColumn)
+}
+
+// EqualsColumn append = operation between two columns
+func (c *Conditions) EqualsColumn(leftColumn, rightColumn string) *Conditions {
+	return c.CompareColumn(Equals, leftColumn, rightColumn)
+}
+
 // Like append Like operation
 func (c *Conditions) Like(column string, value string) *Conditions {
 	//return c.Condition(Like, Column(column), Sql(value))
@@ -535,6 +826,24 @@ func (c *Conditions) Equals(column string, value interface{}) *Conditions {
 	return c.Condition(Equals, Column(column), asExpression(value))
 }
 
+// EqualsMap append column = value of each item of m, columns are sorted by name, nil value append IS NULL
+func (c *Conditions) EqualsMap(m map[string]interface{}) *Conditions {
+	columns := make([]string, 0, len(m))
+	for column := range m {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	for _, column := range columns {
+		if v := m[column]; v == nil {
+			c.IsNull(column)
+		} else {
+			c.Equals(column, v)
+		}
+	}
+	return c
+}
+
 // NotEquals append <> operation
 func (c *Conditions) NotEquals(column string, value interface{}) *Conditions {
 	return c.Condition(NotEquals, Column(column), asExpression(value))
@@ -570,6 +879,9 @@ func newConditions() *Conditions {
 type Aggregate struct {
 	Name Func
 	Exp  Expression
+
+	// Filter is condition of rows to aggregate, like SUM(x) FILTER (WHERE ...)
+	Filter *Conditions
 }
 
 // String
@@ -577,6 +889,9 @@ func (a *Aggregate) String() string {
 	if a == nil {
 		return _nilStr
 	}
+	if !a.Filter.isEmpty() {
+		return fmt.Sprintf("%v (%v) FILTER (WHERE %v)", a.Name, a.Exp, a.Filter)
+	}
 	return fmt.Sprintf("%v (%v)", a.Name, a.Exp)
 }
 
@@ -593,6 +908,254 @@ func NewAggregate(name Func, exp Expression) *Aggregate {
 	}
 }
 
+// Where set filter of aggregate, conditions are built by fn
+func (a *Aggregate) Where(fn func(c *Conditions)) *Aggregate {
+	if fn == nil {
+		return a
+	}
+
+	c := newConditions()
+	fn(c)
+	a.Filter = c
+	return a
+}
+
+// Window is sql window function, like func(exp) OVER (PARTITION BY ... ORDER BY ...)
+type Window struct {
+	Name       Func
+	Exp        Expression
+	Partitions []Expression
+	OrderBy    *OrderBy
+}
+
+// String
+func (w *Window) String() string {
+	if w == nil {
+		return _nilStr
+	}
+
+	buf := bytes.Buffer{}
+	if len(w.Partitions) > 0 {
+		buf.WriteString(fmt.Sprint(ansi.PartitionBy, " ", w.Partitions))
+	}
+	if w.OrderBy != nil && !w.OrderBy.isEmpty() {
+		if buf.Len() > 0 {
+			buf.WriteString(" ")
+		}
+		buf.WriteString(w.OrderBy.String())
+	}
+	if w.Exp == nil {
+		return fmt.Sprintf("%v () %s (%s)", w.Name, ansi.Over, buf.String())
+	}
+	return fmt.Sprintf("%v (%v) %s (%s)", w.Name, w.Exp, ansi.Over, buf.String())
+}
+
+// Node return NodeWindow
+func (w *Window) Node() NodeType {
+	return NodeWindow
+}
+
+// Partition append a partition by expression
+func (w *Window) Partition(exp Expression) *Window {
+	w.Partitions = append(w.Partitions, exp)
+	return w
+}
+
+// PartitionBy append partition by columns
+func (w *Window) PartitionBy(columns ...string) *Window {
+	for i := 0; i < len(columns); i++ {
+		w.Partition(Column(columns[i]))
+	}
+	return w
+}
+
+// UseOrderBy initialize w.OrderBy then return it
+func (w *Window) UseOrderBy() *OrderBy {
+	if w.OrderBy == nil {
+		w.OrderBy = NewOrderBy()
+	}
+	return w.OrderBy
+}
+
+// NewWindow return *Window, exp can be nil for functions like ROW_NUMBER()
+func NewWindow(name Func, exp Expression) *Window {
+	return &Window{
+		Name: name,
+		Exp:  exp,
+	}
+}
+
+// ArithOperator is arithmetic operator in sql
+type ArithOperator string
+
+// String
+func (op ArithOperator) String() string {
+	return string(op)
+}
+
+// precedence return precedence of operator, * / % bind tighter than + -
+func (op ArithOperator) precedence() int {
+	switch op {
+	case Multiply, Divide, Modulo:
+		return 2
+	}
+	return 1
+}
+
+const (
+	Plus     ArithOperator = ansi.Plus
+	Minus    ArithOperator = ansi.Minus
+	Multiply ArithOperator = ansi.Multiply
+	Divide   ArithOperator = ansi.Divide
+	Modulo   ArithOperator = ansi.Modulo
+)
+
+// Arith is binary arithmetic expression, like left + right
+type Arith struct {
+	Op    ArithOperator
+	Left  Expression
+	Right Expression
+}
+
+// String
+func (a *Arith) String() string {
+	if a == nil {
+		return _nilStr
+	}
+	return fmt.Sprintf("(%v %v %v)", a.Left, a.Op, a.Right)
+}
+
+// Node return NodeArith
+func (a *Arith) Node() NodeType {
+	return NodeArith
+}
+
+// NewArith return *Arith, left and right can be Expression or value
+func NewArith(op ArithOperator, left, right interface{}) *Arith {
+	return &Arith{
+		Op:    op,
+		Left:  asExpression(left),
+		Right: asExpression(right),
+	}
+}
+
+// Cast is type conversion, like CAST(exp AS INTEGER), postgres exp::INTEGER if Shorthand is true
+type Cast struct {
+	Exp       Expression
+	Type      ansi.DbType
+	Length    int
+	Precision int
+	Scale     int
+
+	// Shorthand use :: form of postgres, it is ignored if SupportCastShorthand of dialect return false
+	Shorthand bool
+}
+
+// String
+func (c *Cast) String() string {
+	if c == nil {
+		return _nilStr
+	}
+	return fmt.Sprintf("%s(%v %s %v)", ansi.Cast, c.Exp, ansi.As, c.Type)
+}
+
+// Node return NodeCast
+func (c *Cast) Node() NodeType {
+	return NodeCast
+}
+
+// NewCast return *Cast, exp can be Expression or value
+func NewCast(exp interface{}, t ansi.DbType) *Cast {
+	return &Cast{
+		Exp:  asExpression(exp),
+		Type: t,
+	}
+}
+
+// CaseWhen is a WHEN branch of CASE, like WHEN conditions THEN exp
+type CaseWhen struct {
+	Conditions *Conditions
+	Then       Expression
+}
+
+// Case is searched case expression, like CASE WHEN status = ? THEN 0 ELSE 1 END
+type Case struct {
+	Whens []*CaseWhen
+
+	// Else is optional value if none of whens match
+	Else Expression
+}
+
+// String
+func (c *Case) String() string {
+	if c == nil {
+		return _nilStr
+	}
+	buf := bytes.Buffer{}
+	buf.WriteString(ansi.Case)
+	for _, w := range c.Whens {
+		buf.WriteString(fmt.Sprint(" ", ansi.When, " ", w.Conditions, " ", ansi.Then, " ", w.Then))
+	}
+	if c.Else != nil {
+		buf.WriteString(fmt.Sprint(" ", ansi.Else, " ", c.Else))
+	}
+	buf.WriteString(" " + ansi.End)
+	return buf.String()
+}
+
+// Node return NodeCase
+func (c *Case) Node() NodeType {
+	return NodeCase
+}
+
+// When append a WHEN branch, fn build conditions of branch, then can be Expression or value
+func (c *Case) When(fn func(c *Conditions), then interface{}) *Case {
+	conditions := newConditions()
+	if fn != nil {
+		fn(conditions)
+	}
+	c.Whens = append(c.Whens, &CaseWhen{Conditions: conditions, Then: asExpression(then)})
+	return c
+}
+
+// Otherwise set Else, v can be Expression or value
+func (c *Case) Otherwise(v interface{}) *Case {
+	c.Else = asExpression(v)
+	return c
+}
+
+// NewCase return *Case
+func NewCase() *Case {
+	return &Case{}
+}
+
+// Collate is expression with collation, like name COLLATE "en_US"
+type Collate struct {
+	Exp       Expression
+	Collation string
+}
+
+// String
+func (c *Collate) String() string {
+	if c == nil {
+		return _nilStr
+	}
+	return fmt.Sprint(c.Exp, " ", ansi.Collate, " ", c.Collation)
+}
+
+// Node return NodeCollate
+func (c *Collate) Node() NodeType {
+	return NodeCollate
+}
+
+// NewCollate return *Collate, exp can be Expression or value
+func NewCollate(exp interface{}, collation string) *Collate {
+	return &Collate{
+		Exp:       asExpression(exp),
+		Collation: collation,
+	}
+}
+
 // Where is sql where clause
 type Where struct {
 	*Conditions
@@ -617,6 +1180,13 @@ func NewWhere() *Where {
 	return &Where{newConditions()}
 }
 
+// WhereMap return *Where of column = value of each item of m, conditions are joined by AND and sorted by column
+func WhereMap(m map[string]interface{}) *Where {
+	w := NewWhere()
+	w.EqualsMap(m)
+	return w
+}
+
 // Having is sql having clause
 type Having struct {
 	*Conditions
@@ -640,6 +1210,12 @@ func (h *Having) addAggregate(op Operator, name Func, column string, value Expre
 	h.Condition(op, NewAggregate(name, Column(column)), value)
 }
 
+// Aggregate append a aggregate condition, parameters of aggregate are bound before value, like COUNT(CASE WHEN c = ? THEN 1 END) > ?
+func (h *Having) Aggregate(op Operator, a *Aggregate, value interface{}) *Having {
+	h.Condition(op, a, asExpression(value))
+	return h
+}
+
 // Avg append avg(...)
 func (h *Having) Avg(op Operator, column string, value interface{}) *Having {
 	h.addAggregate(op, Avg, column, asExpression(value))
@@ -652,6 +1228,12 @@ func (h *Having) Count(op Operator, column string, value interface{}) *Having {
 	return h
 }
 
+// CountAll append count(*)
+func (h *Having) CountAll(op Operator, value interface{}) *Having {
+	h.Condition(op, NewAggregate(Count, Wildcard), asExpression(value))
+	return h
+}
+
 // Sum append sum(...)
 func (h *Having) Sum(op Operator, column string, value interface{}) *Having {
 	h.addAggregate(op, Sum, column, asExpression(value))
@@ -678,6 +1260,9 @@ func NewHaving() *Having {
 // GroupBy is sql group by clause
 type GroupBy struct {
 	Fields []Expression
+
+	// Grouping is ROLLUP or CUBE of fields, empty means plain group by
+	Grouping GroupingSet
 }
 
 // String
@@ -686,6 +1271,9 @@ func (g *GroupBy) String() string {
 		return _nilStr
 	}
 
+	if g.Grouping != "" {
+		return fmt.Sprint(ansi.GroupBy, " ", g.Grouping, g.Fields)
+	}
 	return fmt.Sprint(ansi.GroupBy, " ", g.Fields)
 }
 
@@ -715,6 +1303,18 @@ func (g *GroupBy) Column(columns ...string) *GroupBy {
 	return g
 }
 
+// Rollup group by ROLLUP of fields, mysql write WITH ROLLUP
+func (g *GroupBy) Rollup() *GroupBy {
+	g.Grouping = Rollup
+	return g
+}
+
+// Cube group by CUBE of fields
+func (g *GroupBy) Cube() *GroupBy {
+	g.Grouping = Cube
+	return g
+}
+
 // NewGroupBy return  *GroupBy
 func NewGroupBy() *GroupBy {
 	return &GroupBy{Fields: make([]Expression, 0, _defaultCapicity)}
@@ -724,6 +1324,23 @@ func NewGroupBy() *GroupBy {
 type Table struct {
 	Name  string
 	Alias string
+
+	// Schema and Catalog qualify Name, like schema.table or catalog.schema.table.
+	// query writes the name as given like Name, insert/update/delete quote each part
+	Schema  string
+	Catalog string
+
+	// Sample is sampling of table, like TABLESAMPLE SYSTEM (10)
+	Sample *TableSample
+
+	// Hints is index hints of table, like USE INDEX (idx), only mysql support it
+	Hints []*IndexHint
+
+	// Columns is column alias list of derived table, like AS t(c1, c2)
+	Columns []string
+
+	// Source is subquery or values list of derived table, Name is ignored if Source isn't nil
+	Source Expression
 }
 
 // String
@@ -732,10 +1349,23 @@ func (t *Table) String() string {
 		return _nilStr
 	}
 
+	name := t.qualifiedName()
+	if t.Source != nil {
+		name = fmt.Sprint("(", t.Source, ")")
+	}
 	if t.Alias == "" {
-		return t.Name
+		return name
+	}
+	if len(t.Columns) > 0 {
+		return fmt.Sprint(name, " AS ", t.Alias, " (", strings.Join(t.Columns, ", "), ")")
 	}
-	return fmt.Sprint(t.Name, " AS ", t.Alias)
+	return fmt.Sprint(name, " AS ", t.Alias)
+}
+
+// AliasColumns set column alias list of t, like AS t(c1, c2)
+func (t *Table) AliasColumns(columns ...string) *Table {
+	t.Columns = columns
+	return t
 }
 
 // Node return NodeTable
@@ -743,6 +1373,82 @@ func (t *Table) Node() NodeType {
 	return NodeTable
 }
 
+// TableSample set sampling of t, method is SYSTEM or BERNOULLI, percent is percentage of rows
+func (t *Table) TableSample(method string, percent float64) *Table {
+	t.Sample = &TableSample{Method: method, Percent: percent}
+	return t
+}
+
+// UseIndex append USE INDEX (indexes) hint
+func (t *Table) UseIndex(indexes ...string) *Table {
+	return t.IndexHint(UseIndex, indexes...)
+}
+
+// ForceIndex append FORCE INDEX (indexes) hint
+func (t *Table) ForceIndex(indexes ...string) *Table {
+	return t.IndexHint(ForceIndex, indexes...)
+}
+
+// IndexHint append a index hint
+func (t *Table) IndexHint(hint IndexHintType, indexes ...string) *Table {
+	t.Hints = append(t.Hints, &IndexHint{Type: hint, Indexes: indexes})
+	return t
+}
+
+// InSchema set schema of t, mysql use schema as database name
+func (t *Table) InSchema(schema string) *Table {
+	t.Schema = schema
+	return t
+}
+
+// InCatalog set catalog and schema of t
+func (t *Table) InCatalog(catalog, schema string) *Table {
+	t.Catalog = catalog
+	t.Schema = schema
+	return t
+}
+
+// isQualified return true if t has schema or catalog
+func (t *Table) isQualified() bool {
+	return t.Schema != "" || t.Catalog != ""
+}
+
+// qualifiedName return catalog.schema.name, empty parts are skipped
+func (t *Table) qualifiedName() string {
+	if !t.isQualified() {
+		return t.Name
+	}
+
+	parts := make([]string, 0, 3)
+	for _, part := range []string{t.Catalog, t.Schema, t.Name} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ansi.Split)
+}
+
+// Column return column qualified with alias of t, or name of t if alias is empty
+func (t *Table) Column(name string) *TableColumn {
+	table := t.Alias
+	if table == "" {
+		table = t.Name
+	}
+	return &TableColumn{
+		Table: table,
+		Name:  name,
+	}
+}
+
+// NewDerivedTable return *Table of subquery or values list, like (VALUES ...) AS alias (c1, c2)
+func NewDerivedTable(source Expression, alias string, columns ...string) *Table {
+	return &Table{
+		Alias:   alias,
+		Columns: columns,
+		Source:  source,
+	}
+}
+
 func newTable(name string, alias string) *Table {
 	return &Table{
 		Name:  name,
@@ -750,6 +1456,37 @@ func newTable(name string, alias string) *Table {
 	}
 }
 
+// IndexHint is mysql index hint, like USE INDEX (idx1, idx2)
+type IndexHint struct {
+	Type    IndexHintType
+	Indexes []string
+}
+
+// String
+func (ih *IndexHint) String() string {
+	if ih == nil {
+		return _nilStr
+	}
+	return fmt.Sprint(ih.Type, " (", strings.Join(ih.Indexes, ", "), ")")
+}
+
+// TableSample is sampling of table, like TABLESAMPLE SYSTEM (10)
+type TableSample struct {
+	// Method is SYSTEM or BERNOULLI
+	Method string
+
+	// Percent is percentage of rows to sample, should be in (0, 100]
+	Percent float64
+}
+
+// String
+func (ts *TableSample) String() string {
+	if ts == nil {
+		return _nilStr
+	}
+	return fmt.Sprint(ansi.TableSample, " ", ts.Method, " (", ts.Percent, ")")
+}
+
 // Field is each field in sql select clause
 type Field struct {
 	Exp   Expression
@@ -801,7 +1538,7 @@ func NewSelect() *Select {
 
 func (s *Select) addField(exp Expression, alias string) *Select {
 	if s.Fields == nil {
-		s.Fields = make([]*Field, 0, 5)
+		s.Fields = make([]*Field, 0, _defaultCapicity)
 	}
 	s.Fields = append(s.Fields, &Field{
 		Exp:   exp,
@@ -821,6 +1558,14 @@ func (s *Select) Column(columns ...string) *Select {
 	return s
 }
 
+// TableColumn append columns qualified with table t to select list
+func (s *Select) TableColumn(t *Table, columns ...string) *Select {
+	for i := 0; i < len(columns); i++ {
+		s.addField(t.Column(columns[i]), "")
+	}
+	return s
+}
+
 // ColumnAs append [column] as [alias] to select list
 func (s *Select) ColumnAs(column, alias string) *Select {
 	return s.addField(Column(column), alias)
@@ -841,6 +1586,11 @@ func (s *Select) Aggregate(name Func, exp Expression, alias string) *Select {
 	return s.addField(NewAggregate(name, exp), alias)
 }
 
+// Window append a window function
+func (s *Select) Window(w *Window, alias string) *Select {
+	return s.addField(w, alias)
+}
+
 // Avg append avg(...) 
 func (s *Select) Avg(column string, alias string) *Select {
 	return s.Aggregate(Avg, Column(column), alias)
@@ -851,6 +1601,11 @@ func (s *Select) Count(column string, alias string) *Select {
 	return s.Aggregate(Count, Column(column), alias)
 }
 
+// CountAll append count(*)
+func (s *Select) CountAll(alias string) *Select {
+	return s.Aggregate(Count, Wildcard, alias)
+}
+
 // Sum append sum(...) 
 func (s *Select) Sum(column string, alias string) *Select {
 	return s.Aggregate(Sum, Column(column), alias)
@@ -870,6 +1625,9 @@ func (s *Select) Max(column string, alias string) *Select {
 type OrderByField struct {
 	Exp       Expression
 	Direction SortDir
+
+	// Collation is optional collation of Exp
+	Collation string
 }
 
 // String
@@ -878,6 +1636,9 @@ func (oi *OrderByField) String() string {
 		return _nilStr
 	}
 
+	if oi.Collation != "" {
+		return fmt.Sprint(oi.Exp, " ", ansi.Collate, " ", oi.Collation, " ", oi.Direction)
+	}
 	return fmt.Sprint(oi.Exp, " ", oi.Direction)
 }
 
@@ -926,6 +1687,14 @@ func (od *OrderBy) By(direction SortDir, exp Expression) *OrderBy {
 	return od
 }
 
+// Column append a column to order by without direction, it's compiled as ASC
+func (od *OrderBy) Column(columns ...string) *OrderBy {
+	for i := 0; i < len(columns); i++ {
+		od.By(SortDefault, Column(columns[i]))
+	}
+	return od
+}
+
 // Asc append a column to order by as asc
 func (od *OrderBy) Asc(columns ...string) *OrderBy {
 	for i := 0; i < len(columns); i++ {
@@ -942,6 +1711,29 @@ func (od *OrderBy) Desc(columns ...string) *OrderBy {
 	return od
 }
 
+// ByCollate append a orderby field with direction and collation
+func (od *OrderBy) ByCollate(direction SortDir, exp Expression, collation string) *OrderBy {
+	od.By(direction, exp)
+	od.Fields[len(od.Fields)-1].Collation = collation
+	return od
+}
+
+// AscAlias append alias of select field to order by as asc
+func (od *OrderBy) AscAlias(aliases ...string) *OrderBy {
+	for i := 0; i < len(aliases); i++ {
+		od.By(Asc, AliasRef(aliases[i]))
+	}
+	return od
+}
+
+// DescAlias append alias of select field to order by as desc
+func (od *OrderBy) DescAlias(aliases ...string) *OrderBy {
+	for i := 0; i < len(aliases); i++ {
+		od.By(Desc, AliasRef(aliases[i]))
+	}
+	return od
+}
+
 // NewOrderBy return  *OrderBy
 func NewOrderBy() *OrderBy {
 	return &OrderBy{Fields: make([]*OrderByField, 0, _defaultCapicity)}
@@ -960,6 +1752,10 @@ func (f *From) String() string {
 		return _nilStr
 	}
 
+	if f.isEmpty() {
+		return ""
+	}
+
 	var buf bytes.Buffer
 	buf.WriteString(ansi.From)
 	buf.WriteString(" ")
@@ -984,6 +1780,15 @@ func (f *From) Node() NodeType {
 	return NodeFrom
 }
 
+// isEmpty return true if from has no table, like SELECT 1
+func (f *From) isEmpty() bool {
+	if f == nil {
+		return true
+	}
+	t := f.Table
+	return (t == nil || (t.Name == "" && t.Alias == "" && t.Source == nil)) && len(f.Tables) == 0 && len(f.Joins) == 0
+}
+
 // NewFrom return *From
 func NewFrom(table, alias string) *From {
 	return &From{
@@ -1064,15 +1869,58 @@ func (f *From) RightJoin(toTable, toTableAlias string) *Join {
 	return f.addJoin(RightJoin, toTable, toTableAlias)
 }
 
-// func (f *From) FullJoin(toTable, toTableAlias string) *Join {
-// 	return f.addJoin(FullJoin, toTable, toTableAlias)
-// }
+// Join append full join to *From
+func (f *From) FullJoin(toTable, toTableAlias string) *Join {
+	return f.addJoin(FullJoin, toTable, toTableAlias)
+}
+
+// JoinOn append join of table with on conditions built by fn, return *From so joins can be chained
+func (f *From) JoinOn(joinType JoinType, toTable, toTableAlias string, fn func(c *Conditions)) *From {
+	f.addJoin(joinType, toTable, toTableAlias).OnConditions(fn)
+	return f
+}
+
+// InnerJoinOn append inner join on leftColumn = rightColumn, return *From so joins can be chained
+func (f *From) InnerJoinOn(toTable, toTableAlias, leftColumn, rightColumn string) *From {
+	f.InnerJoin(toTable, toTableAlias).On(leftColumn, rightColumn)
+	return f
+}
+
+// LeftJoinOn append left join on leftColumn = rightColumn, return *From so joins can be chained
+func (f *From) LeftJoinOn(toTable, toTableAlias, leftColumn, rightColumn string) *From {
+	f.LeftJoin(toTable, toTableAlias).On(leftColumn, rightColumn)
+	return f
+}
+
+// RightJoinOn append right join on leftColumn = rightColumn, return *From so joins can be chained
+func (f *From) RightJoinOn(toTable, toTableAlias, leftColumn, rightColumn string) *From {
+	f.RightJoin(toTable, toTableAlias).On(leftColumn, rightColumn)
+	return f
+}
+
+// FullJoinOn append full join on leftColumn = rightColumn, return *From so joins can be chained
+func (f *From) FullJoinOn(toTable, toTableAlias, leftColumn, rightColumn string) *From {
+	f.FullJoin(toTable, toTableAlias).On(leftColumn, rightColumn)
+	return f
+}
+
+// LateralJoin append lateral join of subquery to *From, subquery can reference columns of preceding tables
+func (f *From) LateralJoin(joinType JoinType, query *Query, alias string) *Join {
+	j := NewJoinTable(joinType, f.Table, NewDerivedTable(query, alias))
+	j.Lateral = true
+	f.Join(j)
+	return j
+}
 
 // Join is sql join clause
 type Join struct {
 	JoinType JoinType
 	Left     *Table
 	Right    *Table
+	Using    []string
+
+	// Lateral means right is a subquery that can reference columns of preceding tables, dialect should implement LateralSupporter
+	Lateral bool
 	*Conditions
 }
 
@@ -1092,7 +1940,14 @@ func (j *Join) String() string {
 			buf.WriteString(fmt.Sprint(item))
 		}
 	}
-	return fmt.Sprint(ansi.Join, " ", j.Left, " ", j.JoinType, " ", j.Right, " on (", buf.String(), ")")
+	joinType := j.JoinType.String()
+	if j.Lateral {
+		joinType += " " + ansi.Lateral
+	}
+	if len(j.Using) > 0 {
+		return fmt.Sprint(ansi.Join, " ", j.Left, " ", joinType, " ", j.Right, " using (", strings.Join(j.Using, ", "), ")")
+	}
+	return fmt.Sprint(ansi.Join, " ", j.Left, " ", joinType, " ", j.Right, " on (", buf.String(), ")")
 
 }
 
@@ -1101,15 +1956,35 @@ func (j *Join) Node() NodeType {
 	return NodeJoin
 }
 
-// On means on leftColumn = rightColumn
-func (j *Join) On(leftColumn, rightColumn string) {
+// On means on leftColumn = rightColumn, conditions of multiple calls are joined by AND
+func (j *Join) On(leftColumn, rightColumn string) *Join {
 	j.Condition(Equals, Column(leftColumn), Column(rightColumn))
+	return j
 }
 
 // On means on leftColumn1 = rightColumn1 and leftColumn2 = rightColumn2
-func (j *Join) On2(leftColumn1, rightColumn1, leftColumn2, rightColumn2 string) {
+func (j *Join) On2(leftColumn1, rightColumn1, leftColumn2, rightColumn2 string) *Join {
 	j.Condition(Equals, Column(leftColumn1), Column(rightColumn1))
 	j.Condition(Equals, Column(leftColumn2), Column(rightColumn2))
+	return j
+}
+
+// OnConditions build on conditions by fn, like on a.id = b.aid and b.status = ?
+func (j *Join) OnConditions(fn func(c *Conditions)) *Join {
+	if fn == nil {
+		return j
+	}
+	if j.Conditions == nil {
+		j.Conditions = newConditions()
+	}
+	fn(j.Conditions)
+	return j
+}
+
+// UsingColumns means using (column1, column2, ...)
+func (j *Join) UsingColumns(columns ...string) *Join {
+	j.Using = append(j.Using, columns...)
+	return j
 }
 
 // NewJoin means [left] as [leftAlias] join [right] as [rightAlias]
@@ -1131,3 +2006,73 @@ func NewJoinTable(joinType JoinType, left, right *Table) *Join {
 		Conditions: newConditions(),
 	}
 }
+
+// Cte is common table expression in sql with clause
+type Cte struct {
+	Name    string
+	Columns []string
+	Query   *Query
+}
+
+// String
+func (c *Cte) String() string {
+	if c == nil {
+		return _nilStr
+	}
+
+	if len(c.Columns) == 0 {
+		return fmt.Sprint(c.Name, " ", ansi.As, " (", c.Query, ")")
+	}
+	return fmt.Sprint(c.Name, " (", strings.Join(c.Columns, ", "), ") ", ansi.As, " (", c.Query, ")")
+}
+
+// With is sql with clause
+type With struct {
+	Recursive bool
+	Ctes      []*Cte
+}
+
+// String
+func (w *With) String() string {
+	if w == nil {
+		return _nilStr
+	}
+
+	buf := bytes.Buffer{}
+	buf.WriteString(ansi.With)
+	if w.Recursive {
+		buf.WriteString(" ")
+		buf.WriteString(ansi.Recursive)
+	}
+	for i := 0; i < len(w.Ctes); i++ {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString(" ")
+		buf.WriteString(fmt.Sprint(w.Ctes[i]))
+	}
+	return buf.String()
+}
+
+// Node return NodeWith
+func (w *With) Node() NodeType {
+	return NodeWith
+}
+
+// As append a named subquery, means name (columns...) AS (query)
+func (w *With) As(name string, query *Query, columns ...string) *With {
+	if w.Ctes == nil {
+		w.Ctes = make([]*Cte, 0, _defaultCapicity)
+	}
+	w.Ctes = append(w.Ctes, &Cte{
+		Name:    name,
+		Columns: columns,
+		Query:   query,
+	})
+	return w
+}
+
+// NewWith return *With
+func NewWith() *With {
+	return &With{Ctes: make([]*Cte, 0, _defaultCapicity)}
+}
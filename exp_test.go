@@ -1,6 +1,10 @@
 package kdb
 
 import (
+	sqldriver "database/sql/driver"
+	"errors"
+	"github.com/sdming/kdb/ansi"
+	"reflect"
 	"regexp"
 	"strings"
 	"testing"
@@ -23,6 +27,9 @@ var dataTypeMap map[string]interface{} = map[string]interface{}{
 	"cguid":     "550e8400-e29b-41d4-a716-446655440000",
 }
 
+// dataTypeKeys is keys of dataTypeMap in the order of expected sql
+var dataTypeKeys []string = []string{"cbool", "cint", "cfloat", "cnumeric", "cstring", "cdate", "cdatetime", "cguid"}
+
 func orderby(od *OrderBy) {
 	od.Asc("cint", "cfloat")
 	od.Desc("cnumeric", "cstring")
@@ -130,7 +137,7 @@ func TestQuery(t *testing.T) {
 		Exp(Sql("cint - 1"), "exp_cint")
 
 	//q.From.ThenFrom("ttable_c", "t2")
-	q.From.CrossJoin("ttable_c", "t_c").Equals("t1.cint", Column("t_c.c_int"))
+	q.From.CrossJoin("ttable_c", "t_c")
 	q.From.InnerJoin("ttable_c", "t_i").On("t1.cstring", "t_i.c_string")
 	q.From.LeftJoin("ttable_c", "t_l").On("t1.cstring", "t_l.c_string")
 	q.From.RightJoin("ttable_c", "t_r").On2("t1.cstring", "t_r.c_string", "t1.cint", "t_r.c_int")
@@ -175,7 +182,7 @@ func TestQuery(t *testing.T) {
 	var want string = `
 SELECT DISTINCT cbool, t1.cint, cnumeric AS "a_cnumeric", t1.cstring AS "a_cstring", AVG(cint) AS "avg_cint", COUNT(t1.cint) AS "count_cint", SUM(cint) AS "sum_cint", MIN(t1.cint) AS "min_cint", MAX(cint) AS "max_cint", cint - 1 AS "exp_cint" 
 FROM ttable AS t1
-CROSS JOIN ttable_c AS t_c ON t1.cint = t_c.c_int 
+CROSS JOIN ttable_c AS t_c 
 INNER JOIN ttable_c AS t_i ON t1.cstring = t_i.c_string 
 LEFT JOIN ttable_c AS t_l ON t1.cstring = t_l.c_string 
 RIGHT JOIN ttable_c AS t_r ON t1.cstring = t_r.c_string  AND  t1.cint = t_r.c_int  
@@ -331,7 +338,7 @@ MIN(cint) <=  ?
 AND
 MAX(cint) >=  ?  
 ORDER BY cint ASC, cfloat ASC, cnumeric DESC, cstring DESC, cdatetime ASC 
-LIMIT 3,101 ;
+OFFSET 3 ROWS FETCH NEXT 101 ROWS ONLY ;
 `
 
 	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
@@ -339,6 +346,292 @@ LIMIT 3,101 ;
 	}
 }
 
+func TestJoin(t *testing.T) {
+	comiler, err := GetCompiler("ansi")
+	if err != nil {
+		t.Error("can not find ansi compiler", err)
+	}
+
+	joins := map[JoinType]string{
+		InnerJoin: "INNER JOIN",
+		LeftJoin:  "LEFT JOIN",
+		RightJoin: "RIGHT JOIN",
+		FullJoin:  "FULL JOIN",
+	}
+
+	for joinType, keyword := range joins {
+		q := NewQuery("ttable", "t1")
+		q.From.Join(NewJoinTable(joinType, q.From.Table, newTable("ttable_c", "t2"))).Joins[0].On("t1.cint", "t2.c_int")
+
+		formatedSql, args, err := comiler.Compile("source", q)
+		t.Log(formatedSql, args)
+		if err != nil {
+			t.Error("compile join error", joinType, err)
+		}
+
+		want := "SELECT * FROM ttable AS t1 " + keyword + " ttable_c AS t2 ON t1.cint = t2.c_int ;"
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+			t.Error("compiled join sql error", "\n", formatedSql, "\n", want)
+		}
+	}
+
+	q := NewQuery("ttable", "t1")
+	q.From.CrossJoin("ttable_c", "t2")
+	formatedSql, _, err := comiler.Compile("source", q)
+	if err != nil {
+		t.Error("compile cross join error", err)
+	}
+	want := "SELECT * FROM ttable AS t1 CROSS JOIN ttable_c AS t2 ;"
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled cross join sql error", "\n", formatedSql, "\n", want)
+	}
+
+	invalid := []*Query{
+		NewQuery("ttable", "t1"),
+		NewQuery("ttable", "t1"),
+		NewQuery("ttable", "t1"),
+	}
+	invalid[0].From.InnerJoin("ttable_c", "t2")
+	invalid[1].From.CrossJoin("ttable_c", "t2").On("t1.cint", "t2.c_int")
+	invalid[2].From.addJoin(JoinType("NATURAL JOIN"), "ttable_c", "t2").On("t1.cint", "t2.c_int")
+
+	for i := 0; i < len(invalid); i++ {
+		if _, _, err := comiler.Compile("source", invalid[i]); err == nil {
+			t.Error("compile join should return error", invalid[i])
+		}
+	}
+}
+
+func TestJoinBuilder(t *testing.T) {
+	comiler, err := GetCompiler("mysql")
+	if err != nil {
+		t.Fatal("can not find mysql compiler", err)
+	}
+
+	manual := NewQuery("ttable", "t1")
+	manual.Select.Column("t1.cint", "t2.cstring", "t3.cfloat")
+	inner := NewJoinTable(InnerJoin, manual.From.Table, newTable("ttable_c", "t2"))
+	inner.Condition(Equals, Column("t1.cint"), Column("t2.c_int"))
+	manual.From.Join(inner)
+	left := NewJoinTable(LeftJoin, manual.From.Table, newTable("ttable_d", "t3"))
+	left.Condition(Equals, Column("t2.cint"), Column("t3.c_int"))
+	left.Condition(Equals, Column("t3.cbool"), &Value{Value: true})
+	manual.From.Join(left)
+	manual.Where.Equals("t1.cstring", "a")
+
+	built := NewQuery("ttable", "t1")
+	built.Select.Column("t1.cint", "t2.cstring", "t3.cfloat")
+	built.From.
+		InnerJoinOn("ttable_c", "t2", "t1.cint", "t2.c_int").
+		JoinOn(LeftJoin, "ttable_d", "t3", func(c *Conditions) {
+			c.EqualsColumn("t2.cint", "t3.c_int").Equals("t3.cbool", true)
+		})
+	built.Where.Equals("t1.cstring", "a")
+
+	want, wantArgs, err := comiler.Compile("source", manual)
+	if err != nil {
+		t.Fatal("compile manual join error", err)
+	}
+	formatedSql, args, err := comiler.Compile("source", built)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Fatal("compile join builder error", err)
+	}
+	if formatedSql != want || !reflect.DeepEqual(args, wantArgs) {
+		t.Error("join builder should produce same sql as manual join", "want:", want, wantArgs, "actual:", formatedSql, args)
+	}
+
+	chained := NewQuery("ttable", "t1")
+	chained.Select.Column("t1.cint", "t2.cstring", "t3.cfloat")
+	chained.From.InnerJoin("ttable_c", "t2").On("t1.cint", "t2.c_int")
+	chained.From.LeftJoin("ttable_d", "t3").On("t2.cint", "t3.c_int").OnConditions(func(c *Conditions) {
+		c.Equals("t3.cbool", true)
+	})
+	chained.Where.Equals("t1.cstring", "a")
+	if formatedSql, args, err = comiler.Compile("source", chained); err != nil || formatedSql != want || !reflect.DeepEqual(args, wantArgs) {
+		t.Error("chained join should produce same sql as manual join", "want:", want, wantArgs, "actual:", formatedSql, args, err)
+	}
+}
+
+func TestJoinUsing(t *testing.T) {
+	comiler, err := GetCompiler("ansi")
+	if err != nil {
+		t.Error("can not find ansi compiler", err)
+	}
+
+	q := NewQuery("ttable", "t1")
+	q.From.InnerJoin("ttable_c", "t2").UsingColumns("cint")
+	formatedSql, _, err := comiler.Compile("source", q)
+	t.Log(formatedSql)
+	if err != nil {
+		t.Error("compile join using error", err)
+	}
+	want := `SELECT * FROM ttable AS t1 INNER JOIN ttable_c AS t2 USING ("cint") ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled join using sql error", "\n", formatedSql, "\n", want)
+	}
+
+	q = NewQuery("ttable", "t1")
+	q.From.LeftJoin("ttable_c", "t2").UsingColumns("cint", "cstring")
+	formatedSql, _, err = comiler.Compile("source", q)
+	t.Log(formatedSql)
+	if err != nil {
+		t.Error("compile join using error", err)
+	}
+	want = `SELECT * FROM ttable AS t1 LEFT JOIN ttable_c AS t2 USING ("cint", "cstring") ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled join using sql error", "\n", formatedSql, "\n", want)
+	}
+
+	q = NewQuery("ttable", "t1")
+	j := q.From.InnerJoin("ttable_c", "t2").UsingColumns("cint")
+	j.On("t1.cstring", "t2.cstring")
+	if _, _, err = comiler.Compile("source", q); err == nil {
+		t.Error("compile join with both on and using should return error")
+	}
+}
+
+func TestWindow(t *testing.T) {
+	comiler, err := GetCompiler("ansi")
+	if err != nil {
+		t.Error("can not find ansi compiler", err)
+	}
+
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint")
+
+	rn := NewWindow(RowNumber, nil).PartitionBy("cstring")
+	rn.UseOrderBy().Desc("cint")
+	q.Select.Window(rn, "rn")
+	q.Select.Window(NewWindow(Sum, Column("cint")).PartitionBy("cstring", "cbool"), "sum_cint")
+	q.Select.Window(NewWindow(Count, Column("cint")), "count_cint")
+
+	formatedSql, args, err := comiler.Compile("source", q)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Error("compile window error", err)
+	}
+
+	want := `
+SELECT cint, 
+ROW_NUMBER() OVER (PARTITION BY cstring ORDER BY cint DESC) AS "rn", 
+SUM(cint) OVER (PARTITION BY cstring, cbool) AS "sum_cint", 
+COUNT(cint) OVER () AS "count_cint" 
+FROM ttable ;
+`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled window sql error", "\n", formatedSql, "\n", want)
+	}
+}
+
+func TestWith(t *testing.T) {
+	comiler, err := GetCompiler("postgres")
+	if err != nil {
+		t.Error("can not find postgres compiler", err)
+	}
+
+	// single cte
+	cte := NewQuery("ttable", "")
+	cte.Select.Column("cint", "cstring")
+	cte.Where.GreaterThan("cint", 1)
+
+	q := NewQuery("t_cte", "")
+	q.UseWith().As("t_cte", cte)
+	q.Where.Equals("cstring", "a")
+
+	formatedSql, args, err := comiler.Compile("source", q)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Error("compile with error", err)
+	}
+	want := `
+WITH t_cte AS (SELECT cint, cstring FROM ttable WHERE cint > $1) 
+SELECT * FROM t_cte WHERE cstring = $2 ;
+`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled with sql error", "\n", formatedSql, "\n", want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, "a"}) {
+		t.Error("compiled with args error", args)
+	}
+
+	// two ctes
+	cte1 := NewQuery("ttable", "")
+	cte1.Where.Equals("cint", 1)
+	cte2 := NewQuery("ttable_c", "")
+	cte2.Select.Column("c_int")
+	cte2.Where.Equals("c_int", 2)
+
+	q = NewQuery("t1", "")
+	q.UseWith().As("t1", cte1).As("t2", cte2, "cint")
+	q.Where.In("cint", Sql("select cint from t2")).Equals("cstring", "b")
+
+	formatedSql, args, err = comiler.Compile("source", q)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Error("compile with error", err)
+	}
+	want = `
+WITH t1 AS (SELECT * FROM ttable WHERE cint = $1), 
+t2 (cint) AS (SELECT c_int FROM ttable_c WHERE c_int = $2) 
+SELECT * FROM t1 WHERE cint IN (select cint from t2) AND cstring = $3 ;
+`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled with sql error", "\n", formatedSql, "\n", want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, 2, "b"}) {
+		t.Error("compiled with args error", args)
+	}
+
+	// recursive cte, anchor UNION ALL children of rows already in t_tree
+	child := NewQuery("ttree", "c")
+	child.Select.Column("c.id", "c.parent_id")
+	child.From.InnerJoin("t_tree", "p").On("c.parent_id", "p.id")
+	child.Where.GreaterThan("c.id", 0)
+
+	cte = NewQuery("ttree", "")
+	cte.Select.Column("id", "parent_id")
+	cte.Where.Equals("id", 100)
+	cte.UnionAll(child)
+
+	q = NewQuery("t_tree", "")
+	q.UseWith().As("t_tree", cte, "id", "parent_id").Recursive = true
+	q.Where.NotEquals("id", 200)
+
+	formatedSql, args, err = comiler.Compile("source", q)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Error("compile with error", err)
+	}
+	want = `
+WITH RECURSIVE t_tree (id, parent_id) AS (
+SELECT id, parent_id FROM ttree WHERE id = $1 
+UNION ALL 
+SELECT c.id, c.parent_id FROM ttree AS c INNER JOIN t_tree AS p ON c.parent_id = p.id WHERE c.id > $2) 
+SELECT * FROM t_tree WHERE id <> $3 ;
+`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled with sql error", "\n", formatedSql, "\n", want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{100, 0, 200}) {
+		t.Error("compiled with args error", args)
+	}
+
+	// invalid cte
+	invalid := []*With{
+		NewWith().As("t_cte", nil),
+		NewWith().As("t_cte; drop table ttable", NewQuery("ttable", "")),
+		NewWith().As("t_cte", NewQuery("ttable", ""), "cint)"),
+	}
+	for _, with := range invalid {
+		q = NewQuery("t_cte", "")
+		q.With = with
+		if formatedSql, _, err = comiler.Compile("source", q); err == nil {
+			t.Error("compile invalid cte should fail", formatedSql)
+		}
+	}
+}
+
 func TestText(t *testing.T) {
 	var text *Text
 
@@ -392,6 +685,32 @@ where
 
 }
 
+func TestTextMissingParameter(t *testing.T) {
+	text := NewText("select * from ttable where cint > {cint} and cstring = {cstring}")
+	text.Set("cint", 1)
+
+	comiler, err := GetCompiler("ansi")
+	if err != nil {
+		t.Error("can not find ansi compiler", err)
+	}
+
+	_, _, err = comiler.Compile("source", text)
+	if err == nil {
+		t.Fatal("compile text should return error")
+	}
+
+	var ce *CompileError
+	if !errors.As(err, &ce) {
+		t.Fatal("error should be a *CompileError", err)
+	}
+	if ce.Code != CompileMissingParameter || ce.Parameter != "cstring" || ce.Node != NodeText {
+		t.Error("compile error is invalid", ce.Code, ce.Parameter, ce.Node)
+	}
+	if err.Error() != "text can not find parameter:cstring" {
+		t.Error("compile error message is invalid", err)
+	}
+}
+
 func TestProcedure(t *testing.T) {
 	var p *Procedure
 
@@ -414,9 +733,7 @@ func TestProcedure(t *testing.T) {
 		t.Error("compile procedure error", err)
 	}
 
-	var want string = `
-call sp_types(?,?,?,?,?,?);
-`
+	var want string = "\ncall `sp_types`(?,?,?,?,?,?);\n"
 
 	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
 		t.Error("compiled procedure sql error")
@@ -424,12 +741,188 @@ call sp_types(?,?,?,?,?,?);
 
 }
 
+func TestProcedureQualifiedName(t *testing.T) {
+	newProcedure := func() *Procedure {
+		p := NewProcedure("myschema.sp_count")
+		p.Set("select", 1)
+		p.SetDir("order", nil, ansi.DirOut)
+		return p
+	}
+
+	cases := []struct {
+		driver string
+		want   string
+	}{
+		{"mysql", "CALL `myschema`.`sp_count` ( ?, @`order` ); SELECT @`order` AS `order`;"},
+		{"postgres", `SELECT * FROM "myschema"."sp_count"($1);`},
+		{"adodb", "declare @kdbp1 nvarchar(max) set @kdbp1= ? exec [myschema].[sp_count] @select=? , @order=@kdbp1 output select @kdbp1"},
+	}
+
+	for _, c := range cases {
+		comiler, err := GetCompiler(c.driver)
+		if err != nil {
+			t.Error("can not find compiler", c.driver, err)
+			continue
+		}
+
+		formatedSql, args, err := comiler.Compile("source", newProcedure())
+		t.Log(formatedSql, args)
+		if err != nil {
+			t.Error("compile qualified procedure error", c.driver, err)
+			continue
+		}
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(c.want)) {
+			t.Error("compile qualified procedure error", c.driver, "want:", c.want, "actual:", formatedSql)
+		}
+	}
+
+	comiler, _ := GetCompiler("mysql")
+	for _, name := range []string{"myschema.", ".sp_count", "myschema..sp_count"} {
+		if _, _, err := comiler.Compile("source", NewProcedure(name)); err == nil {
+			t.Error("compile procedure with invalid name should fail", name)
+		}
+	}
+
+	p := NewProcedure("sp_count")
+	p.Set("x = 1; DROP TABLE ttable; --", 1)
+	if _, _, err := comiler.Compile("source", p); err == nil {
+		t.Error("compile procedure with invalid parameter name should fail")
+	}
+}
+
+func TestNewProcedureFromSchema(t *testing.T) {
+	fn := &ansi.DbFunction{
+		Name:   "sp_inout",
+		Schema: "myschema",
+		Parameters: []ansi.DbParameter{
+			{Name: "total", Position: 3, DbType: ansi.Int, Dir: ansi.DirOut},
+			{Name: "x", Position: 1, DbType: ansi.Int, Dir: ansi.DirIn},
+			{Name: "y", Position: 2, DbType: ansi.Int, Dir: ansi.DirInOut},
+		},
+	}
+
+	sp, err := NewProcedureFromSchema(fn, 1, 2)
+	if err != nil {
+		t.Fatal("new procedure from schema error", err)
+	}
+	if sp.Name != "myschema.sp_inout" || len(sp.Parameters) != 3 {
+		t.Fatal("new procedure from schema error", sp)
+	}
+
+	want := []*Parameter{
+		{Name: "x", Value: 1, DbType: ansi.Int, Dir: ansi.DirIn},
+		{Name: "y", Value: 2, DbType: ansi.Int, Dir: ansi.DirInOut},
+		{Name: "total", DbType: ansi.Int, Dir: ansi.DirOut},
+	}
+	if !reflect.DeepEqual(sp.Parameters, want) {
+		t.Error("new procedure from schema parameters error", "want:", want, "actual:", sp.Parameters)
+	}
+	if fn.Parameters[0].Name != "total" {
+		t.Error("new procedure from schema should not change parameters of function")
+	}
+
+	comiler, _ := GetCompiler("mysql")
+	formatedSql, args, err := comiler.Compile("source", sp)
+	t.Log(formatedSql, args)
+	wantSql := "SET @`y` = ?; CALL `myschema`.`sp_inout` ( ?, @`y`, @`total` ); SELECT @`y` AS `y`, @`total` AS `total`;"
+	if err != nil || !strings.EqualFold(removeSpace(formatedSql), removeSpace(wantSql)) {
+		t.Error("compile procedure from schema error", "want:", wantSql, "actual:", formatedSql, err)
+	}
+	if !reflect.DeepEqual(args, []interface{}{2, 1}) {
+		t.Error("compile procedure from schema args error", args)
+	}
+
+	if _, err := NewProcedureFromSchema(fn, 1); err == nil {
+		t.Error("new procedure from schema with less args should fail")
+	}
+	if _, err := NewProcedureFromSchema(fn, 1, 2, 3); err == nil {
+		t.Error("new procedure from schema with more args should fail")
+	}
+	if _, err := NewProcedureFromSchema(nil); err == nil {
+		t.Error("new procedure from nil schema should fail")
+	}
+}
+
+func TestProcedureValidate(t *testing.T) {
+	twoReturn := NewProcedure("fn_double").
+		SetDir("ret1", nil, ansi.DirReturn).
+		SetDir("ret2", nil, ansi.DirReturn).
+		Set("v", 21)
+	unnamedOut := NewProcedure("sp_out").
+		Set("v", 21).
+		SetDir("", nil, ansi.DirOut)
+	invalidDir := NewProcedure("sp_out").
+		SetDir("v", 21, ansi.Dir(9))
+
+	cases := []struct {
+		name string
+		sp   *Procedure
+	}{
+		{"two return", twoReturn},
+		{"unnamed out", unnamedOut},
+		{"invalid direction", invalidDir},
+	}
+
+	for _, driver := range []string{"mysql", "postgres"} {
+		comiler, _ := GetCompiler(driver)
+		for _, c := range cases {
+			_, _, err := comiler.Compile("source", c.sp)
+			t.Log(driver, c.name, err)
+			if err == nil {
+				t.Error("compile procedure with", c.name, "should fail", driver)
+				continue
+			}
+			if ce, ok := err.(*CompileError); !ok || ce.Code != CompileInvalid || ce.Node != NodeProcedure {
+				t.Error("compile procedure with", c.name, "should return invalid error", driver, err)
+			}
+		}
+	}
+
+	sp := NewProcedure("sp_inout").Set("x", 1).SetDir("y", 2, ansi.DirInOut).SetOut("total", ansi.Int)
+	if err := sp.validate(); err != nil {
+		t.Error("validate procedure error", err)
+	}
+}
+
+func TestProcedureReturn(t *testing.T) {
+	p := NewProcedure("fn_double")
+	p.SetDir("ret", nil, ansi.DirReturn)
+	p.Set("v", 21)
+
+	comiler, err := GetCompiler("mysql")
+	if err != nil {
+		t.Error("can not find mysql compiler", err)
+	}
+
+	formatedSql, args, err := comiler.Compile("source", p)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Fatal("compile procedure with return error", err)
+	}
+
+	want := "SET @`ret` = `fn_double` ( ? ); SELECT @`ret` AS `ret`;"
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled procedure with return error", "want:", want, "actual:", formatedSql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{21}) {
+		t.Error("compiled procedure with return args error", args)
+	}
+
+	setProcedureOutputs(p, map[string]interface{}{"ret": int64(42)})
+	if v, ok := p.ReturnValue(); !ok || v != int64(42) {
+		t.Error("procedure return value error", v, ok)
+	}
+	if _, ok := NewProcedure("sp_types").ReturnValue(); ok {
+		t.Error("procedure without return parameter should not have return value")
+	}
+}
+
 func TestUpdate(t *testing.T) {
 	var u *Update
 
 	u = NewUpdate("ttable")
-	for k, v := range dataTypeMap {
-		u.Set(k, v)
+	for _, k := range dataTypeKeys {
+		u.Set(k, dataTypeMap[k])
 	}
 	u.Where.Equals("cint", 101)
 	u.OrderBy.Asc("cint")
@@ -447,11 +940,11 @@ func TestUpdate(t *testing.T) {
 	}
 
 	var want string = `
-UPDATE ttable SET 
-cbool=? , cint=? , cfloat=? , cnumeric=? , cstring=? , cdate=? , cdatetime=? , cguid=? 
+UPDATE "ttable" SET 
+"cbool"=? , "cint"=? , "cfloat"=? , "cnumeric"=? , "cstring"=? , "cdate"=? , "cdatetime"=? , "cguid"=? 
 WHERE
-cint = ?  
-ORDER BY cint ASC 
+"cint" = ?  
+ORDER BY "cint" ASC 
 LIMIT 101;
 `
 	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
@@ -480,10 +973,10 @@ func TestDelete(t *testing.T) {
 	}
 
 	var want string = `
-DELETE FROM ttable
+DELETE FROM "ttable"
 WHERE
-cint =  ?  
-ORDER BY cint ASC 
+"cint" =  ?  
+ORDER BY "cint" ASC 
 LIMIT 101;
 `
 	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
@@ -495,8 +988,8 @@ func TestInsert(t *testing.T) {
 	var insert *Insert
 
 	insert = NewInsert("ttable")
-	for k, v := range dataTypeMap {
-		insert.Set(k, v)
+	for _, k := range dataTypeKeys {
+		insert.Set(k, dataTypeMap[k])
 	}
 
 	comiler, err := GetCompiler("ansi")
@@ -511,10 +1004,2602 @@ func TestInsert(t *testing.T) {
 	}
 
 	var want string = `
-INSERT INTO ttable(cbool, cint, cfloat, cnumeric, cstring, cdate, cdatetime, cguid)
+INSERT INTO "ttable"("cbool", "cint", "cfloat", "cnumeric", "cstring", "cdate", "cdatetime", "cguid")
 VALUES( ? ,  ? ,  ? ,  ? ,  ? ,  ? ,  ? ,  ? );
 `
 	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
 		t.Error("compiled insert sql error")
 	}
 }
+
+func TestQueryStable(t *testing.T) {
+	comiler, err := GetCompiler("postgres")
+	if err != nil {
+		t.Error("can not find postgres compiler", err)
+	}
+
+	q1 := NewQuery("ttable", "t1")
+	q1.Select.Column("cint", "cstring").Count("cint", "count_cint")
+	q1.From.LeftJoin("ttable_c", "t_l").On("t1.cstring", "t_l.c_string")
+	q1.Where.Equals("cint", 1).In("cstring", []string{"a", "b"}).LessThan("cfloat", 3.14)
+	q1.UseGroupBy().Column("cint", "cstring")
+	q1.UseHaving().Count(GreaterThan, "cint", 2)
+	q1.UseOrderBy().Desc("cint")
+
+	q2 := &Query{
+		Select: NewSelect(),
+		From:   NewFrom("ttable", "t1"),
+		Where:  NewWhere(),
+	}
+	q2.Select.Exp(Column("cint"), "").Exp(Column("cstring"), "").Aggregate(Count, Column("cint"), "count_cint")
+	j := NewJoinTable(LeftJoin, q2.From.Table, newTable("ttable_c", "t_l"))
+	j.Condition(Equals, Column("t1.cstring"), Column("t_l.c_string"))
+	q2.From.Join(j)
+	q2.Where.Compare(Equals, "cint", 1)
+	q2.Where.Compare(In, "cstring", []string{"a", "b"})
+	q2.Where.Compare(LessThan, "cfloat", 3.14)
+	q2.UseGroupBy().By(Column("cint")).By(Column("cstring"))
+	q2.UseHaving().Condition(GreaterThan, NewAggregate(Count, Column("cint")), &Value{Value: 2})
+	q2.UseOrderBy().By(Desc, Column("cint"))
+
+	sql1, args1, err := comiler.Compile("source", q1)
+	if err != nil {
+		t.Error("compile query error", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		sql2, args2, err := comiler.Compile("source", q2)
+		if err != nil {
+			t.Error("compile query error", err)
+		}
+		if sql1 != sql2 {
+			t.Error("compiled sql is not stable", "\n", sql1, "\n", sql2)
+		}
+		if !reflect.DeepEqual(args1, args2) {
+			t.Error("compiled args is not stable", args1, args2)
+		}
+	}
+
+	want := []interface{}{1, "a", "b", 3.14, 2}
+	if !reflect.DeepEqual(args1, want) {
+		t.Error("compiled args order error", args1, want)
+	}
+}
+
+func TestMapFields(t *testing.T) {
+	m := Map{}
+	for k, v := range dataTypeMap {
+		m[k] = v
+	}
+
+	want := []string{"cbool", "cdate", "cdatetime", "cfloat", "cguid", "cint", "cnumeric", "cstring"}
+	for i := 0; i < 10; i++ {
+		if fields := m.Fields(); !reflect.DeepEqual(fields, want) {
+			t.Error("map fields should be sorted", fields)
+		}
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	comiler, err := GetCompiler("ansi")
+	if err != nil {
+		t.Error("can not find ansi compiler", err)
+	}
+
+	insert := NewInsert("users; DROP")
+	insert.Set("cint", 1)
+	formatedSql, _, err := comiler.Compile("source", insert)
+	t.Log(formatedSql)
+	if err != nil {
+		t.Error("compile insert error", err)
+	}
+	if !strings.Contains(formatedSql, `INSERT INTO "users; DROP"("cint")`) {
+		t.Error("table name should be quoted", formatedSql)
+	}
+
+	IdentifierRegexp = SafeIdentifierRegexp
+	defer func() {
+		IdentifierRegexp = nil
+	}()
+
+	update := NewUpdate("users; DROP").Set("cint", 1)
+	update.AllowEmptyWhere = true
+	del := NewDelete("users; DROP")
+	del.AllowEmptyWhere = true
+	column := NewUpdate("ttable").Set("cint = 1; DROP", 1)
+	column.AllowEmptyWhere = true
+	whereColumn := NewUpdate("ttable").Set("cint", 1)
+	whereColumn.Where.Equals("cint = 1 OR 1", 1)
+	orderColumn := NewDelete("ttable")
+	orderColumn.Where.Equals("cint", 1)
+	orderColumn.OrderBy.Asc("cint; DROP")
+
+	exps := []Expression{
+		NewInsert("users; DROP").Set("cint", 1),
+		update,
+		del,
+		column,
+		whereColumn,
+		orderColumn,
+	}
+	for i := 0; i < len(exps); i++ {
+		if _, _, err = comiler.Compile("source", exps[i]); err == nil {
+			t.Error("compile should reject invalid identifier", exps[i])
+		}
+	}
+
+	u := NewUpdate("demo.ttable").Set("cint", 1)
+	u.AllowEmptyWhere = true
+	formatedSql, _, err = comiler.Compile("source", u)
+	t.Log(formatedSql)
+	if err != nil {
+		t.Error("compile update error", err)
+	}
+	want := `UPDATE "demo"."ttable" SET "cint"= ? ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled update sql error", "\n", formatedSql, "\n", want)
+	}
+	sub := NewQuery("ttable_c", "")
+	sub.Select.Column("c_int")
+	d := NewDelete("ttable")
+	d.Where.Equals("cstring", "a").In("cint", sub)
+	formatedSql, _, err = comiler.Compile("source", d)
+	t.Log(formatedSql)
+	if err != nil {
+		t.Error("compile delete error", err)
+	}
+	want = `DELETE FROM "ttable" WHERE "cstring" = ? AND "cint" IN (SELECT c_int FROM ttable_c) ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled delete sql error", "\n", formatedSql, "\n", want)
+	}
+}
+
+func TestDropTable(t *testing.T) {
+	comiler, err := GetCompiler("mysql")
+	if err != nil {
+		t.Error("can not find mysql compiler", err)
+	}
+
+	d := NewDropTable("ttable")
+	d.IfExists = true
+	formatedSql, _, err := comiler.Compile("source", d)
+	t.Log(formatedSql)
+	if err != nil {
+		t.Error("compile drop table error", err)
+	}
+	want := "DROP TABLE IF EXISTS `ttable` ;"
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled drop table sql error", "\n", formatedSql, "\n", want)
+	}
+
+	comiler, _ = GetCompiler("goracle")
+	if _, _, err = comiler.Compile("source", d); err == nil {
+		t.Error("oracle drop table if exists should return error")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	comiler, err := GetCompiler("postgres")
+	if err != nil {
+		t.Error("can not find postgres compiler", err)
+	}
+
+	tr := NewTruncate("ttable")
+	tr.RestartIdentity = true
+	tr.Cascade = true
+	formatedSql, _, err := comiler.Compile("source", tr)
+	t.Log(formatedSql)
+	if err != nil {
+		t.Error("compile truncate error", err)
+	}
+	want := `TRUNCATE TABLE "ttable" RESTART IDENTITY CASCADE ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled truncate sql error", "\n", formatedSql, "\n", want)
+	}
+
+	comiler, _ = GetCompiler("mysql")
+	if _, _, err = comiler.Compile("source", tr); err == nil {
+		t.Error("mysql truncate restart identity should return error")
+	}
+}
+
+func TestExists(t *testing.T) {
+	comiler, err := GetCompiler("postgres")
+	if err != nil {
+		t.Error("can not find postgres compiler", err)
+	}
+
+	sub := NewQuery("ttable_c", "t2")
+	sub.Select.Column("c_int")
+	sub.Where.Sql("t2.c_int = t1.cint").GreaterThan("t2.c_float", 1.5)
+
+	q := NewQuery("ttable", "t1")
+	q.Where.Equals("t1.cstring", "a").Exists(sub).LessThan("t1.cint", 10)
+
+	formatedSql, args, err := comiler.Compile("source", q)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Error("compile exists error", err)
+	}
+	want := `
+SELECT * FROM ttable AS t1
+WHERE t1.cstring = $1 AND EXISTS (SELECT c_int FROM ttable_c AS t2 WHERE t2.c_int = t1.cint AND t2.c_float > $2) AND t1.cint < $3 ;
+`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled exists sql error", "\n", formatedSql, "\n", want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"a", 1.5, 10}) {
+		t.Error("compiled exists args error", args)
+	}
+
+	q = NewQuery("ttable", "")
+	q.Where.NotExists(NewQuery("ttable_c", ""))
+	formatedSql, _, err = comiler.Compile("source", q)
+	if err != nil {
+		t.Error("compile not exists error", err)
+	}
+	if !strings.Contains(formatedSql, "NOT EXISTS (") {
+		t.Error("compiled not exists sql error", formatedSql)
+	}
+
+	q = NewQuery("ttable", "")
+	q.Where.Exists(Column("cint"))
+	if _, _, err = comiler.Compile("source", q); err == nil {
+		t.Error("exists without subquery should return error")
+	}
+}
+
+func TestIsNull(t *testing.T) {
+	comiler, err := GetCompiler("ansi")
+	if err != nil {
+		t.Error("can not find ansi compiler", err)
+	}
+
+	cases := []struct {
+		where func(w *Where)
+		want  string
+		args  []interface{}
+	}{
+		{func(w *Where) { w.IsNull("cint") }, `SELECT * FROM ttable WHERE cint IS NULL ;`, nil},
+		{func(w *Where) { w.IsNotNull("cint") }, `SELECT * FROM ttable WHERE cint IS NOT NULL ;`, nil},
+		{func(w *Where) { w.IsNull("cint").Equals("cstring", "a") }, `SELECT * FROM ttable WHERE cint IS NULL AND cstring = ? ;`, []interface{}{"a"}},
+		{func(w *Where) {
+			w.OpenParentheses().IsNull("cint").Or().IsNotNull("cstring").CloseParentheses().Equals("cbool", true)
+		}, `SELECT * FROM ttable WHERE ( cint IS NULL OR cstring IS NOT NULL ) AND cbool = ? ;`, []interface{}{true}},
+	}
+
+	for i, c := range cases {
+		q := NewQuery("ttable", "")
+		c.where(q.Where)
+		formatedSql, args, err := comiler.Compile("source", q)
+		t.Log(formatedSql, args)
+		if err != nil {
+			t.Error("compile is null error", i, err)
+		}
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(c.want)) {
+			t.Error("compiled is null sql error", i, "\n", formatedSql, "\n", c.want)
+		}
+		if len(args) != len(c.args) || (len(args) > 0 && !reflect.DeepEqual(args, c.args)) {
+			t.Error("compiled is null args error", i, args)
+		}
+	}
+
+	q := NewQuery("ttable", "")
+	q.Where.Condition(IsNull, Column("cint"), &Value{Value: 1})
+	if _, _, err = comiler.Compile("source", q); err == nil {
+		t.Error("is null with right operand should return error")
+	}
+}
+
+func TestArith(t *testing.T) {
+	comiler, err := GetCompiler("postgres")
+	if err != nil {
+		t.Error("can not find postgres compiler", err)
+	}
+
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint").
+		Exp(NewArith(Multiply, Column("cint"), Column("cfloat")), "total")
+	q.Where.Condition(GreaterThan, NewArith(Minus, Column("cfloat"), 1.5), asExpression(0))
+
+	formatedSql, args, err := comiler.Compile("source", q)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Error("compile arith error", err)
+	}
+	want := `SELECT cint, cint * cfloat AS "total" FROM ttable WHERE cfloat - $1 > $2 ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled arith sql error", "\n", formatedSql, "\n", want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1.5, 0}) {
+		t.Error("compiled arith args error", args)
+	}
+
+	a, b, c := Column("a"), Column("b"), Column("c")
+	cases := []struct {
+		exp  *Arith
+		want string
+	}{
+		{NewArith(Plus, a, NewArith(Multiply, b, c)), "a + b * c"},
+		{NewArith(Multiply, NewArith(Plus, a, b), c), "(a + b) * c"},
+		{NewArith(Minus, a, NewArith(Minus, b, c)), "a - (b - c)"},
+		{NewArith(Minus, NewArith(Minus, a, b), c), "a - b - c"},
+		{NewArith(Divide, a, NewArith(Modulo, b, c)), "a / (b % c)"},
+	}
+
+	for _, c := range cases {
+		q = NewQuery("ttable", "")
+		q.Select.Exp(c.exp, "")
+		formatedSql, _, err = comiler.Compile("source", q)
+		if err != nil {
+			t.Error("compile arith error", c.want, err)
+		}
+		want = "SELECT " + c.want + " FROM ttable ;"
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+			t.Error("compiled arith precedence error", "\n", formatedSql, "\n", want)
+		}
+	}
+
+	q = NewQuery("ttable", "")
+	q.Select.Exp(NewArith(ArithOperator("^"), a, b), "")
+	if _, _, err = comiler.Compile("source", q); err == nil {
+		t.Error("unsupported arithmetic operator should return error")
+	}
+}
+
+func TestLock(t *testing.T) {
+	limits := map[string]string{
+		"mysql":    "LIMIT 0,10",
+		"postgres": "LIMIT 10",
+	}
+	for driver, limit := range limits {
+		comiler, err := GetCompiler(driver)
+		if err != nil {
+			t.Error("can not find compiler", driver, err)
+		}
+
+		q := NewQuery("ttable", "")
+		q.Where.Equals("cint", 1)
+		q.UseOrderBy().Asc("cint")
+		q.Limit(0, 10)
+		q.ForUpdate()
+
+		formatedSql, _, err := comiler.Compile("source", q)
+		t.Log(formatedSql)
+		if err != nil {
+			t.Error("compile lock error", driver, err)
+		}
+		if !strings.HasSuffix(removeSpace(formatedSql), removeSpace(limit+" FOR UPDATE ;")) {
+			t.Error("compiled lock sql error", driver, "\n", formatedSql)
+		}
+
+		q.ForShare().SkipLocked = true
+		formatedSql, _, err = comiler.Compile("source", q)
+		if err != nil {
+			t.Error("compile lock error", driver, err)
+		}
+		if !strings.Contains(formatedSql, "FOR SHARE SKIP LOCKED") {
+			t.Error("compiled lock sql error", driver, "\n", formatedSql)
+		}
+	}
+
+	comiler, _ := GetCompiler("goracle")
+	q := NewQuery("ttable", "")
+	q.ForUpdate().NoWait = true
+	formatedSql, _, err := comiler.Compile("source", q)
+	if err != nil || !strings.Contains(formatedSql, "FOR UPDATE NOWAIT") {
+		t.Error("compiled oracle lock error", formatedSql, err)
+	}
+	q.ForShare()
+	if _, _, err = comiler.Compile("source", q); err == nil {
+		t.Error("oracle for share should return error")
+	}
+
+	for _, driver := range []string{"ansi", "sqlite3", "adodb"} {
+		comiler, _ := GetCompiler(driver)
+		q := NewQuery("ttable", "")
+		q.ForUpdate()
+		if _, _, err := comiler.Compile("source", q); err == nil {
+			t.Error("lock should return error", driver)
+		}
+	}
+}
+
+func TestDeleteJoin(t *testing.T) {
+	newDelete := func() *Delete {
+		d := NewDelete("ttable")
+		d.UseFrom("ttable", "t1").InnerJoin("ttable_c", "t2").On("t1.cint", "t2.c_int")
+		d.Where.Equals("t2.c_string", "a")
+		return d
+	}
+
+	cases := map[string]string{
+		"mysql":    "DELETE `t1` FROM ttable AS t1 INNER JOIN ttable_c AS t2 ON t1.cint = t2.c_int WHERE t2.c_string = ? ;",
+		"postgres": `DELETE FROM ttable AS t1 USING ttable_c AS t2 WHERE (t1.cint = t2.c_int) AND (t2.c_string = $1) ;`,
+	}
+
+	for driver, want := range cases {
+		comiler, err := GetCompiler(driver)
+		if err != nil {
+			t.Error("can not find compiler", driver, err)
+		}
+
+		formatedSql, args, err := comiler.Compile("source", newDelete())
+		t.Log(formatedSql, args)
+		if err != nil {
+			t.Error("compile delete join error", driver, err)
+		}
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+			t.Error("compiled delete join sql error", driver, "\n", formatedSql, "\n", want)
+		}
+		if !reflect.DeepEqual(args, []interface{}{"a"}) {
+			t.Error("compiled delete join args error", driver, args)
+		}
+	}
+
+	comiler, _ := GetCompiler("sqlite3")
+	if _, _, err := comiler.Compile("source", newDelete()); err == nil {
+		t.Error("sqlite delete join should return error")
+	}
+
+	comiler, _ = GetCompiler("postgres")
+	d := NewDelete("ttable")
+	d.UseFrom("ttable", "t1").LeftJoin("ttable_c", "t2").On("t1.cint", "t2.c_int")
+	if _, _, err := comiler.Compile("source", d); err == nil {
+		t.Error("postgres delete with left join should return error")
+	}
+}
+
+func TestUpdateJoin(t *testing.T) {
+	newUpdate := func(column string) *Update {
+		u := NewUpdate("ttable")
+		u.UseFrom("ttable", "t1").InnerJoin("ttable_c", "t2").On("t1.cint", "t2.c_int")
+		u.Set(column, Column("t2.c_string")).Set("cfloat", 1.5)
+		u.Where.Equals("t2.c_bool", true)
+		return u
+	}
+
+	cases := []struct {
+		driver string
+		column string
+		want   string
+	}{
+		{"mysql", "t1.cstring", "UPDATE ttable AS t1 INNER JOIN ttable_c AS t2 ON t1.cint = t2.c_int SET `t1`.`cstring` = t2.c_string, `cfloat` = ? WHERE t2.c_bool = ? ;"},
+		{"postgres", "cstring", `UPDATE ttable AS t1 SET "cstring" = t2.c_string, "cfloat" = $1 FROM ttable_c AS t2 WHERE (t1.cint = t2.c_int) AND (t2.c_bool = $2) ;`},
+		{"adodb", "t1.cstring", `UPDATE [t1] SET [t1].[cstring] = t2.c_string, [cfloat] = ? FROM ttable AS t1 INNER JOIN ttable_c AS t2 ON t1.cint = t2.c_int WHERE t2.c_bool = ? ;`},
+	}
+
+	for _, c := range cases {
+		comiler, err := GetCompiler(c.driver)
+		if err != nil {
+			t.Error("can not find compiler", c.driver, err)
+		}
+
+		formatedSql, args, err := comiler.Compile("source", newUpdate(c.column))
+		t.Log(formatedSql, args)
+		if err != nil {
+			t.Error("compile update join error", c.driver, err)
+		}
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(c.want)) {
+			t.Error("compiled update join sql error", c.driver, "\n", formatedSql, "\n", c.want)
+		}
+		if !reflect.DeepEqual(args, []interface{}{1.5, true}) {
+			t.Error("compiled update join args error", c.driver, args)
+		}
+	}
+
+	comiler, _ := GetCompiler("sqlite3")
+	if _, _, err := comiler.Compile("source", newUpdate("cstring")); err == nil {
+		t.Error("sqlite update join should return error")
+	}
+}
+
+func TestQualifiedColumn(t *testing.T) {
+	comiler, err := GetCompiler("postgres")
+	if err != nil {
+		t.Error("can not find postgres compiler", err)
+	}
+
+	q := NewQuery("ttable", "t1")
+	t2 := newTable("ttable_c", "")
+	j := NewJoinTable(InnerJoin, q.From.Table, t2)
+	j.Condition(Equals, q.From.Table.Column("cint"), t2.Column("cint"))
+	q.From.Join(j)
+
+	q.Select.TableColumn(q.From.Table, "cint", "cstring").
+		Exp(t2.Column("cstring"), "c_string").
+		TableColumn(t2, "*")
+	q.Where.Condition(Equals, t2.Column("cint"), asExpression(1))
+
+	formatedSql, args, err := comiler.Compile("source", q)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Error("compile table column error", err)
+	}
+	want := `SELECT "t1"."cint", "t1"."cstring", "ttable_c"."cstring" AS "c_string", "ttable_c".*
+FROM ttable AS t1 INNER JOIN ttable_c ON "t1"."cint" = "ttable_c"."cint"
+WHERE "ttable_c"."cint" = $1 ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled table column sql error", "\n", formatedSql, "\n", want)
+	}
+
+	if s := t2.Column("cint").String(); s != "ttable_c.cint" {
+		t.Error("table column string error", s)
+	}
+}
+
+func TestCountAll(t *testing.T) {
+	comiler, err := GetCompiler("ansi")
+	if err != nil {
+		t.Error("can not find ansi compiler", err)
+	}
+
+	q := NewQuery("ttable", "")
+	q.Select.Aggregate(Count, nil, "")
+	formatedSql, _, err := comiler.Compile("source", q)
+	t.Log(formatedSql)
+	if err != nil {
+		t.Error("compile count(*) error", err)
+	}
+	want := "SELECT COUNT(*) FROM ttable ;"
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled count(*) sql error", "\n", formatedSql, "\n", want)
+	}
+
+	q = NewQuery("ttable", "")
+	q.Select.CountAll("total").Count("cint", "")
+	formatedSql, _, err = comiler.Compile("source", q)
+	t.Log(formatedSql)
+	if err != nil {
+		t.Error("compile count(*) error", err)
+	}
+	want = `SELECT COUNT(*) AS "total", COUNT(cint) FROM ttable ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled count(*) sql error", "\n", formatedSql, "\n", want)
+	}
+
+	invalid := []*Aggregate{NewAggregate(Sum, nil), NewAggregate(Max, Wildcard)}
+	for i := 0; i < len(invalid); i++ {
+		q = NewQuery("ttable", "")
+		q.Select.Exp(invalid[i], "")
+		if _, _, err = comiler.Compile("source", q); err == nil {
+			t.Error("compile invalid aggregate should return error", invalid[i])
+		}
+	}
+}
+
+func TestHavingWithoutGroupBy(t *testing.T) {
+	comiler, err := GetCompiler("postgres")
+	if err != nil {
+		t.Error("can not find postgres compiler", err)
+	}
+
+	q := NewQuery("ttable", "")
+	q.Select.CountAll("total")
+	q.Where.Equals("cbool", true)
+	q.UseHaving().CountAll(GreaterThan, 10)
+
+	formatedSql, args, err := comiler.Compile("source", q)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Error("compile having error", err)
+	}
+	want := `SELECT COUNT(*) AS "total" FROM ttable WHERE cbool = $1 HAVING COUNT(*) > $2 ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled having sql error", "\n", formatedSql, "\n", want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{true, 10}) {
+		t.Error("compiled having args error", args)
+	}
+}
+
+func TestDerivedTable(t *testing.T) {
+	comiler, err := GetCompiler("postgres")
+	if err != nil {
+		t.Error("can not find postgres compiler", err)
+	}
+
+	q := NewQuery("(VALUES (1, 'a'), (2, 'b'))", "t")
+	q.From.Table.AliasColumns("n", "s")
+	q.Select.Column("t.n", "t.s")
+	q.Where.GreaterThan("t.n", 1)
+
+	formatedSql, args, err := comiler.Compile("source", q)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Error("compile derived table error", err)
+	}
+	want := `SELECT t.n, t.s FROM (VALUES (1, 'a'), (2, 'b')) AS t (n, s) WHERE t.n > $1 ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled derived table sql error", "\n", formatedSql, "\n", want)
+	}
+
+	q = NewQuery("(VALUES (1))", "")
+	q.From.Table.AliasColumns("n")
+	if _, _, err = comiler.Compile("source", q); err == nil {
+		t.Error("column alias list without alias should return error")
+	}
+}
+
+func TestValuesList(t *testing.T) {
+	comiler, err := GetCompiler("postgres")
+	if err != nil {
+		t.Error("can not find postgres compiler", err)
+	}
+
+	values := NewValuesList().Row(1, "a").Row(2, "b")
+
+	q := NewQuery("", "")
+	q.From.Table = NewDerivedTable(values, "t", "n", "s")
+	q.Select.Column("t.n", "t.s")
+	q.Where.GreaterThan("t.n", 0)
+
+	formatedSql, args, err := comiler.Compile("source", q)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Error("compile values list error", err)
+	}
+	want := `SELECT t.n, t.s FROM (VALUES ($1, $2), ($3, $4)) AS t (n, s) WHERE t.n > $5 ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled values list sql error", "\n", formatedSql, "\n", want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, "a", 2, "b", 0}) {
+		t.Error("compiled values list args error", args)
+	}
+
+	invalid := []*ValuesList{
+		NewValuesList(),
+		NewValuesList().Row(1, 2).Row(3),
+	}
+	for i := 0; i < len(invalid); i++ {
+		q = NewQuery("", "")
+		q.From.Table = NewDerivedTable(invalid[i], "t")
+		if _, _, err = comiler.Compile("source", q); err == nil {
+			t.Error("compile invalid values list should return error", i)
+		}
+	}
+
+	q = NewQuery("", "")
+	q.From.Table = NewDerivedTable(values, "")
+	if _, _, err = comiler.Compile("source", q); err == nil {
+		t.Error("derived table without alias should return error")
+	}
+}
+
+func TestInsertSelect(t *testing.T) {
+	comiler, err := GetCompiler("postgres")
+	if err != nil {
+		t.Error("can not find postgres compiler", err)
+	}
+
+	insert := NewInsert("ttable_c").Set("c_int", 1).Set("c_string", "a")
+	formatedSql, args, err := comiler.Compile("source", insert)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Error("compile insert error", err)
+	}
+	want := `INSERT INTO "ttable_c" ("c_int", "c_string") VALUES ($1, $2) ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled insert sql error", "\n", formatedSql, "\n", want)
+	}
+
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint", "cstring")
+	q.Where.GreaterThan("cint", 1).Equals("cbool", true)
+
+	insert = NewInsert("ttable_c").Select(q, "c_int", "c_string")
+	formatedSql, args, err = comiler.Compile("source", insert)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Error("compile insert select error", err)
+	}
+	want = `INSERT INTO "ttable_c" ("c_int", "c_string") SELECT cint, cstring FROM ttable WHERE cint > $1 AND cbool = $2 ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compiled insert select sql error", "\n", formatedSql, "\n", want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, true}) {
+		t.Error("compiled insert select args error", args)
+	}
+
+	insert = NewInsert("ttable_c").Set("c_int", 1).Select(q)
+	if _, _, err = comiler.Compile("source", insert); err == nil {
+		t.Error("insert with both sets and query should return error")
+	}
+}
+
+func TestCompact(t *testing.T) {
+	q := NewQuery("ttable", "t1")
+	q.Select.Column("cint", "cstring")
+	q.From.InnerJoin("ttable_c", "t2").On("t1.cint", "t2.c_int")
+	q.Where.OpenParentheses().Equals("cint", 1).Or().Sql("cstring = 'a  b'").CloseParentheses()
+	q.UseOrderBy().Desc("cint")
+
+	pretty, prettyArgs, err := NewStmtCompiler(AnsiDialecter{}).Compile(q, "source")
+	if err != nil {
+		t.Error("compile pretty error", err)
+	}
+
+	sc := NewStmtCompiler(AnsiDialecter{})
+	sc.Compact = true
+	compact, compactArgs, err := sc.Compile(q, "source")
+	t.Log(pretty, "\n", compact)
+	if err != nil {
+		t.Error("compile compact error", err)
+	}
+
+	if !strings.ContainsAny(pretty, "\n\t") {
+		t.Error("pretty sql should have line breaks", pretty)
+	}
+	if strings.ContainsAny(compact, "\n\t") || strings.Contains(strings.Replace(compact, "'a  b'", "s", 1), "  ") {
+		t.Error("compact sql should not have line breaks or repeated spaces", compact)
+	}
+	if compact != strings.TrimSpace(compact) {
+		t.Error("compact sql should not have leading or trailing space", compact)
+	}
+	if !strings.Contains(compact, "'a  b'") {
+		t.Error("compact sql should keep quoted string", compact)
+	}
+	if removeSpace(pretty) != removeSpace(compact) {
+		t.Error("compact sql should equal to pretty sql except whitespace", "\n", pretty, "\n", compact)
+	}
+	if !reflect.DeepEqual(prettyArgs, compactArgs) {
+		t.Error("compact args error", prettyArgs, compactArgs)
+	}
+
+	want := `SELECT cint, cstring FROM ttable AS t1 INNER JOIN ttable_c AS t2 ON t1.cint = t2.c_int WHERE ( cint = ? OR cstring = 'a  b' ) ORDER BY cint DESC ;`
+	if compact != want {
+		t.Error("compiled compact sql error", "\n", compact, "\n", want)
+	}
+}
+
+func benchmarkCompileIn(b *testing.B, capHint int) {
+	values := make([]int, 5000)
+	for i := 0; i < len(values); i++ {
+		values[i] = i
+	}
+	q := NewQuery("ttable", "")
+	q.Where.In("cint", values)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := NewStmtCompilerSized(AnsiDialecter{}, capHint).Compile(q, "source"); err != nil {
+			b.Fatal("compile error", err)
+		}
+	}
+}
+
+func BenchmarkCompileIn(b *testing.B) {
+	benchmarkCompileIn(b, 0)
+}
+
+func BenchmarkCompileInSized(b *testing.B) {
+	benchmarkCompileIn(b, 32*1024)
+}
+
+func TestStmtCompilerReset(t *testing.T) {
+	sc := NewStmtCompiler(PostgreSQLDialecter{})
+
+	q1 := NewQuery("ttable", "")
+	q1.Where.Equals("cint", 1).Equals("cstring", "a")
+	sql1, args1, err := sc.Compile(q1, "source")
+	if err != nil {
+		t.Error("compile first query error", err)
+	}
+
+	q2 := NewQuery("ttable_c", "")
+	q2.Where.Equals("c_int", 2)
+	sql2, args2, err := sc.Compile(q2, "source")
+	t.Log(sql1, args1, sql2, args2)
+	if err != nil {
+		t.Error("compile second query error", err)
+	}
+
+	want := `SELECT * FROM ttable_c WHERE c_int = $1 ;`
+	if !strings.EqualFold(removeSpace(sql2), removeSpace(want)) {
+		t.Error("compiled second query sql error", "\n", sql2, "\n", want)
+	}
+	if !reflect.DeepEqual(args2, []interface{}{2}) {
+		t.Error("compiled second query args error", args2)
+	}
+	if !reflect.DeepEqual(args1, []interface{}{1, "a"}) {
+		t.Error("args of first query should not be changed", args1)
+	}
+
+	sc.Reset()
+	if sc.w.Len() != 0 || sc.args != nil || sc.paraIndex != 0 || sc.source != "" || sc.err != nil {
+		t.Error("reset compiler error", sc.w.Len(), sc.args, sc.paraIndex, sc.source, sc.err)
+	}
+}
+
+func TestConditionsGroup(t *testing.T) {
+	comiler, err := GetCompiler("ansi")
+	if err != nil {
+		t.Error("can not find ansi compiler", err)
+	}
+
+	q := NewQuery("ttable", "")
+	q.Where.Group(func(g *Conditions) {
+		g.Equals("a", 1).Equals("b", 2)
+	}).Or().Group(func(g *Conditions) {
+		g.Equals("c", 3).Group(func(g *Conditions) {
+			g.Equals("d", 4).Or().Equals("e", 5)
+		})
+	}).Group(func(g *Conditions) {})
+
+	formatedSql, args, err := comiler.Compile("source", q)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Error("compile group error", err)
+		return
+	}
+
+	want := `SELECT * FROM ttable WHERE ( a = ? AND b = ? ) OR ( c = ? AND ( d = ? OR e = ? ) ) ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compile group error", "want:", want, "actual:", formatedSql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, 2, 3, 4, 5}) {
+		t.Error("compile group args error", args)
+	}
+
+	unbalanced := []func(w *Where){
+		func(w *Where) { w.OpenParentheses().Equals("a", 1) },
+		func(w *Where) { w.Equals("a", 1).CloseParentheses() },
+		func(w *Where) { w.CloseParentheses().Equals("a", 1).OpenParentheses() },
+	}
+	for i, fn := range unbalanced {
+		q := NewQuery("ttable", "")
+		fn(q.Where)
+		if _, _, err := comiler.Compile("source", q); err == nil {
+			t.Error("compile unbalanced conditions should fail", i)
+		}
+	}
+}
+
+func TestConditionsParenthesesFormat(t *testing.T) {
+	comiler, err := GetCompiler("ansi")
+	if err != nil {
+		t.Error("can not find ansi compiler", err)
+	}
+
+	q := NewQuery("ttable", "")
+	q.Where.OpenParentheses().
+		Equals("a", 1).
+		Or().
+		Equals("b", 2).
+		CloseParentheses().
+		OpenParentheses().
+		Equals("c", 3).
+		CloseParentheses()
+
+	formatedSql, _, err := comiler.Compile("source", q)
+	t.Log(formatedSql)
+	if err != nil {
+		t.Error("compile parentheses error", err)
+		return
+	}
+
+	want := `SELECT * FROM ttable WHERE ( a = ? OR b = ? ) AND ( c = ? ) ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compile parentheses error", "want:", want, "actual:", formatedSql)
+	}
+	if regexp.MustCompile(`(AND|OR)\s*\n\s*\(`).MatchString(formatedSql) {
+		t.Error("line break before open parentheses", formatedSql)
+	}
+
+	c := &Conditions{Conditions: []Expression{CloseParentheses, Sql("a = 1"), OpenParentheses, Sql("b = 2"), CloseParentheses}}
+	s := c.String()
+	t.Log(s)
+	if s != ")\na = 1 (\n\tb = 2\n)" {
+		t.Errorf("conditions string error: %q", s)
+	}
+}
+
+func TestConditionsLogicOperator(t *testing.T) {
+	comiler, err := GetCompiler("ansi")
+	if err != nil {
+		t.Error("can not find ansi compiler", err)
+	}
+
+	cases := []struct {
+		where func(w *Where)
+		want  string
+	}{
+		{func(w *Where) { w.Equals("a", 1).Or().Equals("b", 2) }, `SELECT * FROM ttable WHERE a = ? OR b = ? ;`},
+		{func(w *Where) { w.Equals("a", 1).Equals("b", 2).Or().Equals("c", 3) }, `SELECT * FROM ttable WHERE a = ? AND b = ? OR c = ? ;`},
+		{func(w *Where) {
+			w.Equals("a", 1).Group(func(g *Conditions) { g.Equals("b", 2).Or().Equals("c", 3) })
+		}, `SELECT * FROM ttable WHERE a = ? AND ( b = ? OR c = ? ) ;`},
+		{func(w *Where) {
+			w.Group(func(g *Conditions) { g.Equals("a", 1).Equals("b", 2) }).Or().Equals("c", 3)
+		}, `SELECT * FROM ttable WHERE ( a = ? AND b = ? ) OR c = ? ;`},
+		{func(w *Where) {
+			w.Conditions.Conditions = []Expression{Sql("a = 1"), Sql("b = 2"), Or, Sql("c = 3")}
+		}, `SELECT * FROM ttable WHERE a = 1 AND b = 2 OR c = 3 ;`},
+	}
+
+	for i, c := range cases {
+		q := NewQuery("ttable", "")
+		c.where(q.Where)
+		formatedSql, args, err := comiler.Compile("source", q)
+		t.Log(formatedSql, args)
+		if err != nil {
+			t.Error("compile logic operator error", i, err)
+			continue
+		}
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(c.want)) {
+			t.Error("compile logic operator error", i, "want:", c.want, "actual:", formatedSql)
+		}
+	}
+
+	invalid := [][]Expression{
+		{Or, Sql("a = 1")},
+		{Sql("a = 1"), And},
+		{Sql("a = 1"), And, Or, Sql("b = 2")},
+		{OpenParentheses, CloseParentheses},
+	}
+	for i, items := range invalid {
+		q := NewQuery("ttable", "")
+		q.Where.Conditions.Conditions = items
+		if _, _, err := comiler.Compile("source", q); err == nil {
+			t.Error("compile invalid logic operator should fail", i)
+		}
+	}
+}
+
+func TestRaw(t *testing.T) {
+	comiler, err := GetCompiler("postgres")
+	if err != nil {
+		t.Error("can not find postgres compiler", err)
+	}
+
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint").Exp(Raw("cstring::text || ?", "-"), "ctext")
+	q.Where.Raw("MATCH(cstring) AGAINST(? IN BOOLEAN MODE)", "word").Raw("cstring <> '?'").Equals("cint", 1)
+
+	formatedSql, args, err := comiler.Compile("source", q)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Error("compile raw error", err)
+		return
+	}
+
+	want := `SELECT cint, cstring::text || $1 AS "ctext" FROM ttable WHERE MATCH(cstring) AGAINST($2 IN BOOLEAN MODE) AND cstring <> '?' AND cint = $3 ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compile raw error", "want:", want, "actual:", formatedSql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"-", "word", 1}) {
+		t.Error("compile raw args error", args)
+	}
+
+	for i, r := range []*RawSql{Raw("a = ? AND b = ?", 1), Raw("a = ?", 1, 2)} {
+		q := NewQuery("ttable", "")
+		q.Where.Condition(Equals, Column("c"), r)
+		if _, _, err := comiler.Compile("source", q); err == nil {
+			t.Error("compile raw with mismatched arguments should fail", i)
+		}
+	}
+}
+
+func TestNamedParameter(t *testing.T) {
+	cases := []struct {
+		driver string
+		want   string
+		args   []interface{}
+	}{
+		{"ansi", `SELECT * FROM ttable WHERE cint > ? AND cstring = ? OR cbigint > ? ;`, []interface{}{1, "a", 1}},
+		{"postgres", `SELECT * FROM ttable WHERE cint > $1 AND cstring = $2 OR cbigint > $1 ;`, []interface{}{1, "a"}},
+		{"goracle", `SELECT * FROM ttable WHERE cint > :min AND cstring = :pv2 OR cbigint > :min`, []interface{}{1, "a"}},
+	}
+
+	for _, c := range cases {
+		comiler, err := GetCompiler(c.driver)
+		if err != nil {
+			t.Error("can not find compiler", c.driver, err)
+			continue
+		}
+
+		min := &Parameter{Name: "min", Value: 1}
+		q := NewQuery("ttable", "")
+		q.Where.GreaterThan("cint", min).Equals("cstring", "a").Or().GreaterThan("cbigint", min)
+
+		formatedSql, args, err := comiler.Compile("source", q)
+		t.Log(formatedSql, args)
+		if err != nil {
+			t.Error("compile named parameter error", c.driver, err)
+			continue
+		}
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(c.want)) {
+			t.Error("compile named parameter error", c.driver, "want:", c.want, "actual:", formatedSql)
+		}
+		if !reflect.DeepEqual(args, c.args) {
+			t.Error("compile named parameter args error", c.driver, args)
+		}
+	}
+}
+
+// sqlNode is NodeSql but doesn't implement RawSqler
+type sqlNode struct{}
+
+func (n sqlNode) Node() NodeType {
+	return NodeSql
+}
+
+func TestVisitExpError(t *testing.T) {
+	comiler, err := GetCompiler("postgres")
+	if err != nil {
+		t.Error("can not find postgres compiler", err)
+	}
+
+	exps := []Expression{
+		&Parameter{Name: "out", Dir: ansi.DirOut},
+		&Parameter{Name: "ret", Dir: ansi.DirReturn},
+		&Parameter{Name: "1bad", Value: 1},
+		NewText("select 1"),
+		sqlNode{},
+	}
+
+	for i, exp := range exps {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Error("compile embedded expression should not panic", i, r)
+				}
+			}()
+
+			q := NewQuery("ttable", "")
+			q.Where.Condition(Equals, Column("cint"), exp)
+			if _, _, err := comiler.Compile("source", q); err == nil {
+				t.Error("compile embedded expression should fail", i)
+			} else {
+				t.Log(i, err)
+			}
+		}()
+	}
+}
+
+// panicSql is a RawSqler panic in ToSql
+type panicSql struct{}
+
+func (p panicSql) Node() NodeType {
+	return NodeSql
+}
+
+func (p panicSql) ToSql() string {
+	panic("panic sql")
+}
+
+func TestCompileRecover(t *testing.T) {
+	comiler, err := GetCompiler("ansi")
+	if err != nil {
+		t.Error("can not find ansi compiler", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Error("compile should not panic", r)
+		}
+	}()
+
+	q := NewQuery("ttable", "")
+	q.Where.Equals("cint", 1).Group(func(g *Conditions) {
+		g.Condition(Equals, Column("cstring"), panicSql{})
+	})
+
+	formatedSql, args, err := comiler.Compile("source", q)
+	t.Log(formatedSql, args, err)
+	if err == nil {
+		t.Error("compile panic should return error")
+		return
+	}
+
+	if e, ok := err.(*CompileError); !ok || e.Node != NodeSql {
+		t.Error("compile panic error should have node context", err)
+	}
+	if formatedSql != "" || args != nil {
+		t.Error("compile panic should not return sql or args", formatedSql, args)
+	}
+}
+
+func TestDistinctOn(t *testing.T) {
+	comiler, err := GetCompiler("postgres")
+	if err != nil {
+		t.Error("can not find postgres compiler", err)
+	}
+
+	q := NewQuery("ttable", "")
+	q.DistinctOnColumn("cstring", "t.cint")
+	q.Select.Column("cstring", "cint")
+	q.UseOrderBy().Asc("cstring").Desc("cint")
+
+	formatedSql, args, err := comiler.Compile("source", q)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Error("compile distinct on error", err)
+		return
+	}
+
+	want := `SELECT DISTINCT ON ("cstring", "t"."cint") cstring, cint FROM ttable ORDER BY cstring ASC, cint DESC ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compile distinct on error", "want:", want, "actual:", formatedSql)
+	}
+
+	for _, driver := range []string{"ansi", "mysql"} {
+		comiler, err := GetCompiler(driver)
+		if err != nil {
+			t.Error("can not find compiler", driver, err)
+			continue
+		}
+		if _, _, err := comiler.Compile("source", q); err == nil {
+			t.Error("compile distinct on should fail", driver)
+		}
+	}
+}
+
+func TestOrderByAlias(t *testing.T) {
+	comiler, err := GetCompiler("mysql")
+	if err != nil {
+		t.Error("can not find mysql compiler", err)
+	}
+
+	q := NewQuery("ttable", "")
+	q.Select.Column("cstring").CountAll("total")
+	q.UseGroupBy().Column("cstring")
+	q.UseOrderBy().DescAlias("total").By(Asc, NewAggregate(Max, Column("cint"))).Asc("cstring")
+
+	formatedSql, args, err := comiler.Compile("source", q)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Error("compile order by alias error", err)
+		return
+	}
+
+	want := "SELECT cstring, COUNT(*) AS `total` FROM ttable GROUP BY cstring ORDER BY `total` DESC, MAX(cint) ASC, cstring ASC ;"
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compile order by alias error", "want:", want, "actual:", formatedSql)
+	}
+}
+
+func TestEmptySets(t *testing.T) {
+	comiler, err := GetCompiler("ansi")
+	if err != nil {
+		t.Error("can not find ansi compiler", err)
+	}
+
+	update := NewUpdate("ttable")
+	update.Where.Equals("cint", 1)
+
+	exps := []Expression{
+		update,
+		NewInsert("ttable"),
+	}
+
+	for i, exp := range exps {
+		formatedSql, _, err := comiler.Compile("source", exp)
+		if err == nil {
+			t.Error("compile empty sets should fail", i, formatedSql)
+		} else if e, ok := err.(*CompileError); !ok || e.Code != CompileInvalid {
+			t.Error("compile empty sets error should be CompileInvalid", i, err)
+		}
+	}
+}
+
+func TestAllowEmptyWhere(t *testing.T) {
+	comiler, err := GetCompiler("mysql")
+	if err != nil {
+		t.Error("can not find mysql compiler", err)
+	}
+
+	d := NewDelete("ttable")
+	u := NewUpdate("ttable").Set("cint", 1)
+	for i, exp := range []Expression{d, u} {
+		if _, _, err := comiler.Compile("source", exp); err == nil {
+			t.Error("compile without where should fail", i)
+		}
+	}
+
+	d.AllowEmptyWhere = true
+	u.AllowEmptyWhere = true
+	cases := []struct {
+		exp  Expression
+		want string
+	}{
+		{d, "DELETE FROM `ttable` ;"},
+		{u, "UPDATE `ttable` SET `cint` = ? ;"},
+	}
+	for i, c := range cases {
+		formatedSql, _, err := comiler.Compile("source", c.exp)
+		t.Log(formatedSql)
+		if err != nil {
+			t.Error("compile with AllowEmptyWhere error", i, err)
+			continue
+		}
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(c.want)) {
+			t.Error("compile with AllowEmptyWhere error", i, "want:", c.want, "actual:", formatedSql)
+		}
+	}
+
+	j := NewDelete("ttable")
+	j.UseFrom("ttable", "t1").InnerJoin("ttable_c", "t2").On("t1.cint", "t2.c_int")
+	if _, _, err := comiler.Compile("source", j); err != nil {
+		t.Error("compile delete with join conditions error", err)
+	}
+}
+
+func TestCountQuery(t *testing.T) {
+	comiler, err := GetCompiler("postgres")
+	if err != nil {
+		t.Error("can not find postgres compiler", err)
+	}
+
+	q := NewQuery("ttable", "t1")
+	q.Select.Column("t1.cstring").Count("t2.c_int", "n")
+	q.From.InnerJoin("ttable_c", "t2").On("t1.cint", "t2.c_int")
+	q.Where.GreaterThan("t1.cint", 1).Equals("t2.c_string", "a")
+	q.UseGroupBy().Column("t1.cstring")
+	q.UseOrderBy().Desc("n")
+	q.Limit(20, 10)
+
+	formatedSql, args, err := comiler.Compile("source", CountQuery(q))
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Error("compile count query error", err)
+		return
+	}
+
+	want := `SELECT COUNT(*) FROM (SELECT t1.cstring, COUNT(t2.c_int) AS "n" FROM ttable AS t1 INNER JOIN ttable_c AS t2 ON t1.cint = t2.c_int WHERE t1.cint > $1 AND t2.c_string = $2 GROUP BY t1.cstring) AS sub ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compile count query error", "want:", want, "actual:", formatedSql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, "a"}) {
+		t.Error("compile count query args error", args)
+	}
+
+	if q.OrderBy == nil || q.Count != 10 || q.Offset != 20 {
+		t.Error("count query should not change source query", q)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	q := NewQuery("ttable", "")
+	q.Where.GreaterThan("cint", 1)
+	q.UseOrderBy().Asc("cint")
+
+	data, count, err := Paginate(q, 3, 10)
+	if err != nil {
+		t.Error("paginate error", err)
+		return
+	}
+	if data.Offset != 20 || data.Count != 10 {
+		t.Error("paginate offset or count error", data.Offset, data.Count)
+	}
+	if q.Offset != 0 || q.Count != 0 {
+		t.Error("paginate should not change source query", q.Offset, q.Count)
+	}
+
+	cases := []struct {
+		driver string
+		data   string
+		count  string
+	}{
+		{"mysql", "SELECT * FROM ttable WHERE cint > ? ORDER BY cint ASC LIMIT 20,10 ;", "SELECT COUNT(*) FROM (SELECT * FROM ttable WHERE cint > ?) AS sub ;"},
+		{"postgres", "SELECT * FROM ttable WHERE cint > $1 ORDER BY cint ASC LIMIT 10 OFFSET 20 ;", "SELECT COUNT(*) FROM (SELECT * FROM ttable WHERE cint > $1) AS sub ;"},
+	}
+
+	for _, c := range cases {
+		comiler, err := GetCompiler(c.driver)
+		if err != nil {
+			t.Error("can not find compiler", c.driver, err)
+			continue
+		}
+
+		pairs := []struct {
+			q    *Query
+			want string
+		}{{data, c.data}, {count, c.count}}
+		for _, pair := range pairs {
+			formatedSql, args, err := comiler.Compile("source", pair.q)
+			t.Log(formatedSql, args)
+			if err != nil {
+				t.Error("compile paginate error", c.driver, err)
+			} else if !strings.EqualFold(removeSpace(formatedSql), removeSpace(pair.want)) {
+				t.Error("compile paginate error", c.driver, "want:", pair.want, "actual:", formatedSql)
+			}
+		}
+	}
+
+	invalid := [][2]int{{0, 10}, {-1, 10}, {1, 0}, {1, -5}}
+	for _, ps := range invalid {
+		if _, _, err := Paginate(q, ps[0], ps[1]); err == nil {
+			t.Error("paginate should fail", ps)
+		}
+	}
+}
+
+func TestInsertColumnCount(t *testing.T) {
+	comiler, err := GetCompiler("postgres")
+	if err != nil {
+		t.Error("can not find postgres compiler", err)
+	}
+
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint", "cstring")
+	insert := NewInsert("ttable_c").Select(q, "c_int", "c_string", "c_bool")
+	if _, _, err := comiler.Compile("source", insert); err == nil {
+		t.Error("compile insert with 3 columns and 2 values should fail")
+	} else {
+		t.Log(err)
+	}
+
+	q = NewQuery("", "")
+	q.From.Table = NewDerivedTable(NewValuesList().Row(1, "a"), "v")
+	insert = NewInsert("ttable_c").Select(q, "c_int", "c_string", "c_bool")
+	if _, _, err := comiler.Compile("source", insert); err == nil {
+		t.Error("compile insert with 3 columns and 2 values row should fail")
+	}
+
+	q = NewQuery("ttable", "")
+	insert = NewInsert("ttable_c").Select(q, "c_int", "c_string", "c_bool")
+	if _, _, err := comiler.Compile("source", insert); err != nil {
+		t.Error("compile insert select * should not validate column count", err)
+	}
+}
+
+func TestAggregateName(t *testing.T) {
+	comiler, err := GetCompiler("ansi")
+	if err != nil {
+		t.Error("can not find ansi compiler", err)
+	}
+
+	q := NewQuery("ttable", "")
+	q.Select.Sum("cint", "total")
+	formatedSql, _, err := comiler.Compile("source", q)
+	t.Log(formatedSql)
+	if err != nil {
+		t.Error("compile sum error", err)
+	}
+
+	for _, name := range []Func{"SUM(cint); DROP TABLE ttable; --", "string_agg_x"} {
+		q = NewQuery("ttable", "")
+		q.Select.Aggregate(name, Column("cint"), "")
+		if _, _, err = comiler.Compile("source", q); err == nil {
+			t.Error("compile unknown aggregate should fail", name)
+		}
+	}
+
+	RegisterAggregate("string_agg_x")
+	defer func() {
+		_aggregatesLock.Lock()
+		delete(_aggregates, "STRING_AGG_X")
+		_aggregatesLock.Unlock()
+	}()
+	if formatedSql, _, err = comiler.Compile("source", q); err != nil {
+		t.Error("compile registered aggregate error", err)
+	}
+	t.Log(formatedSql)
+}
+
+func TestFuncCall(t *testing.T) {
+	comiler, err := GetCompiler("mysql")
+	if err != nil {
+		t.Error("can not find mysql compiler", err)
+	}
+
+	q := NewQuery("ttable", "")
+	q.Select.
+		Exp(NewFuncCall(Coalesce, Column("cstring"), "none"), "cstring").
+		Exp(NewFuncCall(Concat, Column("cstring"), Sql("' '"), Column("cint")), "full")
+	q.Where.Condition(Equals, NewFuncCall(Lower, Column("cstring")), &Value{Value: "abc"})
+
+	formatedSql, args, err := comiler.Compile("source", q)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Error("compile function error", err)
+		return
+	}
+
+	want := "SELECT COALESCE(cstring, ?) AS `cstring`, CONCAT(cstring, ' ', cint) AS `full` FROM ttable WHERE LOWER(cstring) = ? ;"
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compile function error", "want:", want, "actual:", formatedSql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"none", "abc"}) {
+		t.Error("compile function args error", args)
+	}
+
+	q = NewQuery("ttable", "")
+	q.Select.Exp(NewFuncCall("LOWER(cstring); DROP TABLE ttable; --"), "")
+	if _, _, err = comiler.Compile("source", q); err == nil {
+		t.Error("compile invalid function name should fail")
+	}
+}
+
+func TestCast(t *testing.T) {
+	cases := []struct {
+		driver    string
+		shorthand bool
+		want      string
+	}{
+		{"ansi", false, `SELECT CAST(cstring AS INTEGER) AS "n" FROM ttable WHERE CAST(cstring AS INTEGER) > ? ;`},
+		{"postgres", false, `SELECT CAST(cstring AS INTEGER) AS "n" FROM ttable WHERE CAST(cstring AS INTEGER) > $1 ;`},
+		{"postgres", true, `SELECT cstring::INTEGER AS "n" FROM ttable WHERE cstring::INTEGER > $1 ;`},
+		{"mysql", true, "SELECT CAST(cstring AS SIGNED) AS `n` FROM ttable WHERE CAST(cstring AS SIGNED) > ? ;"},
+	}
+
+	for _, c := range cases {
+		comiler, err := GetCompiler(c.driver)
+		if err != nil {
+			t.Error("can not find compiler", c.driver, err)
+			continue
+		}
+
+		cast := NewCast(Column("cstring"), ansi.Int)
+		cast.Shorthand = c.shorthand
+		q := NewQuery("ttable", "")
+		q.Select.Exp(cast, "n")
+		q.Where.Condition(GreaterThan, cast, &Value{Value: 1})
+
+		formatedSql, args, err := comiler.Compile("source", q)
+		t.Log(formatedSql, args)
+		if err != nil {
+			t.Error("compile cast error", c.driver, err)
+			continue
+		}
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(c.want)) {
+			t.Error("compile cast error", c.driver, "want:", c.want, "actual:", formatedSql)
+		}
+	}
+
+	comiler, _ := GetCompiler("ansi")
+	q := NewQuery("ttable", "")
+	q.Select.Exp(NewCast(Column("cstring"), ansi.Var), "")
+	if _, _, err := comiler.Compile("source", q); err == nil {
+		t.Error("compile cast to unsupported type should fail")
+	}
+}
+
+func TestCompareColumn(t *testing.T) {
+	comiler, err := GetCompiler("postgres")
+	if err != nil {
+		t.Error("can not find postgres compiler", err)
+	}
+
+	q := NewQuery("ta", "a")
+	q.From.InnerJoin("tb", "b").On("a.id", "b.a_id")
+	q.Where.EqualsColumn("a.cint", "b.cint").CompareColumn(LessThan, "a.ctime", "b.ctime")
+
+	formatedSql, args, err := comiler.Compile("source", q)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Error("compile column compare error", err)
+		return
+	}
+
+	want := `SELECT * FROM ta AS a INNER JOIN tb AS b ON a.id = b.a_id WHERE a.cint = b.cint AND a.ctime < b.ctime ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compile column compare error", "want:", want, "actual:", formatedSql)
+	}
+	if len(args) != 0 {
+		t.Error("compile column compare should not have args", args)
+	}
+}
+
+func TestCollate(t *testing.T) {
+	cases := []struct {
+		driver    string
+		collation string
+		want      string
+	}{
+		{"postgres", "en_US", `SELECT * FROM ttable WHERE cstring COLLATE "en_US" = $1 ORDER BY cstring COLLATE "en_US" ASC, cint DESC ;`},
+		{"mysql", "utf8mb4_unicode_ci", "SELECT * FROM ttable WHERE cstring COLLATE utf8mb4_unicode_ci = ? ORDER BY cstring COLLATE utf8mb4_unicode_ci ASC, cint DESC ;"},
+	}
+
+	for _, c := range cases {
+		comiler, err := GetCompiler(c.driver)
+		if err != nil {
+			t.Error("can not find compiler", c.driver, err)
+			continue
+		}
+
+		q := NewQuery("ttable", "")
+		q.Where.CompareCollate(Equals, "cstring", "a", c.collation)
+		q.UseOrderBy().ByCollate(Asc, Column("cstring"), c.collation).Desc("cint")
+
+		formatedSql, args, err := comiler.Compile("source", q)
+		t.Log(formatedSql, args)
+		if err != nil {
+			t.Error("compile collate error", c.driver, err)
+			continue
+		}
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(c.want)) {
+			t.Error("compile collate error", c.driver, "want:", c.want, "actual:", formatedSql)
+		}
+	}
+
+	comiler, _ := GetCompiler("mysql")
+	q := NewQuery("ttable", "")
+	q.UseOrderBy().ByCollate(Asc, Column("cstring"), "x; DROP TABLE ttable")
+	if _, _, err := comiler.Compile("source", q); err == nil {
+		t.Error("compile invalid collation should fail")
+	}
+}
+
+func TestSplitIn(t *testing.T) {
+	comiler := &SqlDriver{Dialecter: MysqlDialecter{}, MaxInItems: 100}
+
+	values := make([]interface{}, 1000)
+	for i := 0; i < len(values); i++ {
+		values[i] = i
+	}
+
+	q := NewQuery("ttable", "")
+	q.Where.In("cint", values).NotIn("cstring", []string{"a", "b", "c"}).Equals("cbool", true)
+
+	formatedSql, args, err := comiler.Compile("source", q)
+	if err != nil {
+		t.Error("compile split in error", err)
+		return
+	}
+	if n := strings.Count(formatedSql, "cint IN ("); n != 10 {
+		t.Error("compile split in should have 10 chunks", n)
+	}
+	if n := strings.Count(formatedSql, " OR "); n != 9 {
+		t.Error("compile split in should have 9 OR", n)
+	}
+	if len(args) != 1004 || args[0] != 0 || args[999] != 999 || args[1003] != true {
+		t.Error("compile split in args error", len(args))
+	}
+
+	comiler.MaxInItems = 2
+	q = NewQuery("ttable", "")
+	q.Where.NotIn("cint", [5]int{1, 2, 3, 4, 5})
+	formatedSql, args, err = comiler.Compile("source", q)
+	t.Log(formatedSql, args)
+	want := "SELECT * FROM ttable WHERE (cint NOT IN (1, 2) AND cint NOT IN (3, 4) AND cint NOT IN (5)) ;"
+	if err != nil {
+		t.Error("compile split not in error", err)
+	} else if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compile split not in error", "want:", want, "actual:", formatedSql)
+	}
+
+	sqlite := &SqlDriver{Dialecter: SqliteDialecter{}}
+	if max := dialectMaxParameters(sqlite.Dialecter); max != 32766 {
+		t.Error("sqlite max parameters error", max)
+	}
+	q = NewQuery("ttable", "")
+	q.Where.In("cint", values)
+	if _, _, err = sqlite.Compile("source", q); err != nil {
+		t.Error("compile 1000 parameters on sqlite error", err)
+	}
+
+	many := make([]interface{}, 32767)
+	for i := 0; i < len(many); i++ {
+		many[i] = i
+	}
+	q = NewQuery("ttable", "")
+	q.Where.In("cint", many)
+	if _, _, err = sqlite.Compile("source", q); err == nil {
+		t.Error("compile with too many parameters should fail")
+	}
+}
+
+func TestArrayIn(t *testing.T) {
+	values := []int{1, 2, 3}
+
+	pg := &SqlDriver{Dialecter: PostgreSQLDialecter{}, ArrayIn: true}
+	q := NewQuery("ttable", "")
+	q.Where.In("cint", values).NotIn("cstring", [2]string{"a", "b"})
+
+	formatedSql, args, err := pg.Compile("source", q)
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Error("compile array in error", err)
+		return
+	}
+	want := `SELECT * FROM ttable WHERE cint = ANY($1) AND cstring <> ALL($2) ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compile array in error", "want:", want, "actual:", formatedSql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{arrayValue{values}, arrayValue{[]string{"a", "b"}}}) {
+		t.Error("compile array in args error", args)
+	}
+	for i, want := range []string{`{1,2,3}`, `{"a","b"}`} {
+		valuer, ok := args[i].(sqldriver.Valuer)
+		if !ok {
+			t.Errorf("array arg should be driver.Valuer, actual=[%T]", args[i])
+			continue
+		}
+		if v, err := valuer.Value(); err != nil || v != want {
+			t.Errorf("array arg value error, want=[%v]; actual=[%v]; err=%v", want, v, err)
+		}
+	}
+
+	s := "x"
+	v, err := arrayValue{[]interface{}{`a"b\c`, nil, &s, 1.5, true}}.Value()
+	if want := `{"a\"b\\c",NULL,"x",1.5,true}`; err != nil || v != want {
+		t.Errorf("array value error, want=[%v]; actual=[%v]; err=%v", want, v, err)
+	}
+
+	mysql := &SqlDriver{Dialecter: MysqlDialecter{}, ArrayIn: true}
+	q = NewQuery("ttable", "")
+	q.Where.In("cstring", []string{"a", "b", "c"})
+	formatedSql, args, err = mysql.Compile("source", q)
+	t.Log(formatedSql, args)
+	want = "SELECT * FROM ttable WHERE cstring IN (?, ?, ?) ;"
+	if err != nil {
+		t.Error("compile mysql in error", err)
+	} else if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compile mysql in error", "want:", want, "actual:", formatedSql)
+	}
+	if len(args) != 3 {
+		t.Error("compile mysql in should have 3 args", args)
+	}
+}
+
+func TestParameterNames(t *testing.T) {
+	comiler := &SqlDriver{Dialecter: OracleSQLDialecter{}}
+
+	q := NewQuery("ttable", "")
+	min := &Parameter{Name: "min", Value: 1}
+	q.Where.GreaterThan("cint", min).Equals("cstring", "a").LessThan("cbigint", min).In("cfloat", []interface{}{1.1, 2.2})
+
+	formatedSql, args, names, err := comiler.CompileNamed("source", q)
+	t.Log(formatedSql, args, names)
+	if err != nil {
+		t.Error("compile named error", err)
+		return
+	}
+
+	wantNames := []string{"min", "pv2", "pv3", "pv4"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Error("parameter names error", names, wantNames)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, "a", 1.1, 2.2}) {
+		t.Error("parameter args error", args)
+	}
+	for i := 0; i < len(names); i++ {
+		if !strings.Contains(formatedSql, ":"+names[i]) {
+			t.Error("parameter name isn't in sql", names[i])
+		}
+	}
+
+	mysql := &SqlDriver{Dialecter: MysqlDialecter{}}
+	if _, args, names, err = mysql.CompileNamed("source", q); err != nil || names != nil || len(args) != 5 {
+		t.Error("compile named of mysql error", args, names, err)
+	}
+
+	q = NewQuery("ttable", "")
+	q.Where.Equals("cstring", "a").Equals("cint", &Parameter{Name: "pv2", Value: 1})
+	if _, _, _, err = comiler.CompileNamed("source", q); err == nil {
+		t.Error("compile named with reserved parameter name should fail")
+	}
+}
+
+func TestQueryWithoutFrom(t *testing.T) {
+	cases := map[string]string{
+		"mysql":    "SELECT NOW() AS `now`, 1 + 1 AS `x` ;",
+		"postgres": `SELECT NOW() AS "now", 1 + 1 AS "x" ;`,
+	}
+
+	for driver, want := range cases {
+		comiler, err := GetCompiler(driver)
+		if err != nil {
+			t.Error("can not find compiler", driver, err)
+			continue
+		}
+
+		q := NewQuery("", "")
+		q.Select.Exp(Raw("NOW()"), "now").Exp(Raw("1 + 1"), "x")
+
+		formatedSql, args, err := comiler.Compile("source", q)
+		t.Log(formatedSql, args)
+		if err != nil {
+			t.Error("compile query without from error", driver, err)
+			continue
+		}
+
+		if strings.Contains(strings.ToUpper(formatedSql), ansi.From) {
+			t.Error("query without from should not write FROM", driver, formatedSql)
+		}
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+			t.Error("compile query without from error", driver, "want:", want, "actual:", formatedSql)
+		}
+
+		if q.From.String() != "" {
+			t.Error("string of empty from should be empty", q.From.String())
+		}
+	}
+}
+
+func TestDualTable(t *testing.T) {
+	cases := map[string]string{
+		"goracle":  `SELECT 1 AS x FROM DUAL`,
+		"mysql":    "SELECT 1 AS `x` ;",
+		"postgres": `SELECT 1 AS "x" ;`,
+	}
+
+	for driver, want := range cases {
+		comiler, err := GetCompiler(driver)
+		if err != nil {
+			t.Error("can not find compiler", driver, err)
+			continue
+		}
+
+		q := NewQuery("", "")
+		q.Select.Exp(Raw("1"), "x")
+
+		formatedSql, _, err := comiler.Compile("source", q)
+		t.Log(formatedSql)
+		if err != nil {
+			t.Error("compile query without from error", driver, err)
+			continue
+		}
+
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+			t.Error("compile dual table error", driver, "want:", want, "actual:", formatedSql)
+		}
+	}
+
+	comiler, _ := GetCompiler("goracle")
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint")
+	formatedSql, _, err := comiler.Compile("source", q)
+	if err != nil || strings.Contains(strings.ToUpper(formatedSql), "DUAL") {
+		t.Error("query with table should not use dual table", formatedSql, err)
+	}
+}
+
+func TestGroupByRollup(t *testing.T) {
+	newQuery := func() *Query {
+		q := NewQuery("ttable", "")
+		q.Select.Column("cstring", "cint").Sum("cfloat", "total")
+		q.UseGroupBy().Column("cstring", "cint")
+		return q
+	}
+
+	cases := []struct {
+		driver   string
+		grouping GroupingSet
+		want     string
+	}{
+		{"mysql", Rollup, "SELECT cstring, cint, SUM(cfloat) AS `total` FROM ttable GROUP BY cstring, cint WITH ROLLUP ;"},
+		{"postgres", Rollup, `SELECT cstring, cint, SUM(cfloat) AS "total" FROM ttable GROUP BY ROLLUP (cstring, cint) ;`},
+		{"postgres", Cube, `SELECT cstring, cint, SUM(cfloat) AS "total" FROM ttable GROUP BY CUBE (cstring, cint) ;`},
+		{"clickhouse", Cube, "SELECT cstring, cint, SUM(cfloat) AS `total` FROM ttable GROUP BY cstring, cint WITH CUBE ;"},
+	}
+
+	for _, c := range cases {
+		comiler, err := GetCompiler(c.driver)
+		if err != nil {
+			t.Error("can not find compiler", c.driver, err)
+			continue
+		}
+
+		q := newQuery()
+		q.GroupBy.Grouping = c.grouping
+		formatedSql, _, err := comiler.Compile("source", q)
+		t.Log(formatedSql)
+		if err != nil {
+			t.Error("compile group by", c.grouping, "error", c.driver, err)
+			continue
+		}
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(c.want)) {
+			t.Error("compile group by", c.grouping, "error", c.driver, "want:", c.want, "actual:", formatedSql)
+		}
+	}
+
+	comiler, _ := GetCompiler("mysql")
+	q := newQuery()
+	q.GroupBy.Cube()
+	if _, _, err := comiler.Compile("source", q); err == nil {
+		t.Error("mysql should not support group by cube")
+	}
+	comiler, _ = GetCompiler("sqlite3")
+	if _, _, err := comiler.Compile("source", q); err == nil {
+		t.Error("sqlite should not support group by cube")
+	}
+	comiler, _ = GetCompiler("mysql")
+
+	q.GroupBy.Grouping = "GROUPING SETS"
+	if _, _, err := comiler.Compile("source", q); err == nil {
+		t.Error("compile invalid grouping set should fail")
+	}
+}
+
+func TestAggregateFilter(t *testing.T) {
+	newQuery := func() *Query {
+		q := NewQuery("ttable", "")
+		q.Select.Exp(NewAggregate(Sum, Column("cfloat")).Where(func(c *Conditions) {
+			c.GreaterThan("cint", 1)
+		}), "total")
+		q.Select.Exp(NewAggregate(Count, nil).Where(func(c *Conditions) {
+			c.Equals("cstring", "a")
+		}), "c")
+		return q
+	}
+
+	cases := map[string]string{
+		"postgres": `SELECT SUM(cfloat) FILTER (WHERE cint > $1) AS "total", COUNT(*) FILTER (WHERE cstring = $2) AS "c" FROM ttable ;`,
+		"mysql":    "SELECT SUM(CASE WHEN cint > ? THEN cfloat END) AS `total`, COUNT(CASE WHEN cstring = ? THEN 1 END) AS `c` FROM ttable ;",
+	}
+
+	for driver, want := range cases {
+		comiler, err := GetCompiler(driver)
+		if err != nil {
+			t.Error("can not find compiler", driver, err)
+			continue
+		}
+
+		formatedSql, args, err := comiler.Compile("source", newQuery())
+		t.Log(formatedSql, args)
+		if err != nil {
+			t.Error("compile aggregate filter error", driver, err)
+			continue
+		}
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+			t.Error("compile aggregate filter error", driver, "want:", want, "actual:", formatedSql)
+		}
+		if !reflect.DeepEqual(args, []interface{}{1, "a"}) {
+			t.Error("compile aggregate filter args error", driver, args)
+		}
+	}
+}
+
+func TestParamOffset(t *testing.T) {
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint")
+	q.Where.Equals("cint", 1).Equals("cstring", "a")
+
+	sc := NewStmtCompiler(PostgreSQLDialecter{}).WithParamOffset(3)
+	formatedSql, args, err := sc.Compile(q, "source")
+	t.Log(formatedSql, args)
+	if err != nil {
+		t.Fatal("compile with param offset error", err)
+	}
+
+	want := `SELECT cint FROM ttable WHERE cint = $4 AND cstring = $5 ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compile with param offset error", "want:", want, "actual:", formatedSql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, "a"}) {
+		t.Error("compile with param offset args error", args)
+	}
+
+	// offset is kept by next compile
+	if formatedSql, _, _ = sc.Compile(q, "source"); !strings.Contains(formatedSql, "$4") {
+		t.Error("param offset should be kept after reset", formatedSql)
+	}
+
+	formatedSql, _, _ = sc.WithParamOffset(0).Compile(q, "source")
+	if !strings.Contains(formatedSql, "$1") {
+		t.Error("compile without param offset error", formatedSql)
+	}
+
+	max := dialectMaxParameters(sc.Dialecter)
+	if _, _, err = sc.WithParamOffset(max-2).Compile(q, "source"); err != nil {
+		t.Error("compile with param offset under max error", err)
+	}
+	if _, _, err = sc.WithParamOffset(max-1).Compile(q, "source"); err == nil {
+		t.Error("compile with param offset over max should fail")
+	}
+}
+
+func TestSchemaTable(t *testing.T) {
+	cases := map[string]string{
+		"mysql":    "SELECT cint FROM db.ttable AS t WHERE cint = ? ;",
+		"postgres": `SELECT cint FROM public.ttable AS t WHERE cint = $1 ;`,
+	}
+
+	for driver, want := range cases {
+		comiler, err := GetCompiler(driver)
+		if err != nil {
+			t.Error("can not find compiler", driver, err)
+			continue
+		}
+
+		schema := "public"
+		if driver == "mysql" {
+			schema = "db"
+		}
+		q := NewQuery("ttable", "t")
+		q.From.Table.InSchema(schema)
+		q.Select.Column("cint")
+		q.Where.Equals("cint", 1)
+
+		formatedSql, _, err := comiler.Compile("source", q)
+		t.Log(formatedSql)
+		if err != nil {
+			t.Error("compile schema table error", driver, err)
+			continue
+		}
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+			t.Error("compile schema table error", driver, "want:", want, "actual:", formatedSql)
+		}
+	}
+
+	comiler, _ := GetCompiler("postgres")
+	d := NewDelete("ttable")
+	d.Table.InCatalog("db", "public")
+	d.Where.Equals("cint", 1)
+	formatedSql, _, err := comiler.Compile("source", d)
+	if err != nil || !strings.Contains(formatedSql, `"db"."public"."ttable"`) {
+		t.Error("compile catalog table error", formatedSql, err)
+	}
+
+	comiler, _ = GetCompiler("mysql")
+	if _, _, err = comiler.Compile("source", d); err == nil {
+		t.Error("mysql should not support catalog of table")
+	}
+	q := NewQuery("ttable", "")
+	q.From.Table.InCatalog("db", "public")
+	if _, _, err = comiler.Compile("source", q); err == nil {
+		t.Error("mysql should not support catalog of query table")
+	}
+
+	// schema of query table isn't quoted, so it must be a plain identifier
+	comiler, _ = GetCompiler("postgres")
+	q = NewQuery("ttable", "")
+	q.From.Table.InSchema("public; DROP")
+	if formatedSql, _, err = comiler.Compile("source", q); err == nil {
+		t.Error("compile invalid schema should fail", formatedSql)
+	}
+}
+
+func TestSampleTable(t *testing.T) {
+	newQuery := func() *Query {
+		q := NewQuery("big_table", "t")
+		q.From.Table.TableSample("system", 10)
+		q.Select.Column("cint")
+		return q
+	}
+
+	comiler, _ := GetCompiler("postgres")
+	formatedSql, _, err := comiler.Compile("source", newQuery())
+	t.Log(formatedSql)
+	if err != nil {
+		t.Fatal("compile table sample error", err)
+	}
+	want := `SELECT cint FROM big_table AS t TABLESAMPLE SYSTEM (10) ;`
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compile table sample error", "want:", want, "actual:", formatedSql)
+	}
+
+	comiler, _ = GetCompiler("lodbc")
+	formatedSql, _, err = comiler.Compile("source", newQuery())
+	if err != nil || !strings.Contains(formatedSql, "TABLESAMPLE SYSTEM (10 PERCENT)") {
+		t.Error("compile mssql table sample error", formatedSql, err)
+	}
+
+	for _, driver := range []string{"mysql", "sqlite3"} {
+		comiler, _ = GetCompiler(driver)
+		if _, _, err = comiler.Compile("source", newQuery()); err == nil {
+			t.Error("table sample should be unsupported", driver)
+		} else if ce, ok := err.(*CompileError); !ok || ce.Code != CompileUnsupported {
+			t.Error("table sample error should be unsupported", driver, err)
+		}
+	}
+
+	comiler, _ = GetCompiler("postgres")
+	q := newQuery()
+	q.From.Table.TableSample("random", 10)
+	if _, _, err = comiler.Compile("source", q); err == nil {
+		t.Error("invalid table sample method should fail")
+	}
+	q.From.Table.TableSample("bernoulli", 0)
+	if _, _, err = comiler.Compile("source", q); err == nil {
+		t.Error("invalid table sample percent should fail")
+	}
+}
+
+func TestIndexHint(t *testing.T) {
+	comiler, _ := GetCompiler("mysql")
+
+	q := NewQuery("ttable", "t")
+	q.From.Table.UseIndex("idx_cint", "idx_cstring").ForceIndex("PRIMARY")
+	q.Select.Column("cint")
+	q.Where.Equals("cint", 1)
+
+	formatedSql, _, err := comiler.Compile("source", q)
+	t.Log(formatedSql)
+	if err != nil {
+		t.Fatal("compile index hint error", err)
+	}
+	want := "SELECT cint FROM ttable AS t USE INDEX (`idx_cint`, `idx_cstring`) FORCE INDEX (`PRIMARY`) WHERE cint = ? ;"
+	if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compile index hint error", "want:", want, "actual:", formatedSql)
+	}
+
+	comiler, _ = GetCompiler("postgres")
+	if _, _, err = comiler.Compile("source", q); err == nil {
+		t.Error("postgres should not support index hint")
+	}
+
+	comiler, _ = GetCompiler("mysql")
+	q = NewQuery("ttable", "")
+	q.From.Table.IndexHint(UseIndex)
+	if _, _, err = comiler.Compile("source", q); err == nil {
+		t.Error("index hint without index should fail")
+	}
+}
+
+func TestWhereMap(t *testing.T) {
+	comiler, _ := GetCompiler("postgres")
+
+	m := map[string]interface{}{"status": "active", "org_id": 7, "deleted_at": nil, "cint": 1}
+	for i := 0; i < 5; i++ {
+		q := NewQuery("ttable", "")
+		q.Select.Column("cint")
+		q.Where = WhereMap(m)
+
+		formatedSql, args, err := comiler.Compile("source", q)
+		if err != nil {
+			t.Fatal("compile where map error", err)
+		}
+
+		want := `SELECT cint FROM ttable WHERE cint = $1 AND deleted_at IS NULL AND org_id = $2 AND status = $3 ;`
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+			t.Error("compile where map error", "want:", want, "actual:", formatedSql)
+		}
+		if !reflect.DeepEqual(args, []interface{}{1, 7, "active"}) {
+			t.Error("compile where map args error", args)
+		}
+	}
+
+	if w := WhereMap(nil); !w.isEmpty() {
+		t.Error("where of empty map should be empty")
+	}
+}
+
+func TestEmptyIn(t *testing.T) {
+	comiler, _ := GetCompiler("postgres")
+
+	cases := []struct {
+		op    Operator
+		value interface{}
+		want  string
+	}{
+		{In, []int{}, `SELECT cint FROM ttable WHERE 1=0 AND cstring = $1 ;`},
+		{NotIn, []string{}, `SELECT cint FROM ttable WHERE 1=1 AND cstring = $1 ;`},
+		{In, [0]int{}, `SELECT cint FROM ttable WHERE 1=0 AND cstring = $1 ;`},
+	}
+
+	for _, c := range cases {
+		q := NewQuery("ttable", "")
+		q.Select.Column("cint")
+		q.Where.Compare(c.op, "cint", c.value).Equals("cstring", "a")
+
+		formatedSql, args, err := comiler.Compile("source", q)
+		t.Log(formatedSql, args)
+		if err != nil {
+			t.Error("compile empty in error", c.op, err)
+			continue
+		}
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(c.want)) {
+			t.Error("compile empty in error", "want:", c.want, "actual:", formatedSql)
+		}
+		if !reflect.DeepEqual(args, []interface{}{"a"}) {
+			t.Error("empty in should not have args", args)
+		}
+	}
+}
+
+type int16Slice []int16
+
+func TestInSliceTypes(t *testing.T) {
+	comiler, _ := GetCompiler("ansi")
+	t1 := time.Date(2013, 1, 1, 1, 2, 3, 0, time.UTC)
+	t2 := time.Date(2014, 1, 1, 1, 2, 3, 0, time.UTC)
+
+	cases := []struct {
+		value interface{}
+		want  string
+		args  []interface{}
+	}{
+		{[]uint64{1, 18446744073709551615}, `cint IN (1, 18446744073709551615)`, nil},
+		{[]int32{-1, 2}, `cint IN (-1, 2)`, nil},
+		{[]bool{true, false}, `cint IN ( ? , ? )`, []interface{}{true, false}},
+		{[]time.Time{t1, t2}, `cint IN ( ? , ? )`, []interface{}{t1, t2}},
+		{int16Slice{1, 2}, `cint IN ( ? , ? )`, []interface{}{int16(1), int16(2)}},
+	}
+
+	for _, c := range cases {
+		q := NewQuery("ttable", "")
+		q.Select.Column("cint")
+		q.Where.In("cint", c.value)
+
+		formatedSql, args, err := comiler.Compile("source", q)
+		t.Log(formatedSql, args)
+		if err != nil {
+			t.Errorf("compile in %T error: %v", c.value, err)
+			continue
+		}
+
+		want := `SELECT cint FROM ttable WHERE ` + c.want + ` ;`
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+			t.Errorf("compile in %T error, want: %s, actual: %s", c.value, want, formatedSql)
+		}
+		if len(args) != len(c.args) || (len(c.args) > 0 && !reflect.DeepEqual(args, c.args)) {
+			t.Errorf("compile in %T args error, want: %v, actual: %v", c.value, c.args, args)
+		}
+	}
+}
+
+func TestInNull(t *testing.T) {
+	var nilPtr *int
+	cases := []struct {
+		mode  InNullMode
+		op    Operator
+		value interface{}
+		want  string
+	}{
+		{InNullDrop, In, []interface{}{1, nil, 2}, `cint IN ($1, $2)`},
+		{InNullDrop, NotIn, []interface{}{1, nilPtr}, `cint NOT IN ($1)`},
+		{InNullDrop, In, []interface{}{nil}, `1=0`},
+		{InNullMatch, In, []interface{}{1, nil, 2}, `(cint IN ($1, $2) OR cint IS NULL)`},
+		{InNullMatch, NotIn, []interface{}{1, nil}, `(cint NOT IN ($1) AND cint IS NOT NULL)`},
+		{InNullMatch, In, []interface{}{nil}, `cint IS NULL`},
+		{InNullMatch, In, []interface{}{1, 2}, `cint IN ($1, $2)`},
+	}
+
+	for _, c := range cases {
+		driver := &SqlDriver{Dialecter: PostgreSQLDialecter{}, InNull: c.mode}
+
+		q := NewQuery("ttable", "")
+		q.Select.Column("cint")
+		q.Where.Compare(c.op, "cint", c.value)
+
+		formatedSql, args, err := driver.Compile("source", q)
+		t.Log(formatedSql, args)
+		if err != nil {
+			t.Error("compile in null error", c.mode, c.op, err)
+			continue
+		}
+
+		want := `SELECT cint FROM ttable WHERE ` + c.want + ` ;`
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+			t.Error("compile in null error", c.mode, "want:", want, "actual:", formatedSql)
+		}
+		for _, arg := range args {
+			if arg == nil || arg == interface{}(nilPtr) {
+				t.Error("nil should not be bound as arg", args)
+			}
+		}
+	}
+}
+
+func TestSetOperation(t *testing.T) {
+	newQuery := func(op SetOperator) *Query {
+		q := NewQuery("ttable", "")
+		q.Select.Column("cint")
+		q.Where.Equals("cstring", "a")
+		other := NewQuery("ttable2", "")
+		other.Select.Column("cint")
+		other.Where.Equals("cstring", "b")
+		q.Combine(op, other)
+		q.UseOrderBy().Asc("cint")
+		return q
+	}
+
+	cases := []struct {
+		driver string
+		op     SetOperator
+		want   string
+	}{
+		{"postgres", Except, `SELECT cint FROM ttable WHERE cstring = $1 EXCEPT SELECT cint FROM ttable2 WHERE cstring = $2 ORDER BY cint ASC ;`},
+		{"postgres", IntersectAll, `SELECT cint FROM ttable WHERE cstring = $1 INTERSECT ALL SELECT cint FROM ttable2 WHERE cstring = $2 ORDER BY cint ASC ;`},
+		{"mysql", UnionAll, `SELECT cint FROM ttable WHERE cstring = ? UNION ALL SELECT cint FROM ttable2 WHERE cstring = ? ORDER BY cint ASC ;`},
+		{"goracle", Except, `SELECT cint FROM ttable WHERE cstring = :pv1 MINUS SELECT cint FROM ttable2 WHERE cstring = :pv2 ORDER BY cint ASC`},
+	}
+
+	for _, c := range cases {
+		comiler, err := GetCompiler(c.driver)
+		if err != nil {
+			t.Error("can not find compiler", c.driver, err)
+			continue
+		}
+
+		formatedSql, args, err := comiler.Compile("source", newQuery(c.op))
+		t.Log(formatedSql)
+		if err != nil {
+			t.Error("compile set operation", c.op, "error", c.driver, err)
+			continue
+		}
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(c.want)) {
+			t.Error("compile set operation", c.op, "error", c.driver, "want:", c.want, "actual:", formatedSql)
+		}
+		if !reflect.DeepEqual(args, []interface{}{"a", "b"}) {
+			t.Error("compile set operation", c.op, "args error", c.driver, args)
+		}
+	}
+
+	comiler, _ := GetCompiler("mysql")
+	for _, op := range []SetOperator{Except, ExceptAll, Intersect, IntersectAll} {
+		_, _, err := comiler.Compile("source", newQuery(op))
+		if err == nil {
+			t.Error("mysql should not support", op)
+			continue
+		}
+		if ce, ok := err.(*CompileError); !ok || ce.Code != CompileUnsupported {
+			t.Error("mysql", op, "should return unsupported error", err)
+		}
+	}
+
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint")
+	other := NewQuery("ttable2", "")
+	other.Select.Column("cint")
+	other.UseOrderBy().Desc("cint")
+	other.Limit(0, 1)
+	q.Union(other)
+	comiler, _ = GetCompiler("postgres")
+	formatedSql, _, err := comiler.Compile("source", q)
+	want := `SELECT cint FROM ttable UNION (SELECT cint FROM ttable2 ORDER BY cint DESC LIMIT 1) ;`
+	if err != nil || !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compile union with limit error", "want:", want, "actual:", formatedSql, err)
+	}
+}
+
+func TestLateralJoin(t *testing.T) {
+	newQuery := func() *Query {
+		sub := NewQuery("torder", "o")
+		sub.Select.Column("o.amount")
+		sub.Where.EqualsColumn("o.uid", "u.id").GreaterThan("o.amount", 10)
+		sub.UseOrderBy().Desc("o.amount")
+		sub.Limit(0, 3)
+
+		q := NewQuery("tuser", "u")
+		q.Select.Column("u.id", "t.amount")
+		q.From.LateralJoin(LeftJoin, sub, "t")
+		q.Where.Equals("u.name", "a")
+		return q
+	}
+
+	comiler, _ := GetCompiler("postgres")
+	formatedSql, args, err := comiler.Compile("source", newQuery())
+	t.Log(formatedSql)
+	want := `SELECT u.id, t.amount FROM tuser AS u LEFT JOIN LATERAL (SELECT o.amount FROM torder AS o WHERE o.uid = u.id AND o.amount > $1 ORDER BY o.amount DESC LIMIT 3) AS t ON TRUE WHERE u.name = $2 ;`
+	if err != nil || !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compile lateral join error", "want:", want, "actual:", formatedSql, err)
+	}
+	if !reflect.DeepEqual(args, []interface{}{10, "a"}) {
+		t.Error("compile lateral join args error", args)
+	}
+
+	comiler, _ = GetCompiler("mysql")
+	if _, _, err := comiler.Compile("source", newQuery()); err == nil {
+		t.Error("mysql should not support lateral join")
+	}
+
+	q := NewQuery("tuser", "u")
+	q.Select.Column("u.id")
+	q.From.LeftJoin("torder", "o").Lateral = true
+	comiler, _ = GetCompiler("postgres")
+	if _, _, err := comiler.Compile("source", q); err == nil {
+		t.Error("lateral join of table should fail")
+	}
+}
+
+func TestOmitTerminator(t *testing.T) {
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint")
+	q.Where.Equals("cint", 1)
+
+	cases := []struct {
+		omit bool
+		want string
+	}{
+		{false, `SELECT cint FROM ttable WHERE cint = ? ;`},
+		{true, `SELECT cint FROM ttable WHERE cint = ?`},
+	}
+
+	for _, c := range cases {
+		sc := NewStmtCompiler(MysqlDialecter{})
+		sc.OmitTerminator = c.omit
+		formatedSql, _, err := sc.Compile(q, "source")
+		t.Log(formatedSql)
+		if err != nil {
+			t.Error("compile omit terminator", c.omit, "error", err)
+			continue
+		}
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(c.want)) {
+			t.Error("compile omit terminator", c.omit, "error", "want:", c.want, "actual:", formatedSql)
+		}
+	}
+
+	driver := &SqlDriver{Dialecter: PostgreSQLDialecter{}, OmitTerminator: true}
+	u := NewUpdate("ttable")
+	u.Set("cstring", "a")
+	u.Where.Equals("cint", 1)
+	formatedSql, _, err := driver.Compile("source", u)
+	if err != nil || strings.Contains(formatedSql, ";") {
+		t.Error("driver omit terminator error", formatedSql, err)
+	}
+}
+
+func TestOrderByCase(t *testing.T) {
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint", "cstring")
+	q.Where.Equals("cint", 1)
+	q.UseOrderBy().
+		By(Asc, NewCase().When(func(c *Conditions) { c.Equals("cstring", "a") }, 0).Otherwise(1)).
+		By(Desc, Raw("CASE WHEN cfloat > ? THEN 0 ELSE 1 END", 1.5))
+	q.Limit(10, 5)
+
+	cases := []struct {
+		driver string
+		want   string
+	}{
+		{"mysql", `SELECT cint, cstring FROM ttable WHERE cint = ? ORDER BY CASE WHEN cstring = ? THEN ? ELSE ? END ASC, CASE WHEN cfloat > ? THEN 0 ELSE 1 END DESC LIMIT 10,5 ;`},
+		{"postgres", `SELECT cint, cstring FROM ttable WHERE cint = $1 ORDER BY CASE WHEN cstring = $2 THEN $3 ELSE $4 END ASC, CASE WHEN cfloat > $5 THEN 0 ELSE 1 END DESC LIMIT 5 OFFSET 10 ;`},
+	}
+
+	for _, c := range cases {
+		comiler, err := GetCompiler(c.driver)
+		if err != nil {
+			t.Error("can not find compiler", c.driver, err)
+			continue
+		}
+
+		formatedSql, args, err := comiler.Compile("source", q)
+		t.Log(formatedSql, args)
+		if err != nil {
+			t.Error("compile order by case error", c.driver, err)
+			continue
+		}
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(c.want)) {
+			t.Error("compile order by case error", c.driver, "want:", c.want, "actual:", formatedSql)
+		}
+		if !reflect.DeepEqual(args, []interface{}{1, "a", 0, 1, 1.5}) {
+			t.Error("compile order by case args error", c.driver, args)
+		}
+	}
+
+	comiler, _ := GetCompiler("mysql")
+	q.UseOrderBy().By(Asc, NewCase())
+	if _, _, err := comiler.Compile("source", q); err == nil {
+		t.Error("compile case without when should fail")
+	}
+}
+
+func TestInMapKeys(t *testing.T) {
+	comiler, _ := GetCompiler("ansi")
+
+	cases := []struct {
+		value interface{}
+		want  string
+		args  []interface{}
+	}{
+		{map[int]bool{3: true, 1: true, 2: false}, `cint IN (1, 2, 3)`, nil},
+		{map[string]struct{}{"b": {}, "a": {}}, `cint IN ( ? , ? )`, []interface{}{"a", "b"}},
+		{map[int16]bool{-2: true, 5: true}, `cint IN ( ? , ? )`, []interface{}{int16(-2), int16(5)}},
+		{map[int]bool{}, `1=0`, nil},
+	}
+
+	for _, c := range cases {
+		q := NewQuery("ttable", "")
+		q.Select.Column("cint")
+		q.Where.In("cint", c.value)
+
+		formatedSql, args, err := comiler.Compile("source", q)
+		t.Log(formatedSql, args)
+		if err != nil {
+			t.Errorf("compile in %T error: %v", c.value, err)
+			continue
+		}
+
+		want := `SELECT cint FROM ttable WHERE ` + c.want + ` ;`
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+			t.Errorf("compile in %T error, want: %s, actual: %s", c.value, want, formatedSql)
+		}
+		if len(args) != len(c.args) || (len(c.args) > 0 && !reflect.DeepEqual(args, c.args)) {
+			t.Errorf("compile in %T args error, want: %v, actual: %v", c.value, c.args, args)
+		}
+	}
+
+	driver := &SqlDriver{Dialecter: MysqlDialecter{}, MaxInItems: 2}
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint")
+	q.Where.In("cint", map[int]bool{3: true, 1: true, 2: true})
+	formatedSql, _, err := driver.Compile("source", q)
+	want := `SELECT cint FROM ttable WHERE (cint IN (1, 2) OR cint IN (3)) ;`
+	if err != nil || !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compile chunked in of map error", "want:", want, "actual:", formatedSql, err)
+	}
+}
+
+func TestLimitSql(t *testing.T) {
+	cases := []struct {
+		driver string
+		offset int
+		count  int
+		want   string
+	}{
+		{"ansi", 20, 10, `OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY`},
+		{"ansi", 0, 10, `FETCH NEXT 10 ROWS ONLY`},
+		{"ansi", 20, 0, `OFFSET 20 ROWS`},
+		{"adodb", 20, 10, `OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY`},
+		{"adodb", 0, 10, `OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY`},
+		{"adodb", 20, 0, `OFFSET 20 ROWS`},
+		{"mysql", 20, 10, `LIMIT 20,10`},
+		{"postgres", 20, 10, `LIMIT 10 OFFSET 20`},
+		{"postgres", 0, 10, `LIMIT 10`},
+	}
+
+	for _, c := range cases {
+		comiler, err := GetCompiler(c.driver)
+		if err != nil {
+			t.Error("can not find compiler", c.driver, err)
+			continue
+		}
+
+		q := NewQuery("ttable", "")
+		q.Select.Column("cint")
+		q.UseOrderBy().Asc("cint")
+		q.Limit(c.offset, c.count)
+
+		formatedSql, _, err := comiler.Compile("source", q)
+		t.Log(formatedSql)
+		if err != nil {
+			t.Error("compile limit error", c.driver, err)
+			continue
+		}
+		want := `SELECT cint FROM ttable ORDER BY cint ASC ` + c.want + ` ;`
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+			t.Error("compile limit error", c.driver, "want:", want, "actual:", formatedSql)
+		}
+	}
+
+	comiler, _ := GetCompiler("adodb")
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint")
+	q.Limit(0, 10)
+	if _, _, err := comiler.Compile("source", q); err == nil {
+		t.Error("compile mssql limit without order by should fail")
+	}
+
+	// oracle inherits OFFSET FETCH of ansi, which doesn't need ORDER BY
+	comiler, _ = GetCompiler("goracle")
+	formatedSql, _, err := comiler.Compile("source", q)
+	t.Log(formatedSql)
+	want := `SELECT cint FROM ttable FETCH NEXT 10 ROWS ONLY`
+	if err != nil || !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compile oracle limit without order by error", "want:", want, "actual:", formatedSql, err)
+	}
+}
+
+func TestHavingParameters(t *testing.T) {
+	newQuery := func() *Query {
+		q := NewQuery("torder", "")
+		q.Select.Column("uid").Sum("amount", "total")
+		q.Where.Equals("status", "paid")
+		q.UseGroupBy().Column("uid")
+		q.UseHaving().Sum(GreaterThan, "amount", 100)
+		q.Limit(0, 10)
+		return q
+	}
+
+	comiler, _ := GetCompiler("postgres")
+	formatedSql, args, err := comiler.Compile("source", newQuery())
+	t.Log(formatedSql, args)
+	want := `SELECT uid, SUM(amount) AS "total" FROM torder WHERE status = $1 GROUP BY uid HAVING SUM(amount) > $2 LIMIT 10 ;`
+	if err != nil || !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compile having error", "want:", want, "actual:", formatedSql, err)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"paid", 100}) {
+		t.Error("compile having args error", args)
+	}
+
+	cases := []struct {
+		driver string
+		want   string
+	}{
+		{"postgres", `SELECT uid, SUM(amount) AS "total" FROM torder WHERE status = $1 GROUP BY uid HAVING SUM(amount) > $2 AND COUNT(*) FILTER (WHERE amount > $3) >= $4 LIMIT 10 ;`},
+		{"mysql", "SELECT uid, SUM(amount) AS `total` FROM torder WHERE status = ? GROUP BY uid HAVING SUM(amount) > ? AND COUNT(CASE WHEN amount > ? THEN 1 END) >= ? LIMIT 0,10 ;"},
+	}
+
+	for _, c := range cases {
+		comiler, _ := GetCompiler(c.driver)
+		q := newQuery()
+		q.Having.Aggregate(GreaterOrEquals, NewAggregate(Count, Wildcard).Where(func(c *Conditions) {
+			c.GreaterThan("amount", 50)
+		}), 2)
+
+		formatedSql, args, err := comiler.Compile("source", q)
+		t.Log(formatedSql, args)
+		if err != nil {
+			t.Error("compile having aggregate error", c.driver, err)
+			continue
+		}
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(c.want)) {
+			t.Error("compile having aggregate error", c.driver, "want:", c.want, "actual:", formatedSql)
+		}
+		if !reflect.DeepEqual(args, []interface{}{"paid", 100, 50, 2}) {
+			t.Error("compile having aggregate args error", c.driver, args)
+		}
+	}
+}
+
+func TestOrderByDirection(t *testing.T) {
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint")
+	q.UseOrderBy().Desc("cint").Asc("cstring").Column("cfloat")
+
+	cases := []struct {
+		omit bool
+		want string
+	}{
+		{false, `SELECT cint FROM ttable ORDER BY cint DESC, cstring ASC, cfloat ASC ;`},
+		{true, `SELECT cint FROM ttable ORDER BY cint DESC, cstring ASC, cfloat ;`},
+	}
+
+	for _, c := range cases {
+		sc := NewStmtCompiler(MysqlDialecter{})
+		sc.OmitDefaultAsc = c.omit
+		formatedSql, _, err := sc.Compile(q, "source")
+		t.Log(formatedSql)
+		if err != nil {
+			t.Error("compile order by direction error", c.omit, err)
+			continue
+		}
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(c.want)) {
+			t.Error("compile order by direction error", c.omit, "want:", c.want, "actual:", formatedSql)
+		}
+	}
+
+	driver := &SqlDriver{Dialecter: PostgreSQLDialecter{}, OmitDefaultAsc: true}
+	formatedSql, _, err := driver.Compile("source", q)
+	if err != nil || !strings.Contains(formatedSql, "cfloat") || strings.Contains(formatedSql, "cfloat ASC") {
+		t.Error("driver omit default asc error", formatedSql, err)
+	}
+
+	q = NewQuery("ttable", "")
+	q.UseOrderBy().By("asc", Column("cint")).By("DESC NULLS LAST", Column("cbool"))
+	formatedSql, _, err = driver.Compile("source", q)
+	want := `SELECT * FROM ttable ORDER BY cint asc, cbool DESC NULLS LAST ;`
+	if err != nil || !strings.EqualFold(removeSpace(formatedSql), removeSpace(want)) {
+		t.Error("compile explicit sort direction error", "want:", want, "actual:", formatedSql, err)
+	}
+}
+
+func TestNullSafeEqual(t *testing.T) {
+	newQuery := func() *Query {
+		q := NewQuery("ttable", "")
+		q.Select.Column("cint")
+		q.Where.NullSafeEqualsColumn("a", "b").NullSafeEquals("cstring", nil).NullSafeEquals("cint", 1)
+		return q
+	}
+
+	cases := []struct {
+		driver string
+		want   string
+	}{
+		{"mysql", `SELECT cint FROM ttable WHERE a <=> b AND cstring <=> NULL AND cint <=> ? ;`},
+		{"postgres", `SELECT cint FROM ttable WHERE a IS NOT DISTINCT FROM b AND cstring IS NOT DISTINCT FROM NULL AND cint IS NOT DISTINCT FROM $1 ;`},
+		{"ansi", `SELECT cint FROM ttable WHERE a IS NOT DISTINCT FROM b AND cstring IS NOT DISTINCT FROM NULL AND cint IS NOT DISTINCT FROM ? ;`},
+	}
+
+	for _, c := range cases {
+		comiler, err := GetCompiler(c.driver)
+		if err != nil {
+			t.Error("can not find compiler", c.driver, err)
+			continue
+		}
+
+		formatedSql, args, err := comiler.Compile("source", newQuery())
+		t.Log(formatedSql, args)
+		if err != nil {
+			t.Error("compile null-safe equal error", c.driver, err)
+			continue
+		}
+		if !strings.EqualFold(removeSpace(formatedSql), removeSpace(c.want)) {
+			t.Error("compile null-safe equal error", c.driver, "want:", c.want, "actual:", formatedSql)
+		}
+		if !reflect.DeepEqual(args, []interface{}{1}) {
+			t.Error("compile null-safe equal args error", c.driver, args)
+		}
+	}
+
+	comiler, _ := GetCompiler("goracle")
+	if _, _, err := comiler.Compile("source", newQuery()); err == nil {
+		t.Error("oracle should not support null-safe equal")
+	}
+}
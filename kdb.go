@@ -3,6 +3,8 @@ package kdb
 import (
 	"errors"
 	"log"
+	"regexp"
+	"sort"
 )
 
 // Logger
@@ -55,7 +57,7 @@ func (m Map) Get(name string) (interface{}, bool) {
 	return v, ok
 }
 
-// Fields return all map keys
+// Fields return all map keys, sorted so compiled sql & args are stable
 func (m Map) Fields() []string {
 	if m == nil {
 		return nil
@@ -64,6 +66,7 @@ func (m Map) Fields() []string {
 	for k, _ := range m {
 		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 	return keys
 }
 
@@ -72,3 +75,21 @@ var ErrNoResult = errors.New("rows no result")
 
 // ExplictSchema is true mean must use schema when insert/update
 var ExplictSchema = true
+
+// IdentifierRegexp validates table & column names of insert/update/delete, nil means doesn't validate
+var IdentifierRegexp *regexp.Regexp
+
+// simpleNameRegexp matches simple name of named parameter, function and aggregate
+var simpleNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// generatedNameRegexp matches name of generated named parameter, like pv1
+var generatedNameRegexp = regexp.MustCompile(`^pv[0-9]+$`)
+
+// plainIdentifierRegexp matches identifier that doesn't need quote, like table, column$1
+var plainIdentifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$#]*$`)
+
+// collationRegexp matches collation name like en_US, en_US.utf8, utf8mb4_unicode_ci
+var collationRegexp = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.@-]*$`)
+
+// SafeIdentifierRegexp matches names like table, schema.table, _column, column$1
+var SafeIdentifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$#]*(\.[A-Za-z_][A-Za-z0-9_$#]*)*$`)
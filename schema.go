@@ -0,0 +1,451 @@
+package kdb
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/sdming/kdb/ansi"
+	"strings"
+)
+
+// SqlSchemaer query schema of table, view, procedure by sql of Dialecter
+type SqlSchemaer struct {
+	// DB is database to query schema
+	DB *sql.DB
+
+	// Driver is driver name, use to get Dialecter
+	Driver string
+}
+
+// NewSqlSchemaer return *SqlSchemaer with provided db and driver name
+func NewSqlSchemaer(db *sql.DB, driver string) *SqlSchemaer {
+	return &SqlSchemaer{
+		DB:     db,
+		Driver: driver,
+	}
+}
+
+// driverSchemaer implement Schemaer by SqlSchemaer of a driver name
+type driverSchemaer string
+
+var _ Schemaer = driverSchemaer("")
+
+// NewDriverSchemaer return a Schemaer that query schema by sql of Dialecter of driver, it can be passed to RegisterSchemaer
+func NewDriverSchemaer(driver string) Schemaer {
+	return driverSchemaer(driver)
+}
+
+// Table return schema of table,view
+func (d driverSchemaer) Table(db *sql.DB, name string) (*ansi.DbTable, error) {
+	return NewSqlSchemaer(db, string(d)).Table(name)
+}
+
+// Function return schema of store procedure,function
+func (d driverSchemaer) Function(db *sql.DB, name string) (*ansi.DbFunction, error) {
+	return NewSqlSchemaer(db, string(d)).Function(name)
+}
+
+func (s *SqlSchemaer) query(query string) (*sql.Rows, error) {
+	if s.DB == nil {
+		return nil, errors.New("schemaer db is nil")
+	}
+
+	rows, err := s.DB.Query(query)
+	if LogLevel >= LogDebug {
+		logDebug("Schemaer query:", query, err)
+	}
+	return rows, err
+}
+
+// Function return schema of store procedure
+func (s *SqlSchemaer) Function(name string) (fn *ansi.DbFunction, err error) {
+	var dialect Dialecter
+	if dialect, err = GetDialecter(s.Driver); err != nil {
+		return
+	}
+	query := dialect.FunctionSql(name)
+	if query == "" {
+		if schm, ok := dialect.(Schemaer); ok {
+			return schm.Function(s.DB, name)
+		}
+		err = errors.New("driver doesn't support function schema:" + s.Driver)
+		return
+	}
+
+	var rows *sql.Rows
+	if rows, err = s.query(query); err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var f *ansi.DbFunction
+	for rows.Next() {
+		ff := ansi.NewFunction()
+
+		if err = rows.Scan(&ff.Catalog, &ff.Schema, &ff.Name); err != nil {
+			return
+		} else {
+			f = ff
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	if f == nil {
+		err = errors.New("function doesn't exist:" + name)
+		return
+	}
+
+	query = dialect.ParametersSql(name)
+	if query == "" {
+		err = errors.New("driver doesn't support function parameters schema:" + s.Driver)
+		return
+	}
+	var params *sql.Rows
+	if params, err = s.query(query); err != nil {
+		return
+	}
+	defer params.Close()
+
+	for params.Next() {
+		p := ansi.DbParameter{}
+		dir := ""
+		if err = params.Scan(&p.Name, &p.Position, &dir, &p.NativeType, &p.Size, &p.Precision, &p.Scale); err != nil {
+			return
+		} else {
+			p.DbType = dialect.DbType(p.NativeType)
+			switch dir {
+			case "IN":
+				p.Dir = ansi.DirIn
+			case "INOUT":
+				p.Dir = ansi.DirInOut
+			case "OUT":
+				p.Dir = ansi.DirOut
+			default:
+				p.Dir = ansi.DirIn
+			}
+			f.Parameters = append(f.Parameters, p)
+		}
+	}
+	if err = params.Err(); err != nil {
+		return
+	}
+
+	fn = f
+	return
+
+}
+
+// Table return schema of table,view
+func (s *SqlSchemaer) Table(name string) (table *ansi.DbTable, err error) {
+	var dialect Dialecter
+	if dialect, err = GetDialecter(s.Driver); err != nil {
+		return
+	}
+	query := dialect.TableSql(name)
+	if query == "" {
+		if schm, ok := dialect.(Schemaer); ok {
+			return schm.Table(s.DB, name)
+		}
+		err = errors.New("driver doesn't support table schema:" + s.Driver)
+		return
+	}
+
+	var rows *sql.Rows
+	if rows, err = s.query(query); err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var t *ansi.DbTable
+	for rows.Next() {
+		tt := ansi.NewTable()
+
+		if err = rows.Scan(&tt.Catalog, &tt.Schema, &tt.Name, &tt.Type); err != nil {
+			return
+		} else {
+			t = tt
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	if t == nil {
+		err = errors.New("table doesn't exist:" + name)
+		return
+	}
+
+	query = dialect.ColumnsSql(name)
+	if query == "" {
+		err = errors.New("driver doesn't support columns schema:" + s.Driver)
+		return
+	}
+	var columns *sql.Rows
+	if columns, err = s.query(query); err != nil {
+		return
+	}
+	defer columns.Close()
+
+	var cols []string
+	if cols, err = columns.Columns(); err != nil {
+		return
+	}
+
+	for columns.Next() {
+		var col ansi.DbColumn
+		if col, err = scanColumn(columns, cols); err != nil {
+			return
+		} else {
+			col.DbType = dialect.DbType(col.NativeType)
+			t.Columns = append(t.Columns, col)
+		}
+	}
+
+	if err = columns.Err(); err != nil {
+		return
+	}
+
+	if len(t.Columns) == 0 {
+		err = errors.New("table columns doesn't exist:" + name)
+		return
+	}
+
+	if err = s.primaryKey(dialect, t); err != nil {
+		return
+	}
+
+	if err = s.foreignKeys(dialect, t); err != nil {
+		return
+	}
+
+	if err = s.indexes(dialect, t); err != nil {
+		return
+	}
+
+	table = t
+	return
+
+}
+
+// primaryKey fill t.PrimaryKey, use columns order if dialect doesn't support primary key schema
+func (s *SqlSchemaer) primaryKey(dialect Dialecter, t *ansi.DbTable) (err error) {
+	query := dialectKeySchema(dialect).PrimaryKeySql(t.Name)
+	if query == "" {
+		t.PrimaryKey = primaryKeyColumns(t.Columns)
+		return
+	}
+
+	var rows *sql.Rows
+	if rows, err = s.query(query); err != nil {
+		return
+	}
+	defer rows.Close()
+
+	keys := make([]string, 0, 3)
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return
+		}
+		keys = append(keys, name)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	t.PrimaryKey = keys
+	return
+}
+
+// foreignKeys fill t.ForeignKeys, skip if dialect doesn't support foreign key schema
+func (s *SqlSchemaer) foreignKeys(dialect Dialecter, t *ansi.DbTable) (err error) {
+	query := dialectKeySchema(dialect).ForeignKeysSql(t.Name)
+	if query == "" {
+		return
+	}
+
+	var rows *sql.Rows
+	if rows, err = s.query(query); err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var fks []ansi.DbForeignKey
+	for rows.Next() {
+		var name, column, refTable, refColumn, onDelete, onUpdate string
+		if err = rows.Scan(&name, &column, &refTable, &refColumn, &onDelete, &onUpdate); err != nil {
+			return
+		}
+		fks = appendForeignKey(fks, name, column, refTable, refColumn, onDelete, onUpdate)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	t.ForeignKeys = fks
+	return
+}
+
+// appendForeignKey append a foreign key column, rows of same constraint must be adjacent
+func appendForeignKey(fks []ansi.DbForeignKey, name, column, refTable, refColumn, onDelete, onUpdate string) []ansi.DbForeignKey {
+	l := len(fks)
+	if l > 0 && fks[l-1].Name == name {
+		fks[l-1].Columns = append(fks[l-1].Columns, column)
+		fks[l-1].RefColumns = append(fks[l-1].RefColumns, refColumn)
+		return fks
+	}
+
+	return append(fks, ansi.DbForeignKey{
+		Name:       name,
+		Columns:    []string{column},
+		RefTable:   refTable,
+		RefColumns: []string{refColumn},
+		OnDelete:   onDelete,
+		OnUpdate:   onUpdate,
+	})
+}
+
+// indexes fill t.Indexes, skip if dialect doesn't support index schema
+func (s *SqlSchemaer) indexes(dialect Dialecter, t *ansi.DbTable) (err error) {
+	query := dialectKeySchema(dialect).IndexesSql(t.Name)
+	if query == "" {
+		return
+	}
+
+	var rows *sql.Rows
+	if rows, err = s.query(query); err != nil {
+		return
+	}
+	defer rows.Close()
+
+	var indexes []ansi.DbIndex
+	for rows.Next() {
+		var name, column string
+		var unique bool
+		if err = rows.Scan(&name, &column, &unique); err != nil {
+			return
+		}
+		indexes = appendIndex(indexes, name, column, unique)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	t.Indexes = indexes
+	return
+}
+
+// appendIndex append a index column, rows of same index must be adjacent
+func appendIndex(indexes []ansi.DbIndex, name, column string, unique bool) []ansi.DbIndex {
+	l := len(indexes)
+	if l > 0 && indexes[l-1].Name == name {
+		indexes[l-1].Columns = append(indexes[l-1].Columns, column)
+		return indexes
+	}
+
+	return append(indexes, ansi.DbIndex{
+		Name:     name,
+		Columns:  []string{column},
+		IsUnique: unique,
+	})
+}
+
+// primaryKeyColumns return name of primary key columns
+func primaryKeyColumns(columns []ansi.DbColumn) []string {
+	keys := make([]string, 0, 3)
+	for i := 0; i < len(columns); i++ {
+		if columns[i].IsPrimaryKey {
+			keys = append(keys, columns[i].Name)
+		}
+	}
+	return keys
+}
+
+// scanColumn scan a row of columns schema, first 10 columns are fixed, extra columns like columntype are optional
+func scanColumn(rows *sql.Rows, cols []string) (col ansi.DbColumn, err error) {
+	dest := []interface{}{&col.Name, &col.Position, &col.IsNullable, &col.NativeType, &col.Size, &col.Precision, &col.Scale, &col.IsAutoIncrement, &col.IsReadOnly, &col.IsPrimaryKey}
+
+	var columnType, dflt sql.NullString
+	hasDefault := false
+	for i := len(dest); i < len(cols); i++ {
+		switch strings.ToLower(cols[i]) {
+		case "columntype":
+			dest = append(dest, &columnType)
+		case "default":
+			hasDefault = true
+			dest = append(dest, &dflt)
+		default:
+			var v interface{}
+			dest = append(dest, &v)
+		}
+	}
+
+	if err = rows.Scan(dest...); err != nil {
+		return
+	}
+
+	if columnType.Valid {
+		col.Members = parseMembers(columnType.String)
+		if strings.HasSuffix(strings.ToLower(columnType.String), " unsigned") {
+			col.NativeType += " unsigned"
+		}
+	}
+	if hasDefault {
+		columnDefault(&col, dflt)
+	}
+	return
+}
+
+// columnDefault set default value of col, a nullable column without default value has DEFAULT NULL
+func columnDefault(col *ansi.DbColumn, dflt sql.NullString) {
+	switch {
+	case dflt.Valid && dflt.String == ansi.Null:
+		// mariadb return NULL for explicit DEFAULT NULL
+		col.HasDefault = true
+	case dflt.Valid:
+		v := dflt.String
+		col.HasDefault = true
+		col.Default = &v
+	case col.IsNullable && !col.IsAutoIncrement:
+		col.HasDefault = true
+	default:
+		col.HasDefault = false
+	}
+}
+
+// parseMembers parse members of column type like enum('a','b') or set('a','b'), return nil if it isn't enum or set
+func parseMembers(columnType string) []string {
+	s := strings.TrimSpace(columnType)
+	lower := strings.ToLower(s)
+	if !(strings.HasPrefix(lower, "enum(") || strings.HasPrefix(lower, "set(")) || !strings.HasSuffix(s, ")") {
+		return nil
+	}
+	s = s[strings.Index(s, "(")+1 : len(s)-1]
+
+	members := make([]string, 0, 3)
+	var buf []byte
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case !quoted:
+			if c == '\'' {
+				quoted = true
+				buf = buf[:0]
+			}
+		case c == '\\' && i+1 < len(s):
+			i++
+			buf = append(buf, s[i])
+		case c == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+			buf = append(buf, c)
+		case c == '\'':
+			quoted = false
+			members = append(members, string(buf))
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return members
+}
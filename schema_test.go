@@ -0,0 +1,114 @@
+package kdb
+
+import (
+	"database/sql"
+	_ "github.com/changkong/go-sqlite3s"
+	"github.com/sdming/kdb/ansi"
+	"reflect"
+	"testing"
+)
+
+func TestSqlSchemaerTable(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Error("open sqlite error", err)
+		return
+	}
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE ttable (id integer not null, cstring varchar(100), cfloat real, PRIMARY KEY (cstring, id));")
+	if err != nil {
+		t.Error("create table error", err)
+		return
+	}
+
+	table, err := NewSqlSchemaer(db, "sqlite3").Table("ttable")
+	if err != nil {
+		t.Error("Table error", err)
+		return
+	}
+	t.Log(table)
+
+	want := []ansi.DbColumn{
+		{Name: "id", IsNullable: false, IsPrimaryKey: true},
+		{Name: "cstring", IsNullable: true, IsPrimaryKey: true},
+		{Name: "cfloat", IsNullable: true},
+	}
+	if len(table.Columns) != len(want) {
+		t.Error("columns count error", len(table.Columns), len(want))
+		return
+	}
+	for i := 0; i < len(want); i++ {
+		col := table.Columns[i]
+		if col.Name != want[i].Name || col.IsNullable != want[i].IsNullable || col.IsPrimaryKey != want[i].IsPrimaryKey {
+			t.Error("column error", col, want[i])
+		}
+	}
+	if len(table.PrimaryKey) != 2 || table.PrimaryKey[0] != "cstring" || table.PrimaryKey[1] != "id" {
+		t.Error("primary key error", table.PrimaryKey)
+	}
+}
+
+func TestSqlSchemaerDriver(t *testing.T) {
+	if _, err := NewSqlSchemaer(nil, "unknown").Table("ttable"); err == nil {
+		t.Error("unknown driver should return error")
+	}
+
+	RegisterSchemaer("unknown_schemaer", NewDriverSchemaer("unknown"))
+	defer func() {
+		_schemaersLock.Lock()
+		delete(_schemaers, "unknown_schemaer")
+		_schemaersLock.Unlock()
+	}()
+	schemaer, err := GetSchemaer("unknown_schemaer")
+	if err != nil {
+		t.Fatal("get schemaer error", err)
+	}
+	if _, err = schemaer.Table(nil, "ttable"); err == nil {
+		t.Error("unknown driver schemaer should return error")
+	}
+}
+
+func TestParseMembers(t *testing.T) {
+	cases := map[string][]string{
+		"enum('a','b','c')":   {"a", "b", "c"},
+		"set('x', 'y')":       {"x", "y"},
+		"ENUM('it''s','a,b')": {"it's", "a,b"},
+		"enum('')":            {""},
+		"varchar(100)":        nil,
+		"int(11)":             nil,
+	}
+
+	for columnType, want := range cases {
+		members := parseMembers(columnType)
+		if !reflect.DeepEqual(members, want) {
+			t.Errorf("parse members error, column type=[%v]; want=[%v]; actual=[%v]", columnType, want, members)
+		}
+	}
+}
+
+func TestColumnDefault(t *testing.T) {
+	col := ansi.DbColumn{Name: "cstring", IsNullable: true}
+	columnDefault(&col, sql.NullString{String: "abc", Valid: true})
+	if !col.HasDefault || col.Default == nil || *col.Default != "abc" {
+		t.Error("string default error", col)
+	}
+
+	col = ansi.DbColumn{Name: "cint", IsNullable: false}
+	columnDefault(&col, sql.NullString{})
+	if col.HasDefault || col.Default != nil {
+		t.Error("no default error", col)
+	}
+
+	col = ansi.DbColumn{Name: "cfloat", IsNullable: true}
+	columnDefault(&col, sql.NullString{})
+	if !col.HasDefault || col.Default != nil {
+		t.Error("default null error", col)
+	}
+
+	col = ansi.DbColumn{Name: "cbool", IsNullable: true}
+	columnDefault(&col, sql.NullString{String: "NULL", Valid: true})
+	if !col.HasDefault || col.Default != nil {
+		t.Error("explicit default null error", col)
+	}
+}
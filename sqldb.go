@@ -0,0 +1,316 @@
+package kdb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SqlDB wrap *sql.DB, compile expression by compiler of driver then query or execute it
+type SqlDB struct {
+	// DB is the underlying database
+	DB *sql.DB
+
+	// Driver is driver name, use to get Compiler
+	Driver string
+
+	// ExplainFormat is output format of mysql explain, e.g. JSON
+	ExplainFormat string
+}
+
+// NewSqlDB return *SqlDB with provided db and driver name
+func NewSqlDB(db *sql.DB, driver string) *SqlDB {
+	return &SqlDB{
+		DB:     db,
+		Driver: driver,
+	}
+}
+
+// Compile compile expression to native sql & args
+func (s *SqlDB) Compile(source string, exp Expression) (query string, args []interface{}, err error) {
+	if s.DB == nil {
+		err = errors.New("db is nil")
+		return
+	}
+
+	var compiler Compiler
+	if compiler, err = GetCompiler(s.Driver); err != nil {
+		return
+	}
+	return compiler.Compile(source, exp)
+}
+
+// Query compile expression then executes it as a query that returns *sql.Rows
+func (s *SqlDB) Query(source string, exp Expression) (*sql.Rows, error) {
+	query, args, err := s.Compile(source, exp)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.DB.Query(query, args...)
+	if LogLevel >= LogDebug {
+		logDebug("SqlDB query:", query, args, err)
+	}
+	return rows, err
+}
+
+// Exec compile expression then executes it without returning any rows
+func (s *SqlDB) Exec(source string, exp Expression) (sql.Result, error) {
+	query, args, err := s.Compile(source, exp)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.DB.Exec(query, args...)
+	if LogLevel >= LogDebug {
+		logDebug("SqlDB exec:", query, args, result, err)
+	}
+	return result, err
+}
+
+// explainQuery return compiled query with EXPLAIN prefix of Dialecter of driver, it's shared by SqlDB and DB
+func explainQuery(driver string, query string, analyze bool, format string) (string, error) {
+	dialecter, err := GetDialecter(driver)
+	if err != nil {
+		return "", newCompileError(CompileUnsupported, NodeZero, "", err.Error())
+	}
+	return ExplainSql(dialecter, query, analyze, format)
+}
+
+// Explain compile expression then executes it with EXPLAIN prefix, return rows of query plan
+func (s *SqlDB) Explain(source string, exp Expression, analyze bool) (*sql.Rows, error) {
+	query, args, err := s.Compile(source, exp)
+	if err != nil {
+		return nil, err
+	}
+
+	if query, err = explainQuery(s.Driver, query, analyze, s.ExplainFormat); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.DB.Query(query, args...)
+	if LogLevel >= LogDebug {
+		logDebug("SqlDB explain:", query, args, err)
+	}
+	return rows, err
+}
+
+// Prepare compile expression then creates a prepared statement, the statement can be executed with args of
+// expressions that have same structure of exp
+func (s *SqlDB) Prepare(source string, exp Expression) (*SqlStmt, error) {
+	if s.DB == nil {
+		return nil, errors.New("db is nil")
+	}
+
+	compiler, err := GetCompiler(s.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &SqlStmt{
+		source:   source,
+		compiler: compiler,
+	}
+	if c, ok := compiler.(*SqlDriver); ok && exp != nil && exp.Node() != NodeText && exp.Node() != NodeProcedure {
+		st.Sql, st.Args, st.Names, err = c.CompileNamed(source, exp)
+	} else {
+		st.Sql, st.Args, err = compiler.Compile(source, exp)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	st.Stmt, err = s.DB.Prepare(st.Sql)
+	if LogLevel >= LogDebug {
+		logDebug("SqlDB prepare:", st.Sql, st.Args, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// QueryEach compile expression then executes it, call fn for each row, stop at first error of fn.
+// rows is closed before QueryEach return, fn should not keep rows
+func (s *SqlDB) QueryEach(source string, exp Expression, fn func(rows *sql.Rows) error) error {
+	if fn == nil {
+		return errors.New("fn is nil")
+	}
+
+	rows, err := s.Query(source, exp)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err = fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// QueryStruct compile expression then executes it, scan rows into dest, dest should be pointer to []struct or []*struct
+func (s *SqlDB) QueryStruct(source string, exp Expression, dest interface{}) error {
+	if dest == nil {
+		return errors.New("dest is nil")
+	}
+
+	dt := reflect.TypeOf(dest)
+	if dt.Kind() != reflect.Ptr || dt.Elem().Kind() != reflect.Slice || underlyingType(dt.Elem().Elem()).Kind() != reflect.Struct {
+		return fmt.Errorf("QueryStruct does not support dest type %v", dt)
+	}
+
+	rows, err := s.Query(source, exp)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if err = Read(rows, dest); err != nil {
+		return err
+	}
+	return rows.Err()
+}
+
+// QueryMap compile expression then executes it, return rows as maps keyed by column name.
+// NULL column is nil, []byte of text column is converted to string
+func (s *SqlDB) QueryMap(source string, exp Expression) ([]map[string]interface{}, error) {
+	rows, err := s.Query(source, exp)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result, err := readMaps(rows)
+	if err != nil {
+		return nil, err
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// readMaps read rows of current result set as maps keyed by column name
+func readMaps(rows *sql.Rows) ([]map[string]interface{}, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	l := len(types)
+	var result []map[string]interface{}
+	for rows.Next() {
+		v := make([]interface{}, l)
+		for i := 0; i < l; i++ {
+			var tv interface{}
+			v[i] = &tv
+		}
+
+		if err = rows.Scan(v...); err != nil {
+			return nil, err
+		}
+
+		m := make(map[string]interface{}, l)
+		for i := 0; i < l; i++ {
+			mv := *(v[i].(*interface{}))
+			if b, ok := mv.([]byte); ok && !isBinaryColumn(types[i]) {
+				mv = string(b)
+			}
+			m[types[i].Name()] = mv
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+// isBinaryColumn return true if database type of column is binary
+func isBinaryColumn(ct *sql.ColumnType) bool {
+	name := strings.ToUpper(ct.DatabaseTypeName())
+	return strings.Contains(name, "BLOB") || strings.Contains(name, "BINARY") ||
+		name == "BYTEA" || name == "IMAGE" || name == "RAW"
+}
+
+// SqlStmt is prepared statement of expression
+type SqlStmt struct {
+	// Stmt is the underlying prepared statement
+	Stmt *sql.Stmt
+
+	// Sql is compiled sql of statement
+	Sql string
+
+	// Args is args of expression when statement is prepared, values passed to Exec or Query should be in same order
+	Args []interface{}
+
+	// Names is parameter name of Args[i] if dialect support named parameter, otherwise it's nil
+	Names []string
+
+	source   string
+	compiler Compiler
+}
+
+// Close closes the statement
+func (st *SqlStmt) Close() error {
+	return st.Stmt.Close()
+}
+
+// Exec executes prepared statement with args, count of args should be same as Args
+func (st *SqlStmt) Exec(args ...interface{}) (sql.Result, error) {
+	if len(args) != len(st.Args) {
+		return nil, fmt.Errorf("statement needs %d args, but got %d", len(st.Args), len(args))
+	}
+
+	result, err := st.Stmt.Exec(args...)
+	if LogLevel >= LogDebug {
+		logDebug("SqlStmt exec:", st.Sql, args, result, err)
+	}
+	return result, err
+}
+
+// Query executes prepared statement with args, count of args should be same as Args
+func (st *SqlStmt) Query(args ...interface{}) (*sql.Rows, error) {
+	if len(args) != len(st.Args) {
+		return nil, fmt.Errorf("statement needs %d args, but got %d", len(st.Args), len(args))
+	}
+
+	rows, err := st.Stmt.Query(args...)
+	if LogLevel >= LogDebug {
+		logDebug("SqlStmt query:", st.Sql, args, err)
+	}
+	return rows, err
+}
+
+// Bind return args of exp in order of statement, exp should have same structure as prepared expression,
+// it returns error if sql of exp is different from sql of statement
+func (st *SqlStmt) Bind(exp Expression) ([]interface{}, error) {
+	query, args, err := st.compiler.Compile(st.source, exp)
+	if err != nil {
+		return nil, err
+	}
+	if query != st.Sql {
+		return nil, errors.New("expression doesn't match prepared statement")
+	}
+	return args, nil
+}
+
+// ExecExp executes prepared statement with args of exp
+func (st *SqlStmt) ExecExp(exp Expression) (sql.Result, error) {
+	args, err := st.Bind(exp)
+	if err != nil {
+		return nil, err
+	}
+	return st.Exec(args...)
+}
+
+// QueryExp executes prepared statement with args of exp
+func (st *SqlStmt) QueryExp(exp Expression) (*sql.Rows, error) {
+	args, err := st.Bind(exp)
+	if err != nil {
+		return nil, err
+	}
+	return st.Query(args...)
+}
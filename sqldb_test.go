@@ -0,0 +1,320 @@
+package kdb
+
+import (
+	"database/sql"
+	"fmt"
+	_ "github.com/changkong/go-sqlite3s"
+	"testing"
+)
+
+var (
+	_ Queryer = &SqlDB{}
+	_ Execer  = &SqlDB{}
+)
+
+func openSqlDB(t *testing.T) *SqlDB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal("open sqlite error", err)
+	}
+
+	_, err = db.Exec("CREATE TABLE ttable (cint integer, cstring varchar(100), cfloat real);")
+	if err != nil {
+		t.Fatal("create table error", err)
+	}
+	return NewSqlDB(db, "sqlite3")
+}
+
+func TestSqlDBQuery(t *testing.T) {
+	db := openSqlDB(t)
+	defer db.DB.Close()
+
+	insert := NewInsert("ttable").Set("cint", 42).Set("cstring", "string").Set("cfloat", 3.14)
+	if _, err := db.Exec("source", insert); err != nil {
+		t.Fatal("exec insert error", err)
+	}
+
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint", "cstring")
+	q.Where.Equals("cint", 42)
+
+	rows, err := db.Query("source", q)
+	if err != nil {
+		t.Fatal("query error", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		t.Fatal("query no result")
+	}
+
+	var i int
+	var s string
+	if err = rows.Scan(&i, &s); err != nil {
+		t.Fatal("scan error", err)
+	}
+	if i != 42 || s != "string" {
+		t.Error("scan value error", i, s)
+	}
+}
+
+func TestSqlDBCompile(t *testing.T) {
+	db := NewSqlDB(nil, "sqlite3")
+	if _, err := db.Query("source", NewQuery("ttable", "")); err == nil {
+		t.Error("query with nil db should return error")
+	}
+
+	db = NewSqlDB(&sql.DB{}, "unknown")
+	if _, err := db.Exec("source", NewDelete("ttable")); err == nil {
+		t.Error("exec with unknown driver should return error")
+	}
+}
+
+func TestSqlDBRows(t *testing.T) {
+	db := openSqlDB(t)
+	defer db.DB.Close()
+
+	for i := 1; i <= 3; i++ {
+		if _, err := db.Exec("source", NewInsert("ttable").Set("cint", i)); err != nil {
+			t.Fatal("exec insert error", err)
+		}
+	}
+
+	var queryer Queryer = db
+	rows, err := queryer.Query("source", NewQuery("ttable", ""))
+	if err != nil {
+		t.Fatal("query error", err)
+	}
+
+	count := 0
+	for rows.Next() {
+		count++
+	}
+	if err = rows.Err(); err != nil {
+		t.Error("rows error", err)
+	}
+	if count != 3 {
+		t.Error("rows count error", count)
+	}
+
+	if err = rows.Close(); err != nil {
+		t.Error("rows close error", err)
+	}
+	if rows.Next() {
+		t.Error("closed rows should not have next")
+	}
+}
+
+type sqlDBEntity struct {
+	Name   string `db:"cstring"`
+	Id     int    `db:"cint"`
+	Remark string `db:"cremark"`
+}
+
+func TestSqlDBStruct(t *testing.T) {
+	db := openSqlDB(t)
+	defer db.DB.Close()
+
+	for i := 1; i <= 2; i++ {
+		insert := NewInsert("ttable").Set("cint", i).Set("cstring", fmt.Sprint("s", i))
+		if _, err := db.Exec("source", insert); err != nil {
+			t.Fatal("exec insert error", err)
+		}
+	}
+
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint", "cstring")
+	q.UseOrderBy().Asc("cint")
+
+	var entities []sqlDBEntity
+	if err := db.QueryStruct("source", q, &entities); err != nil {
+		t.Fatal("query struct error", err)
+	}
+	if len(entities) != 2 {
+		t.Fatal("query struct count error, want=[2]; actual=", len(entities))
+	}
+	for i, e := range entities {
+		if e.Id != i+1 || e.Name != fmt.Sprint("s", i+1) || e.Remark != "" {
+			t.Errorf("query struct value error, index=%d; actual=%v", i, e)
+		}
+	}
+
+	var pointers []*sqlDBEntity
+	if err := db.QueryStruct("source", q, &pointers); err != nil {
+		t.Fatal("query struct error", err)
+	}
+	if len(pointers) != 2 || pointers[1].Id != 2 {
+		t.Error("query struct pointer error", pointers)
+	}
+
+	if err := db.QueryStruct("source", q, entities); err == nil {
+		t.Error("query struct with non-pointer dest should return error")
+	}
+}
+
+func TestSqlDBMap(t *testing.T) {
+	db := openSqlDB(t)
+	defer db.DB.Close()
+
+	if _, err := db.Exec("source", NewInsert("ttable").Set("cint", 42).Set("cstring", "string")); err != nil {
+		t.Fatal("exec insert error", err)
+	}
+
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint", "cstring", "cfloat")
+
+	maps, err := db.QueryMap("source", q)
+	if err != nil {
+		t.Fatal("query map error", err)
+	}
+	if len(maps) != 1 {
+		t.Fatal("query map count error, want=[1]; actual=", len(maps))
+	}
+
+	m := maps[0]
+	if v, ok := m["cint"].(int64); !ok || v != 42 {
+		t.Errorf("cint error, want=[%v]; actual=[%v]", 42, m["cint"])
+	}
+	if v, ok := m["cstring"].(string); !ok || v != "string" {
+		t.Errorf("cstring error, want=[%v]; actual=[%v]", "string", m["cstring"])
+	}
+	if v, ok := m["cfloat"]; !ok || v != nil {
+		t.Errorf("cfloat error, want=[%v]; actual=[%v]", nil, v)
+	}
+}
+
+func TestSqlDBQueryEach(t *testing.T) {
+	db := openSqlDB(t)
+	defer db.DB.Close()
+
+	for i := 1; i <= 3; i++ {
+		if _, err := db.Exec("source", NewInsert("ttable").Set("cint", i).Set("cstring", fmt.Sprint("s", i))); err != nil {
+			t.Fatal("exec insert error", err)
+		}
+	}
+
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint")
+	q.UseOrderBy().Asc("cint")
+
+	var values []int
+	err := db.QueryEach("source", q, func(rows *sql.Rows) error {
+		var i int
+		if err := rows.Scan(&i); err != nil {
+			return err
+		}
+		values = append(values, i)
+		return nil
+	})
+	if err != nil {
+		t.Fatal("query each error", err)
+	}
+	if len(values) != 3 || values[0] != 1 || values[2] != 3 {
+		t.Error("query each values error", values)
+	}
+
+	stop := fmt.Errorf("stop")
+	count := 0
+	err = db.QueryEach("source", q, func(rows *sql.Rows) error {
+		count++
+		if count == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Error("query each should return error of fn", err)
+	}
+	if count != 2 {
+		t.Error("query each should stop at first error, count=", count)
+	}
+
+	if err = db.QueryEach("source", q, nil); err == nil {
+		t.Error("query each with nil fn should return error")
+	}
+}
+
+func TestSqlDBPrepare(t *testing.T) {
+	db := openSqlDB(t)
+	defer db.DB.Close()
+
+	insert := func(i int) *Insert {
+		return NewInsert("ttable").Set("cint", i).Set("cstring", fmt.Sprint("s", i))
+	}
+
+	stmt, err := db.Prepare("source", insert(0))
+	if err != nil {
+		t.Fatal("prepare error", err)
+	}
+	defer stmt.Close()
+
+	if len(stmt.Args) != 2 {
+		t.Error("prepared args error", stmt.Args)
+	}
+
+	if _, err = stmt.Exec(1, "s1"); err != nil {
+		t.Fatal("exec prepared statement error", err)
+	}
+	if _, err = stmt.ExecExp(insert(2)); err != nil {
+		t.Fatal("exec prepared statement with expression error", err)
+	}
+	if _, err = stmt.ExecExp(insert(3)); err != nil {
+		t.Fatal("exec prepared statement with expression error", err)
+	}
+	if _, err = stmt.Exec(4); err == nil {
+		t.Error("exec prepared statement with wrong count of args should return error")
+	}
+
+	q := NewQuery("ttable", "")
+	q.Select.Column("cint", "cstring")
+	q.UseOrderBy().Asc("cint")
+
+	var values []string
+	err = db.QueryEach("source", q, func(rows *sql.Rows) error {
+		var i int
+		var s string
+		if err := rows.Scan(&i, &s); err != nil {
+			return err
+		}
+		values = append(values, fmt.Sprint(i, s))
+		return nil
+	})
+	if err != nil {
+		t.Fatal("query error", err)
+	}
+	if fmt.Sprint(values) != "[1s1 2s2 3s3]" {
+		t.Error("prepared statement values error", values)
+	}
+}
+
+func TestSqlStmtBind(t *testing.T) {
+	compiler, _ := GetCompiler("sqlite3")
+	insert := NewInsert("ttable").Set("cint", 1).Set("cstring", "s1")
+	query, _, err := compiler.Compile("source", insert)
+	if err != nil {
+		t.Fatal("compile error", err)
+	}
+
+	st := &SqlStmt{Sql: query, source: "source", compiler: compiler}
+	args, err := st.Bind(NewInsert("ttable").Set("cint", 2).Set("cstring", "s2"))
+	if err != nil {
+		t.Fatal("bind error", err)
+	}
+	if fmt.Sprint(args) != "[2 s2]" {
+		t.Error("bind args error", args)
+	}
+
+	if _, err = st.Bind(NewInsert("ttable").Set("cint", 2).Set("cfloat", 1.5)); err == nil {
+		t.Error("bind expression of different structure should return error")
+	}
+
+	st.compiler = &sqlCompiler{compiler}
+	if _, err = st.Bind(NewInsert("ttable").Set("cint", 2).Set("cfloat", 1.5)); err == nil {
+		t.Error("bind expression of different structure should return error")
+	}
+}
+
+// sqlCompiler hide *SqlDriver behind Compiler
+type sqlCompiler struct {
+	Compiler
+}
@@ -0,0 +1,109 @@
+package kdb
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Tx is wrap of *sql.Tx, compile expression by compiler of DSN driver
+type Tx struct {
+	DSN     *DSN
+	innertx *sql.Tx
+}
+
+// Begin starts a transaction
+func (db *DB) Begin() (*Tx, error) {
+	return db.BeginTx(context.Background(), nil)
+}
+
+// BeginTx starts a transaction with provided context and options
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	if err := db.Open(); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.innerdb.BeginTx(ctx, opts)
+	if LogLevel >= LogDebug {
+		logDebug("DB begin:", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{DSN: db.DSN, innertx: tx}, nil
+}
+
+// Tx return internal *sql.Tx
+func (tx *Tx) Tx() *sql.Tx {
+	return tx.innertx
+}
+
+// Commit commits the transaction
+func (tx *Tx) Commit() error {
+	return tx.innertx.Commit()
+}
+
+// Rollback aborts the transaction
+func (tx *Tx) Rollback() error {
+	return tx.innertx.Rollback()
+}
+
+// Query executes a query that returns *sql.Rows
+func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext executes a query that returns *sql.Rows, ctx can cancel the query
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := tx.innertx.QueryContext(ctx, query, args...)
+	if LogLevel >= LogDebug {
+		logDebug("Tx query:", query, args, err)
+	}
+	return rows, err
+}
+
+// Exec executes a query that return sql.Result
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext executes a query that return sql.Result, ctx can cancel the query
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := tx.innertx.ExecContext(ctx, query, args...)
+	if LogLevel >= LogDebug {
+		logDebug("Tx exec:", query, args, result, err)
+	}
+	return result, err
+}
+
+// Compile compile expression to native sql
+func (tx *Tx) Compile(exp Expression) (sql string, args []interface{}, err error) {
+	return compileDSN(tx.DSN, exp)
+}
+
+// QueryExp query a expression
+func (tx *Tx) QueryExp(exp Expression) (*sql.Rows, error) {
+	return tx.QueryExpContext(context.Background(), exp)
+}
+
+// QueryExpContext query a expression, ctx can cancel the query
+func (tx *Tx) QueryExpContext(ctx context.Context, exp Expression) (*sql.Rows, error) {
+	sql, args, err := tx.Compile(exp)
+	if err != nil {
+		return nil, err
+	}
+	return tx.QueryContext(ctx, sql, args...)
+}
+
+// ExecExp execute a expression
+func (tx *Tx) ExecExp(exp Expression) (sql.Result, error) {
+	return tx.ExecExpContext(context.Background(), exp)
+}
+
+// ExecExpContext execute a expression, ctx can cancel the execution
+func (tx *Tx) ExecExpContext(ctx context.Context, exp Expression) (sql.Result, error) {
+	sql, args, err := tx.Compile(exp)
+	if err != nil {
+		return nil, err
+	}
+	return tx.ExecContext(ctx, sql, args...)
+}
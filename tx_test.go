@@ -0,0 +1,86 @@
+package kdb
+
+import (
+	"testing"
+)
+
+func TestDBTransaction(t *testing.T) {
+	RegisterDSN("kdb_tx", "sqlite3", ":memory:")
+	db := NewDB("kdb_tx")
+	if err := db.Open(); err != nil {
+		t.Fatal("open db error", err)
+	}
+	defer db.Close()
+
+	// every connection of :memory: is a new database
+	db.DB().SetMaxOpenConns(1)
+	if _, err := db.Exec("CREATE TABLE ttable (cint integer, cstring varchar(100));"); err != nil {
+		t.Fatal("create table error", err)
+	}
+
+	count := func(q func(exp Expression) (int64, error)) int64 {
+		query := NewQuery("ttable", "")
+		query.Select.CountAll("c")
+		c, err := q(query)
+		if err != nil {
+			t.Fatal("count error", err)
+		}
+		return c
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal("begin error", err)
+	}
+
+	if _, err = tx.ExecExp(NewInsert("ttable").Set("cint", 1).Set("cstring", "s1")); err != nil {
+		t.Fatal("insert in transaction error", err)
+	}
+
+	txCount := func(exp Expression) (c int64, err error) {
+		rows, err := tx.QueryExp(exp)
+		if err != nil {
+			return
+		}
+		defer rows.Close()
+		if rows.Next() {
+			err = rows.Scan(&c)
+		}
+		return
+	}
+	if c := count(txCount); c != 1 {
+		t.Error("select in transaction should see insert, count=", c)
+	}
+
+	if err = tx.Rollback(); err != nil {
+		t.Fatal("rollback error", err)
+	}
+
+	dbCount := func(exp Expression) (c int64, err error) {
+		rows, err := db.QueryExp(exp)
+		if err != nil {
+			return
+		}
+		defer rows.Close()
+		if rows.Next() {
+			err = rows.Scan(&c)
+		}
+		return
+	}
+	if c := count(dbCount); c != 0 {
+		t.Error("insert should be rolled back, count=", c)
+	}
+
+	if tx, err = db.Begin(); err != nil {
+		t.Fatal("begin error", err)
+	}
+	if _, err = tx.ExecExp(NewInsert("ttable").Set("cint", 2).Set("cstring", "s2")); err != nil {
+		t.Fatal("insert in transaction error", err)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal("commit error", err)
+	}
+	if c := count(dbCount); c != 1 {
+		t.Error("insert should be committed, count=", c)
+	}
+}
@@ -129,11 +129,17 @@ func parseStruct(structType reflect.Type) (*structInfo, error) {
 		if f.PkgPath != "" {
 			continue
 		}
+		// db:"-" means the field is not mapped to any column
+		if f.Tag.Get("db") == "-" {
+			continue
+		}
 		tag := parseTag(string(f.Tag))
 
 		var colName string
 		if name, _ := tag.Option("name"); name != "" {
 			colName = name
+		} else if name := f.Tag.Get("db"); name != "" {
+			colName = name
 		} else {
 			colName = f.Name
 		}
@@ -106,3 +106,26 @@ func TestEntity(t *testing.T) {
 	}
 
 }
+
+type dbTagEntity struct {
+	Id   int    `db:"cid"`
+	Name string `db:"-"`
+	Age  int
+}
+
+func TestParseStructDbTag(t *testing.T) {
+	si, err := getStructInfo(reflect.TypeOf(dbTagEntity{}))
+	if err != nil {
+		t.Fatal("getStructInfo error", err)
+	}
+
+	if fi, ok := si.FieldByColName("CID"); !ok || fi.fName != "Id" {
+		t.Errorf("FieldByColName error, want=[%v]; actual=[%v]", "Id", fi)
+	}
+	if fi, ok := si.FieldByColName("name"); ok {
+		t.Errorf("field with db:\"-\" should be skipped, actual=[%v]", fi)
+	}
+	if fi, ok := si.FieldByColName("age"); !ok || fi.fName != "Age" {
+		t.Errorf("FieldByColName error, want=[%v]; actual=[%v]", "Age", fi)
+	}
+}
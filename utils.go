@@ -3,12 +3,15 @@ package kdb
 import (
 	"bytes"
 	"database/sql"
+	sqldriver "database/sql/driver"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/sdming/kdb/ansi"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // CompileTemplate parse template, return formated template, parameter names
@@ -241,3 +244,350 @@ func asString(s interface{}) string {
 	}
 	return fmt.Sprintf("%v", s)
 }
+
+// coerceValue convert driver value v to go type of t, like int64 of ansi.Int, string of ansi.String,
+// text of ansi.Numeric is returned as string. v is returned if t is Zero or nil
+func coerceValue(v interface{}, t ansi.DbType) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	switch t {
+	case ansi.String, ansi.Json, ansi.Guid:
+		return asString(v), nil
+	case ansi.Date, ansi.DateTime:
+		// time.Time is kept, text of date is converted to string
+		if b, ok := v.([]byte); ok {
+			return string(b), nil
+		}
+		return v, nil
+	case ansi.Bytes:
+		switch x := v.(type) {
+		case []byte:
+			return x, nil
+		case string:
+			return []byte(x), nil
+		}
+	case ansi.Int:
+		switch x := v.(type) {
+		case int64:
+			return x, nil
+		case float64:
+			if x == float64(int64(x)) {
+				return int64(x), nil
+			}
+		case []byte, string:
+			return strconv.ParseInt(strings.TrimSpace(asString(x)), 10, 64)
+		default:
+			rv := reflect.ValueOf(v)
+			switch rv.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+				return rv.Int(), nil
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				if u := rv.Uint(); u <= 1<<63-1 {
+					return int64(u), nil
+				}
+			}
+		}
+	case ansi.Uint:
+		switch x := v.(type) {
+		case uint64:
+			return x, nil
+		case int64:
+			if x >= 0 {
+				return uint64(x), nil
+			}
+		case []byte, string:
+			return strconv.ParseUint(strings.TrimSpace(asString(x)), 10, 64)
+		}
+	case ansi.Numeric:
+		// text of decimal is kept as string, convert it to float64 may lose precision
+		if b, ok := v.([]byte); ok {
+			return string(b), nil
+		}
+		return v, nil
+	case ansi.Float:
+		switch x := v.(type) {
+		case float64:
+			return x, nil
+		case float32:
+			return float64(x), nil
+		case int64:
+			return float64(x), nil
+		case []byte, string:
+			return strconv.ParseFloat(strings.TrimSpace(asString(x)), 64)
+		}
+	case ansi.Boolean:
+		switch x := v.(type) {
+		case bool:
+			return x, nil
+		case int64:
+			return x != 0, nil
+		case []byte, string:
+			return strconv.ParseBool(strings.TrimSpace(asString(x)))
+		}
+	default:
+		return v, nil
+	}
+	return nil, fmt.Errorf("can not convert %T to %v", v, t)
+}
+
+// InterpolateParams replace placeholders of query with literal of args, like ? or $1, for debug log only,
+// the result should never be executed. placeholder inside quoted string or identifier is kept.
+// named placeholders are resolved as generated pv1, pv2 ..., use InterpolateNamedParams if query has named Parameter
+func InterpolateParams(driver, query string, args []interface{}) (string, error) {
+	return InterpolateNamedParams(driver, query, args, nil)
+}
+
+// InterpolateNamedParams is InterpolateParams with names of args returned by CompileNamed or ParameterNames,
+// names[i] is name of args[i]; if names is nil, named placeholders are resolved as generated pv1, pv2 ...
+func InterpolateNamedParams(driver, query string, args []interface{}, names []string) (string, error) {
+	d, err := GetDialecter(driver)
+	if err != nil {
+		return "", err
+	}
+
+	prefix := strings.TrimSpace(d.ParameterPlaceHolder())
+	indexed := d.SupportIndexedParameter() || d.SupportNamedParameter()
+
+	var buf bytes.Buffer
+	buf.Grow(len(query) + 16*len(args))
+
+	var quote byte
+	next := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if quote != 0 {
+			buf.WriteByte(c)
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case !indexed && c == '?':
+			if next >= len(args) {
+				return "", fmt.Errorf("interpolate params error, query has more placeholders than %d args", len(args))
+			}
+			buf.WriteString(QuoteLiteral(d, args[next]))
+			next++
+			continue
+		case indexed && strings.HasPrefix(query[i:], prefix):
+			j := i + len(prefix)
+			k := j
+			var n int
+			if d.SupportNamedParameter() && names != nil {
+				for k < len(query) && isNameByte(query[k], k > j) {
+					k++
+				}
+				if k == j {
+					break
+				}
+				n = indexOfString(names, query[j:k]) + 1
+				if n == 0 {
+					return "", fmt.Errorf("interpolate params error, placeholder %s isn't in names", query[i:k])
+				}
+			} else {
+				// generated named parameter of compiled expression is pv1, pv2 ...
+				if d.SupportNamedParameter() && strings.HasPrefix(query[j:], "pv") {
+					j += 2
+					k = j
+				}
+				for k < len(query) && query[k] >= '0' && query[k] <= '9' {
+					k++
+				}
+				if k == j {
+					break
+				}
+				n, _ = strconv.Atoi(query[j:k])
+			}
+
+			if n < 1 || n > len(args) {
+				return "", fmt.Errorf("interpolate params error, placeholder %s is out of %d args", query[i:k], len(args))
+			}
+			buf.WriteString(QuoteLiteral(d, args[n-1]))
+			if n > next {
+				next = n
+			}
+			i = k - 1
+			continue
+		}
+		buf.WriteByte(c)
+	}
+
+	if next != len(args) {
+		return "", fmt.Errorf("interpolate params error, query uses %d of %d args", next, len(args))
+	}
+	return buf.String(), nil
+}
+
+// isNameByte return true if c can be in name of parameter, digit can't be the first byte
+func isNameByte(c byte, digit bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (digit && c >= '0' && c <= '9')
+}
+
+// indexOfString return index of s in a, -1 if a doesn't contain s
+func indexOfString(a []string, s string) int {
+	for i := 0; i < len(a); i++ {
+		if a[i] == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// arrayValue is slice bound as a single array parameter, Value encode it as text of postgres array like {1,2,"a"},
+// so driver doesn't need to accept go slice
+type arrayValue struct {
+	v interface{}
+}
+
+// Value implement driver.Valuer
+func (a arrayValue) Value() (sqldriver.Value, error) {
+	rv := reflect.Indirect(reflect.ValueOf(a.v))
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("array parameter should be slice or array, got %T", a.v)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i := 0; i < rv.Len(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := writeArrayElement(&buf, rv.Index(i).Interface()); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.String(), nil
+}
+
+// writeArrayElement write v as element of postgres array text, string is quoted and " \ are escaped
+func writeArrayElement(buf *bytes.Buffer, v interface{}) error {
+	if valuer, ok := v.(sqldriver.Valuer); ok {
+		dv, err := valuer.Value()
+		if err != nil {
+			return err
+		}
+		v = dv
+	}
+
+	rv := reflect.ValueOf(v)
+	if v == nil || (rv.Kind() == reflect.Ptr && rv.IsNil()) {
+		buf.WriteString(ansi.Null)
+		return nil
+	}
+	rv = reflect.Indirect(rv)
+
+	var s string
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		buf.WriteString(strconv.FormatInt(rv.Int(), 10))
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		buf.WriteString(strconv.FormatUint(rv.Uint(), 10))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		buf.WriteString(strconv.FormatFloat(rv.Float(), 'g', -1, 64))
+		return nil
+	case reflect.Bool:
+		buf.WriteString(strconv.FormatBool(rv.Bool()))
+		return nil
+	case reflect.String:
+		s = rv.String()
+	default:
+		switch x := rv.Interface().(type) {
+		case time.Time:
+			s = x.Format("2006-01-02 15:04:05.999999999Z07:00")
+		case []byte:
+			s = "\\x" + hex.EncodeToString(x)
+		default:
+			s = fmt.Sprint(x)
+		}
+	}
+
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			buf.WriteByte('\\')
+		}
+		buf.WriteByte(s[i])
+	}
+	buf.WriteByte('"')
+	return nil
+}
+
+// QuoteLiteral return sql literal of v for dialect d, like quoted string with doubled single quote, X'0102', NULL.
+// it's for debug log only
+func QuoteLiteral(d Dialecter, v interface{}) string {
+	if valuer, ok := v.(sqldriver.Valuer); ok {
+		if dv, err := valuer.Value(); err == nil {
+			v = dv
+		}
+	}
+
+	switch x := v.(type) {
+	case nil:
+		return ansi.Null
+	case string:
+		return quoteStringLiteral(d, x)
+	case []byte:
+		return hexLiteral(d, x)
+	case bool:
+		switch d.Name() {
+		case "mssql", "oracle":
+			if x {
+				return "1"
+			}
+			return "0"
+		}
+		if x {
+			return "TRUE"
+		}
+		return "FALSE"
+	case time.Time:
+		return quoteStringLiteral(d, x.Format("2006-01-02 15:04:05.999999999"))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(x)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return ansi.Null
+		}
+		return QuoteLiteral(d, rv.Elem().Interface())
+	}
+	return quoteStringLiteral(d, fmt.Sprint(v))
+}
+
+// quoteStringLiteral quote s with single quote, quote in s is doubled, mysql also escape backslash
+func quoteStringLiteral(d Dialecter, s string) string {
+	s = strings.Replace(s, "'", "''", -1)
+	if d.Name() == "mysql" {
+		s = strings.Replace(s, `\`, `\\`, -1)
+	}
+	return "'" + s + "'"
+}
+
+// hexLiteral return binary literal of b, like X'0102' or '\x0102' of postgres
+func hexLiteral(d Dialecter, b []byte) string {
+	h := hex.EncodeToString(b)
+	switch d.Name() {
+	case "postgres":
+		return `'\x` + h + "'"
+	case "mssql":
+		return "0x" + h
+	case "oracle":
+		return "HEXTORAW('" + h + "')"
+	}
+	return "X'" + h + "'"
+}
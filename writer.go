@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/sdming/kdb/ansi"
+	"strings"
+	"unicode/utf8"
 )
 
 const _indentChar = "\t"
@@ -11,6 +13,59 @@ const _indentChar = "\t"
 type sqlWriter struct {
 	depth int
 	bytes.Buffer
+
+	// discard drop everything written, used when only args of expression are needed
+	discard bool
+
+	// expect is the rest of sql expected to be written in discard mode, mismatch is true once written sql differs from it
+	expect   string
+	mismatch bool
+}
+
+// newSqlWriter return *sqlWriter, pre-size buffer if size > 0
+func newSqlWriter(size int) *sqlWriter {
+	sw := &sqlWriter{}
+	if size > 0 {
+		sw.Grow(size)
+	}
+	return sw
+}
+
+// WriteString append s to buffer if writer doesn't discard, otherwise s is compared with expected sql
+func (sw *sqlWriter) WriteString(s string) (int, error) {
+	if sw.discard {
+		sw.match(s)
+		return len(s), nil
+	}
+	return sw.Buffer.WriteString(s)
+}
+
+// WriteRune append r to buffer if writer doesn't discard, otherwise r is compared with expected sql
+func (sw *sqlWriter) WriteRune(r rune) (int, error) {
+	if sw.discard {
+		var b [utf8.UTFMax]byte
+		n := utf8.EncodeRune(b[:], r)
+		sw.match(string(b[:n]))
+		return n, nil
+	}
+	return sw.Buffer.WriteRune(r)
+}
+
+// match consume s from expected sql, set mismatch if expected sql doesn't start with s
+func (sw *sqlWriter) match(s string) {
+	if sw.mismatch {
+		return
+	}
+	if !strings.HasPrefix(sw.expect, s) {
+		sw.mismatch = true
+		return
+	}
+	sw.expect = sw.expect[len(s):]
+}
+
+// matched return true if everything written in discard mode is same as expected sql
+func (sw *sqlWriter) matched() bool {
+	return !sw.mismatch && sw.expect == ""
 }
 
 func (sw *sqlWriter) Blank() {
@@ -65,3 +120,38 @@ func (sw *sqlWriter) PrintSplit(split string, args ...string) {
 func (sw *sqlWriter) Println(args ...interface{}) {
 	sw.WriteString(fmt.Sprintln(args...))
 }
+
+// compactSql collapse runs of whitespace to single space and trim leading/trailing whitespace,
+// whitespace inside quoted string or identifier is kept
+func compactSql(s string) string {
+	var buf bytes.Buffer
+	buf.Grow(len(s))
+
+	var quote byte
+	space := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			buf.WriteByte(c)
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch c {
+		case ' ', '\t', '\n', '\r':
+			space = true
+			continue
+		case '\'', '"', '`':
+			quote = c
+		}
+
+		if space && buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		space = false
+		buf.WriteByte(c)
+	}
+	return buf.String()
+}